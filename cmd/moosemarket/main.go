@@ -2,20 +2,60 @@
 package main
 
 import (
+    "flag"
+    "log"
+
     "fyne.io/fyne/v2/app"
     
+    "github.com/frederikblais/Moose-Market/internal/data"
     "github.com/frederikblais/Moose-Market/internal/ui"
 )
 
 func main() {
+    // Storage settings, flags take precedence over the environment
+    storage := data.StorageConfigFromEnv()
+    flag.StringVar(&storage.Backend, "storage", storage.Backend,
+        "storage backend, json or bolt (default json)")
+    flag.StringVar(&storage.DataDir, "data-dir", storage.DataDir,
+        "directory for profiles and drawings (default $XDG_DATA_HOME/moosemarket)")
+    flag.StringVar(&storage.CacheDir, "cache-dir", storage.CacheDir,
+        "directory for cached market data (default $XDG_CACHE_HOME/moosemarket)")
+    flag.Parse()
+    data.ConfigureStorage(storage)
+    
     // Create the Fyne application
     a := app.New()
     w := a.NewWindow("Moose Market")
     
+    // Choose the market data provider from the environment
+    cfg := data.ProviderConfigFromEnv()
+    provider, err := data.NewProvider(cfg)
+    if err != nil {
+        log.Printf("Falling back to mock market data: %v", err)
+        provider, _ = data.NewProvider(data.ProviderConfig{Name: data.ProviderMock, Seed: cfg.Seed})
+    }
+    
+    // Stream quotes from the configured server, or replay the simulator locally
+    streamURL := cfg.StreamURL
+    if mock, ok := provider.(*data.MockProvider); ok && streamURL == "" {
+        server := data.NewStreamServer(mock.Simulator(), data.DefaultStreamTickInterval)
+        if url, err := server.Start("127.0.0.1:0"); err != nil {
+            log.Printf("Streaming disabled: %v", err)
+        } else {
+            streamURL = url
+            defer server.Stop()
+        }
+    }
+    var stream *data.QuoteStream
+    if streamURL != "" {
+        stream = data.NewQuoteStream(streamURL)
+    }
+    
     // Create and set up the dashboard
-    dashboard := ui.NewDashboard(a, w)
+    dashboard := ui.NewDashboard(a, w, provider, stream)
     dashboard.Setup()
+    defer data.Shutdown()
     
     // Run the application
     w.ShowAndRun()
-}
\ No newline at end of file
+}
@@ -2,24 +2,60 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/frederikblais/Moose-Market/internal/models"
 )
 
 const (
 	baseURL       = "https://www.alphavantage.co/query"
 	envAPIKeyName = "ALPHAVANTAGE_API_KEY"
+
+	// Free tier limits
+	alphaVantageCallsPerMinute = 5
+	alphaVantageCallsPerDay    = 25
+
+	// Retry policy for throttled and failed requests
+	alphaVantageMaxRetries   = 3
+	alphaVantageRetryBackoff = 2 * time.Second
 )
 
+// alphaVantageLimiter is shared by every client since the quota is per API key
+var alphaVantageLimiter = NewRateLimiter(alphaVantageCallsPerMinute, alphaVantageCallsPerDay)
+
+// ThrottleError is returned when Alpha Vantage answers with a rate limit notice
+type ThrottleError struct {
+	Message string
+	Daily   bool // the daily quota, not the per-minute one, was hit
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("alpha vantage rate limit reached: %s", e.Message)
+}
+
+// APIError is returned when Alpha Vantage reports an error in the payload
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("alpha vantage error: %s", e.Message)
+}
+
 // AlphaVantageClient is a client for the Alpha Vantage API
 type AlphaVantageClient struct {
 	APIKey     string
 	HTTPClient *http.Client
+	Limiter    *RateLimiter
 }
 
 // NewAlphaVantageClient creates a new Alpha Vantage API client
@@ -31,7 +67,141 @@ func NewAlphaVantageClient() *AlphaVantageClient {
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Limiter: alphaVantageLimiter,
+	}
+}
+
+// RemainingDailyCalls returns the number of API calls left today and the daily limit
+func (c *AlphaVantageClient) RemainingDailyCalls() (int, int) {
+	return c.Limiter.Remaining()
+}
+
+// query sends a request through the rate limiter, retrying with exponential
+// backoff when the request fails or is throttled, and returns the raw body
+func (c *AlphaVantageClient) query(ctx context.Context, params url.Values) ([]byte, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
+	}
+
+	params.Set("apikey", c.APIKey)
+	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+
+	backoff := alphaVantageRetryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= alphaVantageMaxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		body, err := c.send(ctx, fullURL)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		// A cancelled request is not retried
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// Only throttling and transport failures are worth retrying
+		var throttleErr *ThrottleError
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &throttleErr):
+			if throttleErr.Daily {
+				c.Limiter.Exhaust()
+				return nil, err
+			}
+		case errors.As(err, &apiErr):
+			return nil, err
+		}
 	}
+
+	return nil, lastErr
+}
+
+// send performs a single request and checks the payload for error notices
+func (c *AlphaVantageClient) send(ctx context.Context, fullURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Alpha Vantage request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to Alpha Vantage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Alpha Vantage response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &ThrottleError{Message: resp.Status}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected Alpha Vantage response status: %s", resp.Status)
+	}
+
+	if err := checkPayload(body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// checkPayload detects the notices Alpha Vantage sends with a 200 status
+// instead of data when a request is throttled or invalid
+func checkPayload(body []byte) error {
+	var notice struct {
+		Note         string `json:"Note"`
+		Information  string `json:"Information"`
+		ErrorMessage string `json:"Error Message"`
+	}
+	if err := json.Unmarshal(body, &notice); err != nil {
+		// Not an object, leave it to the caller to report
+		return nil
+	}
+
+	switch {
+	case notice.ErrorMessage != "":
+		return &APIError{Message: notice.ErrorMessage}
+	case notice.Note != "":
+		return &ThrottleError{Message: notice.Note, Daily: isDailyLimitMessage(notice.Note)}
+	case notice.Information != "":
+		if strings.Contains(strings.ToLower(notice.Information), "rate limit") {
+			return &ThrottleError{Message: notice.Information, Daily: isDailyLimitMessage(notice.Information)}
+		}
+		return &APIError{Message: notice.Information}
+	}
+
+	return nil
+}
+
+// isDailyLimitMessage reports whether a notice refers to the daily quota. The
+// per-minute and per-second notices quote the daily limit too, but only ask
+// to slow down.
+func isDailyLimitMessage(message string) bool {
+	message = strings.ToLower(message)
+	if strings.Contains(message, "per minute") || strings.Contains(message, "per second") {
+		return false
+	}
+	return strings.Contains(message, "per day") || strings.Contains(message, "daily")
 }
 
 // SearchResult represents a search result from Alpha Vantage
@@ -53,29 +223,16 @@ type SearchResponse struct {
 }
 
 // SearchSymbols searches for stock symbols by keyword
-func (c *AlphaVantageClient) SearchSymbols(keywords string) ([]SearchResult, error) {
-	if c.APIKey == "" {
-		return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
-	}
-
-	// Build the request URL
+func (c *AlphaVantageClient) SearchSymbols(ctx context.Context, keywords string) ([]SearchResult, error) {
+	// Build the request parameters
 	params := url.Values{}
 	params.Add("function", "SYMBOL_SEARCH")
 	params.Add("keywords", keywords)
-	params.Add("apikey", c.APIKey)
-
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
 	// Send the request
-	resp, err := c.HTTPClient.Get(fullURL)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request to Alpha Vantage: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.query(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Alpha Vantage response: %w", err)
+		return nil, err
 	}
 
 	// Parse the response
@@ -87,8 +244,8 @@ func (c *AlphaVantageClient) SearchSymbols(keywords string) ([]SearchResult, err
 	return searchResp.BestMatches, nil
 }
 
-// QuoteResult represents a global quote result
-type QuoteResult struct {
+// quoteResult represents a global quote result
+type quoteResult struct {
 	Symbol           string `json:"01. symbol"`
 	Open             string `json:"02. open"`
 	High             string `json:"03. high"`
@@ -101,68 +258,59 @@ type QuoteResult struct {
 	ChangePercent    string `json:"10. change percent"`
 }
 
-// QuoteResponse is the response from a quote query
-type QuoteResponse struct {
-	GlobalQuote QuoteResult `json:"Global Quote"`
+// quoteResponse is the response from a quote query
+type quoteResponse struct {
+	GlobalQuote quoteResult `json:"Global Quote"`
 }
 
 // GetQuote gets a stock quote
-func (c *AlphaVantageClient) GetQuote(symbol string) (*QuoteResult, error) {
-	if c.APIKey == "" {
-		return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
-	}
-
-	// Build the request URL
+func (c *AlphaVantageClient) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	// Build the request parameters
 	params := url.Values{}
 	params.Add("function", "GLOBAL_QUOTE")
 	params.Add("symbol", symbol)
-	params.Add("apikey", c.APIKey)
-
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
 	// Send the request
-	resp, err := c.HTTPClient.Get(fullURL)
+	body, err := c.query(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request to Alpha Vantage: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading Alpha Vantage response: %w", err)
+		return nil, err
 	}
 
 	// Parse the response
-	var quoteResp QuoteResponse
+	var quoteResp quoteResponse
 	if err := json.Unmarshal(body, &quoteResp); err != nil {
 		return nil, fmt.Errorf("error parsing Alpha Vantage response: %w", err)
 	}
 
-	return &quoteResp.GlobalQuote, nil
-}
+	quote := quoteResp.GlobalQuote
+	if quote.Symbol == "" {
+		return nil, &APIError{Message: fmt.Sprintf("no quote found for %s", symbol)}
+	}
 
-// TimeSeriesData represents the data for a specific date
-type TimeSeriesData struct {
-	Open   string `json:"1. open"`
-	High   string `json:"2. high"`
-	Low    string `json:"3. low"`
-	Close  string `json:"4. close"`
-	Volume string `json:"5. volume"`
-}
+	// Convert to a stock, failing on the first field that doesn't parse
+	p := newFieldParser("Global Quote")
+	stock := &models.Stock{
+		Symbol:        quote.Symbol,
+		Name:          quote.Symbol,
+		Open:          p.float("02. open", quote.Open),
+		High:          p.float("03. high", quote.High),
+		Low:           p.float("04. low", quote.Low),
+		Price:         p.float("05. price", quote.Price),
+		Volume:        p.int("06. volume", quote.Volume),
+		Change:        p.float("09. change", quote.Change),
+		ChangePercent: p.percent("10. change percent", quote.ChangePercent),
+		Timestamp:     time.Now().Unix(),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
 
-// DailyTimeSeriesResponse is the response from a daily time series query
-type DailyTimeSeriesResponse struct {
-	MetaData   map[string]string             `json:"Meta Data"`
-	TimeSeries map[string]TimeSeriesData `json:"Time Series (Daily)"`
+	return stock, nil
 }
 
-// GetDailyTimeSeries gets daily time series data for a symbol
-func (c *AlphaVantageClient) GetDailyTimeSeries(symbol string, compact bool) (map[string]TimeSeriesData, error) {
-	if c.APIKey == "" {
-		return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
-	}
-
-	// Build the request URL
+// GetDailyTimeSeries gets daily candles for a symbol, oldest first
+func (c *AlphaVantageClient) GetDailyTimeSeries(ctx context.Context, symbol string, compact bool) ([]models.CandleStick, error) {
+	// Build the request parameters
 	params := url.Values{}
 	params.Add("function", "TIME_SERIES_DAILY")
 	params.Add("symbol", symbol)
@@ -172,45 +320,98 @@ func (c *AlphaVantageClient) GetDailyTimeSeries(symbol string, compact bool) (ma
 	} else {
 		params.Add("outputsize", "full") // Full history
 	}
-	
-	params.Add("apikey", c.APIKey)
-
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
 	// Send the request
-	resp, err := c.HTTPClient.Get(fullURL)
+	body, err := c.query(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request to Alpha Vantage: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	entries, err := parseTimeSeries(body, "Time Series (Daily)", rawSeriesFields)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Alpha Vantage response: %w", err)
+		return nil, err
 	}
 
-	// Parse the response
-	var timeSeriesResp DailyTimeSeriesResponse
-	if err := json.Unmarshal(body, &timeSeriesResp); err != nil {
-		return nil, fmt.Errorf("error parsing Alpha Vantage response: %w", err)
+	return rawCandles(entries), nil
+}
+
+// GetDailyAdjustedTimeSeries gets daily candles for a symbol, oldest first,
+// back-adjusted for splits and dividends
+func (c *AlphaVantageClient) GetDailyAdjustedTimeSeries(ctx context.Context, symbol string, compact bool) ([]models.CandleStick, error) {
+	// Build the request parameters
+	params := url.Values{}
+	params.Add("function", "TIME_SERIES_DAILY_ADJUSTED")
+	params.Add("symbol", symbol)
+
+	if compact {
+		params.Add("outputsize", "compact") // Last 100 data points
+	} else {
+		params.Add("outputsize", "full") // Full history
+	}
+
+	// Send the request
+	body, err := c.query(ctx, params)
+	if err != nil {
+		return nil, err
 	}
 
-	return timeSeriesResp.TimeSeries, nil
+	entries, err := parseTimeSeries(body, "Time Series (Daily)", dailyAdjustedFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustForActions(entries), nil
 }
 
-// IntradayTimeSeriesResponse is the response from an intraday time series query
-type IntradayTimeSeriesResponse struct {
-	MetaData   map[string]string             `json:"Meta Data"`
-	TimeSeries map[string]TimeSeriesData `json:"Time Series (5min)"`
+// GetWeeklyTimeSeries gets weekly candles for a symbol, oldest first.
+// Adjusted candles are scaled to the adjusted close of each week.
+func (c *AlphaVantageClient) GetWeeklyTimeSeries(ctx context.Context, symbol string, adjusted bool) ([]models.CandleStick, error) {
+	if adjusted {
+		return c.getPeriodTimeSeries(ctx, symbol, "TIME_SERIES_WEEKLY_ADJUSTED", "Weekly Adjusted Time Series", true)
+	}
+	return c.getPeriodTimeSeries(ctx, symbol, "TIME_SERIES_WEEKLY", "Weekly Time Series", false)
 }
 
-// GetIntradayTimeSeries gets intraday time series data for a symbol
-func (c *AlphaVantageClient) GetIntradayTimeSeries(symbol string, interval string, compact bool) (map[string]TimeSeriesData, error) {
-	if c.APIKey == "" {
-		return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
+// GetMonthlyTimeSeries gets monthly candles for a symbol, oldest first.
+// Adjusted candles are scaled to the adjusted close of each month.
+func (c *AlphaVantageClient) GetMonthlyTimeSeries(ctx context.Context, symbol string, adjusted bool) ([]models.CandleStick, error) {
+	if adjusted {
+		return c.getPeriodTimeSeries(ctx, symbol, "TIME_SERIES_MONTHLY_ADJUSTED", "Monthly Adjusted Time Series", true)
 	}
+	return c.getPeriodTimeSeries(ctx, symbol, "TIME_SERIES_MONTHLY", "Monthly Time Series", false)
+}
 
-	// Build the request URL
+// getPeriodTimeSeries fetches a weekly or monthly series, which always covers the full history
+func (c *AlphaVantageClient) getPeriodTimeSeries(ctx context.Context, symbol, function, key string, adjusted bool) ([]models.CandleStick, error) {
+	// Build the request parameters
+	params := url.Values{}
+	params.Add("function", function)
+	params.Add("symbol", symbol)
+
+	// Send the request
+	body, err := c.query(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !adjusted {
+		entries, err := parseTimeSeries(body, key, rawSeriesFields)
+		if err != nil {
+			return nil, err
+		}
+		return rawCandles(entries), nil
+	}
+
+	entries, err := parseTimeSeries(body, key, periodAdjustedFields)
+	if err != nil {
+		return nil, err
+	}
+	return adjustByAdjustedClose(entries), nil
+}
+
+// GetIntradayTimeSeries gets intraday candles for a symbol, oldest first
+func (c *AlphaVantageClient) GetIntradayTimeSeries(ctx context.Context, symbol string, interval string, compact bool) ([]models.CandleStick, error) {
+	// Build the request parameters
 	params := url.Values{}
 	params.Add("function", "TIME_SERIES_INTRADAY")
 	params.Add("symbol", symbol)
@@ -221,49 +422,78 @@ func (c *AlphaVantageClient) GetIntradayTimeSeries(symbol string, interval strin
 	} else {
 		params.Add("outputsize", "full") // Full history
 	}
-	
-	params.Add("apikey", c.APIKey)
-
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
 	// Send the request
-	resp, err := c.HTTPClient.Get(fullURL)
+	body, err := c.query(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request to Alpha Vantage: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// The time series key varies based on interval
+	entries, err := parseTimeSeries(body, fmt.Sprintf("Time Series (%s)", interval), rawSeriesFields)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Alpha Vantage response: %w", err)
+		return nil, err
+	}
+
+	return rawCandles(entries), nil
+}
+
+// companyOverview represents the company overview returned by Alpha Vantage
+type companyOverview struct {
+	Symbol               string `json:"Symbol"`
+	Name                 string `json:"Name"`
+	Description          string `json:"Description"`
+	Exchange             string `json:"Exchange"`
+	Currency             string `json:"Currency"`
+	Country              string `json:"Country"`
+	Sector               string `json:"Sector"`
+	Industry             string `json:"Industry"`
+	MarketCapitalization string `json:"MarketCapitalization"`
+	PERatio              string `json:"PERatio"`
+	DividendYield        string `json:"DividendYield"`
+}
+
+// GetCompanyOverview gets descriptive company data for a symbol
+func (c *AlphaVantageClient) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyInfo, error) {
+	// Build the request parameters
+	params := url.Values{}
+	params.Add("function", "OVERVIEW")
+	params.Add("symbol", symbol)
+
+	// Send the request
+	body, err := c.query(ctx, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the response
-	var timeSeriesResp map[string]interface{}
-	if err := json.Unmarshal(body, &timeSeriesResp); err != nil {
+	var overview companyOverview
+	if err := json.Unmarshal(body, &overview); err != nil {
 		return nil, fmt.Errorf("error parsing Alpha Vantage response: %w", err)
 	}
 
-	// Find the time series key, which varies based on interval
-	timeSeriesKey := fmt.Sprintf("Time Series (%s)", interval)
-	timeSeriesData, ok := timeSeriesResp[timeSeriesKey].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response format, time series data not found")
+	if overview.Symbol == "" {
+		return nil, &APIError{Message: fmt.Sprintf("no company overview found for %s", symbol)}
 	}
 
-	// Convert to the desired format
-	result := make(map[string]TimeSeriesData)
-	for date, data := range timeSeriesData {
-		if dataMap, ok := data.(map[string]interface{}); ok {
-			result[date] = TimeSeriesData{
-				Open:   dataMap["1. open"].(string),
-				High:   dataMap["2. high"].(string),
-				Low:    dataMap["3. low"].(string),
-				Close:  dataMap["4. close"].(string),
-				Volume: dataMap["5. volume"].(string),
-			}
-		}
+	// Alpha Vantage reports "None" for values it doesn't have
+	p := newFieldParser("Overview")
+	info := &models.CompanyInfo{
+		Symbol:        overview.Symbol,
+		Name:          overview.Name,
+		Exchange:      overview.Exchange,
+		Currency:      overview.Currency,
+		Country:       overview.Country,
+		Sector:        overview.Sector,
+		Industry:      overview.Industry,
+		Description:   overview.Description,
+		MarketCap:     p.optionalFloat("MarketCapitalization", overview.MarketCapitalization),
+		PE:            p.optionalFloat("PERatio", overview.PERatio),
+		DividendYield: p.optionalFloat("DividendYield", overview.DividendYield),
+	}
+	if p.err != nil {
+		return nil, p.err
 	}
 
-	return result, nil
-}
\ No newline at end of file
+	return info, nil
+}
@@ -0,0 +1,266 @@
+// File: internal/data/alphavantage_parse.go
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// errMissingField is wrapped by a ParseError when a field is absent
+var errMissingField = errors.New("field missing")
+
+// ParseError is returned when a field of an Alpha Vantage payload can't be parsed
+type ParseError struct {
+	Context string // the object holding the field, such as "Global Quote"
+	Field   string
+	Value   string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid value %q for field %q in %s: %v", e.Value, e.Field, e.Context, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// fieldParser parses string fields of one object and remembers the first failure
+type fieldParser struct {
+	context string
+	err     error
+}
+
+// newFieldParser creates a parser for the fields of the named object
+func newFieldParser(context string) *fieldParser {
+	return &fieldParser{context: context}
+}
+
+// fail records a parse failure unless an earlier one was already recorded
+func (p *fieldParser) fail(field, value string, err error) {
+	if p.err == nil {
+		p.err = &ParseError{Context: p.context, Field: field, Value: value, Err: err}
+	}
+}
+
+// float parses a decimal field such as "187.4400"
+func (p *fieldParser) float(field, value string) float64 {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		p.fail(field, value, err)
+		return 0
+	}
+	return parsed
+}
+
+// optionalFloat parses a decimal field that may be reported as "None" or "-"
+func (p *fieldParser) optionalFloat(field, value string) float64 {
+	switch strings.TrimSpace(value) {
+	case "", "None", "-":
+		return 0
+	}
+	return p.float(field, value)
+}
+
+// percent parses a percentage field such as "1.23%" into 1.23
+func (p *fieldParser) percent(field, value string) float64 {
+	parsed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		p.fail(field, value, err)
+		return 0
+	}
+	return parsed
+}
+
+// int parses an integer field such as a volume
+func (p *fieldParser) int(field, value string) int64 {
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		p.fail(field, value, err)
+		return 0
+	}
+	return parsed
+}
+
+// lookup returns a field of a string map, recording a failure when it is missing
+func (p *fieldParser) lookup(values map[string]string, field string) string {
+	value, ok := values[field]
+	if !ok {
+		p.fail(field, "", errMissingField)
+	}
+	return value
+}
+
+// seriesFields names the fields of a time series entry, which differ between endpoints
+type seriesFields struct {
+	volume        string
+	adjustedClose string // empty for endpoints without adjustments
+	dividend      string
+	split         string // only reported by the daily adjusted endpoint
+}
+
+var (
+	// rawSeriesFields are used by the intraday, daily, weekly and monthly endpoints
+	rawSeriesFields = seriesFields{volume: "5. volume"}
+
+	// dailyAdjustedFields are used by TIME_SERIES_DAILY_ADJUSTED
+	dailyAdjustedFields = seriesFields{
+		volume:        "6. volume",
+		adjustedClose: "5. adjusted close",
+		dividend:      "7. dividend amount",
+		split:         "8. split coefficient",
+	}
+
+	// periodAdjustedFields are used by the weekly and monthly adjusted endpoints
+	periodAdjustedFields = seriesFields{
+		volume:        "6. volume",
+		adjustedClose: "5. adjusted close",
+		dividend:      "7. dividend amount",
+	}
+)
+
+// seriesEntry is a parsed time series entry with its corporate action data
+type seriesEntry struct {
+	candle        models.CandleStick
+	adjustedClose float64
+	dividend      float64
+	split         float64
+}
+
+// parseTimeSeries decodes the time series stored under key into entries, oldest first
+func parseTimeSeries(body []byte, key string, fields seriesFields) ([]seriesEntry, error) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("error parsing Alpha Vantage response: %w", err)
+	}
+
+	raw, ok := resp[key]
+	if !ok {
+		return nil, &ParseError{Context: "response", Field: key, Err: errMissingField}
+	}
+
+	var series map[string]map[string]string
+	if err := json.Unmarshal(raw, &series); err != nil {
+		return nil, &ParseError{Context: "response", Field: key, Value: string(raw), Err: err}
+	}
+
+	entries := make([]seriesEntry, 0, len(series))
+	for stamp, values := range series {
+		date, err := parseTimestamp(stamp)
+		if err != nil {
+			return nil, &ParseError{Context: key, Field: "date", Value: stamp, Err: err}
+		}
+
+		p := newFieldParser(fmt.Sprintf("%s[%s]", key, stamp))
+		entry := seriesEntry{
+			candle: models.CandleStick{
+				Time:   date,
+				Open:   p.float("1. open", p.lookup(values, "1. open")),
+				High:   p.float("2. high", p.lookup(values, "2. high")),
+				Low:    p.float("3. low", p.lookup(values, "3. low")),
+				Close:  p.float("4. close", p.lookup(values, "4. close")),
+				Volume: p.int(fields.volume, p.lookup(values, fields.volume)),
+			},
+			split: 1,
+		}
+		if fields.adjustedClose != "" {
+			entry.adjustedClose = p.float(fields.adjustedClose, p.lookup(values, fields.adjustedClose))
+		}
+		if fields.dividend != "" {
+			entry.dividend = p.float(fields.dividend, p.lookup(values, fields.dividend))
+		}
+		if fields.split != "" {
+			entry.split = p.float(fields.split, p.lookup(values, fields.split))
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	// Sort entries by time
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].candle.Time.Before(entries[j].candle.Time)
+	})
+
+	return entries, nil
+}
+
+// rawCandles returns the candles of entries as reported
+func rawCandles(entries []seriesEntry) []models.CandleStick {
+	candles := make([]models.CandleStick, len(entries))
+	for i, entry := range entries {
+		candles[i] = entry.candle
+	}
+	return candles
+}
+
+// adjustForActions back-adjusts daily candles for splits and dividends.
+// Walking from the newest day, every earlier price is divided by the split
+// coefficients and scaled by (1 - dividend / previous close) of the later days,
+// and earlier volumes are multiplied by the split coefficients, so a chart
+// shows no jump on the day of a split or ex-dividend date.
+func adjustForActions(entries []seriesEntry) []models.CandleStick {
+	candles := make([]models.CandleStick, len(entries))
+	priceFactor := 1.0
+	volumeFactor := 1.0
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		candle := entries[i].candle
+		candle.Open *= priceFactor
+		candle.High *= priceFactor
+		candle.Low *= priceFactor
+		candle.Close *= priceFactor
+		candle.Volume = int64(float64(candle.Volume) * volumeFactor)
+		candles[i] = candle
+
+		// Actions on this day change the prices of every earlier day
+		if split := entries[i].split; split > 0 && split != 1 {
+			priceFactor /= split
+			volumeFactor *= split
+		}
+		if dividend := entries[i].dividend; dividend > 0 && i > 0 {
+			if prevClose := entries[i-1].candle.Close; prevClose > dividend {
+				priceFactor *= 1 - dividend/prevClose
+			}
+		}
+	}
+
+	return candles
+}
+
+// adjustByAdjustedClose scales every candle by its adjusted close to close ratio,
+// for endpoints that report adjusted closes without split coefficients
+func adjustByAdjustedClose(entries []seriesEntry) []models.CandleStick {
+	candles := make([]models.CandleStick, len(entries))
+	for i, entry := range entries {
+		candle := entry.candle
+		if candle.Close > 0 && entry.adjustedClose > 0 {
+			ratio := entry.adjustedClose / candle.Close
+			candle.Open *= ratio
+			candle.High *= ratio
+			candle.Low *= ratio
+			candle.Close = entry.adjustedClose
+		}
+		candles[i] = candle
+	}
+	return candles
+}
+
+// parseTimestamp parses the daily or intraday keys of a time series
+func parseTimestamp(stamp string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", stamp)
+	if err != nil {
+		// Try intraday format
+		date, err = time.Parse("2006-01-02 15:04:05", stamp)
+	}
+	return date, err
+}
@@ -0,0 +1,117 @@
+// File: internal/data/alphavantage_provider.go
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// AlphaVantageProvider serves market data from the Alpha Vantage API
+type AlphaVantageProvider struct {
+	client *AlphaVantageClient
+
+	// adjustedRefused is set once the plan of the key turns out not to
+	// include adjusted daily data, so later charts don't spend a call on it
+	adjustedRefused atomic.Bool
+}
+
+// NewAlphaVantageProvider creates a provider backed by an Alpha Vantage client
+func NewAlphaVantageProvider(client *AlphaVantageClient) *AlphaVantageProvider {
+	return &AlphaVantageProvider{client: client}
+}
+
+// Name returns the configuration name of the provider
+func (p *AlphaVantageProvider) Name() string {
+	return ProviderAlphaVantage
+}
+
+// RemainingRequests returns the API calls left today and the daily limit
+func (p *AlphaVantageProvider) RemainingRequests() (int, int) {
+	return p.client.RemainingDailyCalls()
+}
+
+// GetQuote returns the latest quote for a symbol
+func (p *AlphaVantageProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	return p.client.GetQuote(ctx, symbol)
+}
+
+// GetCandles returns up to count candles for a symbol and timeframe
+func (p *AlphaVantageProvider) GetCandles(ctx context.Context, symbol, timeframe string, count int) (*models.CandleData, error) {
+	// Choose the appropriate API call based on timeframe
+	var candles []models.CandleStick
+	var err error
+
+	switch timeframe {
+	case "1d":
+		// Daily data adjusted for splits and dividends, when the plan has it
+		if p.adjustedRefused.Load() {
+			candles, err = p.client.GetDailyTimeSeries(ctx, symbol, true)
+			break
+		}
+		candles, err = p.client.GetDailyAdjustedTimeSeries(ctx, symbol, true)
+		// Only a plan refusal falls back, other errors would fail the raw
+		// series too and cost another request
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && isPremiumMessage(apiErr.Message) {
+			p.adjustedRefused.Store(true)
+			candles, err = p.client.GetDailyTimeSeries(ctx, symbol, true)
+		}
+	case "1w":
+		candles, err = p.client.GetWeeklyTimeSeries(ctx, symbol, true)
+	case "1mo":
+		candles, err = p.client.GetMonthlyTimeSeries(ctx, symbol, true)
+	default:
+		// Intraday data, which Alpha Vantage already adjusts
+		candles, err = p.client.GetIntradayTimeSeries(ctx, symbol, timeframe, true)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep only the most recent candles
+	if count > 0 && len(candles) > count {
+		candles = candles[len(candles)-count:]
+	}
+
+	return &models.CandleData{
+		Symbol:    symbol,
+		Timeframe: timeframe,
+		Candles:   candles,
+	}, nil
+}
+
+// SearchSymbols returns symbols matching a keyword
+func (p *AlphaVantageProvider) SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	results, err := p.client.SearchSymbols(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]models.SymbolMatch, 0, len(results))
+	for _, result := range results {
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   result.Symbol,
+			Name:     result.Name,
+			Exchange: result.Region,
+			Currency: result.Currency,
+		})
+	}
+
+	return matches, nil
+}
+
+// GetCompanyInfo returns descriptive data about a company
+func (p *AlphaVantageProvider) GetCompanyInfo(ctx context.Context, symbol string) (*models.CompanyInfo, error) {
+	return p.client.GetCompanyOverview(ctx, symbol)
+}
+
+// isPremiumMessage reports whether an error notice refuses an endpoint that
+// the plan of the key doesn't include
+func isPremiumMessage(message string) bool {
+	return strings.Contains(strings.ToLower(message), "premium")
+}
@@ -0,0 +1,89 @@
+// File: internal/data/alphavantage_provider_test.go
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeAlphaVantage answers requests by function and counts them
+type fakeAlphaVantage struct {
+	responses map[string]string
+	calls     map[string]int
+}
+
+func (f *fakeAlphaVantage) RoundTrip(req *http.Request) (*http.Response, error) {
+	function := req.URL.Query().Get("function")
+	f.calls[function]++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body:       io.NopCloser(strings.NewReader(f.responses[function])),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestGetCandlesRemembersPremiumRefusal(t *testing.T) {
+	fake := &fakeAlphaVantage{
+		responses: map[string]string{
+			"TIME_SERIES_DAILY_ADJUSTED": `{"Information": "Thank you for using Alpha Vantage! This is a premium endpoint. You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium endpoints"}`,
+			"TIME_SERIES_DAILY":          `{"Time Series (Daily)": {"2024-01-02": {"1. open": "10", "2. high": "11", "3. low": "9", "4. close": "10.5", "5. volume": "100"}}}`,
+		},
+		calls: make(map[string]int),
+	}
+	client := &AlphaVantageClient{
+		APIKey:     "test",
+		HTTPClient: &http.Client{Transport: fake},
+		Limiter:    NewRateLimiter(100, 100),
+	}
+	provider := NewAlphaVantageProvider(client)
+
+	for range 3 {
+		data, err := provider.GetCandles(context.Background(), "IBM", "1d", 10)
+		if err != nil {
+			t.Fatalf("GetCandles() error = %v", err)
+		}
+		if len(data.Candles) != 1 {
+			t.Fatalf("GetCandles() returned %d candles, want 1", len(data.Candles))
+		}
+	}
+
+	if got := fake.calls["TIME_SERIES_DAILY_ADJUSTED"]; got != 1 {
+		t.Errorf("adjusted endpoint called %d times, want 1", got)
+	}
+	if got := fake.calls["TIME_SERIES_DAILY"]; got != 3 {
+		t.Errorf("raw endpoint called %d times, want 3", got)
+	}
+}
+
+func TestGetCandlesReturnsOtherAPIErrors(t *testing.T) {
+	fake := &fakeAlphaVantage{
+		responses: map[string]string{
+			"TIME_SERIES_DAILY_ADJUSTED": `{"Error Message": "Invalid API call. Please retry or visit the documentation (https://www.alphavantage.co/documentation/) for TIME_SERIES_DAILY_ADJUSTED."}`,
+		},
+		calls: make(map[string]int),
+	}
+	client := &AlphaVantageClient{
+		APIKey:     "test",
+		HTTPClient: &http.Client{Transport: fake},
+		Limiter:    NewRateLimiter(100, 100),
+	}
+	provider := NewAlphaVantageProvider(client)
+
+	_, err := provider.GetCandles(context.Background(), "NOPE", "1d", 10)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetCandles() error = %v, want an APIError", err)
+	}
+	if got := fake.calls["TIME_SERIES_DAILY"]; got != 0 {
+		t.Errorf("raw endpoint called %d times, want 0", got)
+	}
+	if provider.adjustedRefused.Load() {
+		t.Error("an invalid call was taken for a plan refusal")
+	}
+}
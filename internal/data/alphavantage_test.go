@@ -0,0 +1,74 @@
+// File: internal/data/alphavantage_test.go
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+// Notices Alpha Vantage sends with a 200 status when a key is throttled
+const (
+	perMinuteNotice = "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day. Please visit https://www.alphavantage.co/premium/ if you would like to target a higher API call frequency."
+	perSecondNotice = "Thank you for using Alpha Vantage! Please consider spreading out your free API requests more sparingly (1 request per second). You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to lift the free key rate limit (25 requests per day) and instantly remove all daily rate limits."
+	perDayNotice    = "Thank you for using Alpha Vantage! Our standard API rate limit is 25 requests per day. Please subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly remove all daily rate limits."
+)
+
+func TestIsDailyLimitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		daily   bool
+	}{
+		{
+			name:    "per minute",
+			message: perMinuteNotice,
+			daily:   false,
+		},
+		{
+			name:    "per second burst",
+			message: perSecondNotice,
+			daily:   false,
+		},
+		{
+			name:    "per day",
+			message: perDayNotice,
+			daily:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDailyLimitMessage(tt.message); got != tt.daily {
+				t.Errorf("isDailyLimitMessage() = %v, want %v", got, tt.daily)
+			}
+		})
+	}
+}
+
+func TestCheckPayloadThrottle(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		throttle bool
+		daily    bool
+	}{
+		{"per minute note", `{"Note": "` + perMinuteNotice + `"}`, true, false},
+		{"per second information", `{"Information": "` + perSecondNotice + `"}`, true, false},
+		{"per day information", `{"Information": "` + perDayNotice + `"}`, true, true},
+		{"premium endpoint", `{"Information": "Thank you for using Alpha Vantage! This is a premium endpoint."}`, false, false},
+		{"data", `{"Global Quote": {}}`, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPayload([]byte(tt.body))
+			var throttleErr *ThrottleError
+			if got := errors.As(err, &throttleErr); got != tt.throttle {
+				t.Fatalf("checkPayload() = %v, want a throttle error: %v", err, tt.throttle)
+			}
+			if tt.throttle && throttleErr.Daily != tt.daily {
+				t.Errorf("Daily = %v, want %v", throttleErr.Daily, tt.daily)
+			}
+		})
+	}
+}
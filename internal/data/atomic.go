@@ -0,0 +1,90 @@
+// File: internal/data/atomic.go
+package data
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// tempFileMarker is part of the name of every temp file made by writeFileAtomic
+const tempFileMarker = ".tmp-"
+
+// staleTempFileAge is how old a temp file must be before it is taken for the
+// leftover of an interrupted write. Younger ones may belong to a write another
+// instance is making right now.
+const staleTempFileAge = time.Hour
+
+// writeFileAtomic writes data to path so that path holds either the old or
+// the new content after a crash, never a partial write. The data goes to a
+// temp file in the same directory which is synced and renamed over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+    dir, name := filepath.Split(path)
+    if dir == "" {
+        dir = "."
+    }
+
+    tmp, err := os.CreateTemp(dir, "."+name+tempFileMarker+"*")
+    if err != nil {
+        return err
+    }
+    tmpPath := tmp.Name()
+
+    // Remove the temp file on any failure, the target stays untouched
+    committed := false
+    defer func() {
+        if !committed {
+            os.Remove(tmpPath)
+        }
+    }()
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return fmt.Errorf("failed to sync %s: %w", path, err)
+    }
+    if err := tmp.Close(); err != nil {
+        return fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    if err := os.Chmod(tmpPath, perm); err != nil {
+        return err
+    }
+    if err := os.Rename(tmpPath, path); err != nil {
+        return fmt.Errorf("failed to replace %s: %w", path, err)
+    }
+    committed = true
+
+    // Persist the rename itself. Directories can't be opened for syncing on
+    // every platform, the file content is safe either way.
+    if d, err := os.Open(dir); err == nil {
+        d.Sync()
+        d.Close()
+    }
+
+    return nil
+}
+
+// removeTempFiles deletes temp files left in dir by writes that were
+// interrupted, leaving those recent enough to still be in use
+func removeTempFiles(dir string) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return
+    }
+
+    for _, entry := range entries {
+        if entry.IsDir() || !strings.HasPrefix(entry.Name(), ".") || !strings.Contains(entry.Name(), tempFileMarker) {
+            continue
+        }
+        info, err := entry.Info()
+        if err != nil || time.Since(info.ModTime()) < staleTempFileAge {
+            continue
+        }
+        os.Remove(filepath.Join(dir, entry.Name()))
+    }
+}
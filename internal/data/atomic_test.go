@@ -0,0 +1,82 @@
+// File: internal/data/atomic_test.go
+package data
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestRemoveTempFilesKeepsRecentOnes(t *testing.T) {
+    dir := t.TempDir()
+    stale := filepath.Join(dir, ".profile.json"+tempFileMarker+"1")
+    recent := filepath.Join(dir, ".profile.json"+tempFileMarker+"2")
+    kept := filepath.Join(dir, "profile.json")
+    for _, path := range []string{stale, recent, kept} {
+        if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    old := time.Now().Add(-2 * staleTempFileAge)
+    if err := os.Chtimes(stale, old, old); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Chtimes(kept, old, old); err != nil {
+        t.Fatal(err)
+    }
+
+    removeTempFiles(dir)
+
+    if _, err := os.Stat(stale); !os.IsNotExist(err) {
+        t.Errorf("stale temp file still exists (err = %v)", err)
+    }
+    for _, path := range []string{recent, kept} {
+        if _, err := os.Stat(path); err != nil {
+            t.Errorf("%s was removed: %v", filepath.Base(path), err)
+        }
+    }
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "stocks.json")
+
+    for _, content := range []string{"first", "second"} {
+        if err := writeFileAtomic(path, []byte(content), 0644); err != nil {
+            t.Fatalf("writeFileAtomic() error = %v", err)
+        }
+        if got, err := os.ReadFile(path); err != nil || string(got) != content {
+            t.Errorf("file holds %q (err = %v), want %q", got, err, content)
+        }
+    }
+
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 1 {
+        t.Errorf("directory holds %d files, want only the target", len(entries))
+    }
+}
+
+func TestWriteFileAtomicFailureLeavesTarget(t *testing.T) {
+    dir := t.TempDir()
+
+    // A directory can't be replaced by a file, so the rename fails
+    target := filepath.Join(dir, "target")
+    if err := os.MkdirAll(filepath.Join(target, "inside"), 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := writeFileAtomic(target, []byte("data"), 0644); err == nil {
+        t.Fatal("writeFileAtomic() over a directory succeeded")
+    }
+
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 1 || !entries[0].IsDir() {
+        t.Errorf("failed write left %d entries behind, want only the untouched target", len(entries))
+    }
+}
@@ -0,0 +1,269 @@
+// File: internal/data/audit.go
+package data
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "os/user"
+    "path/filepath"
+    "slices"
+    "sync"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// auditDir holds the audit log of each profile, under the data directory
+const auditDir = "audit"
+
+// AuditEntry is a save of a profile recorded in its audit log
+type AuditEntry struct {
+    Time    time.Time     `json:"time"`
+    Actor   string        `json:"actor"` // user and machine that saved the profile
+    Changes []AuditChange `json:"changes"`
+}
+
+// AuditFilter selects audit log entries. Zero fields match everything.
+type AuditFilter struct {
+    AccountID string    // only the changes made to this account
+    From      time.Time // entries saved at or after From
+    To        time.Time // entries saved before To
+}
+
+// auditMutex keeps the lines written to audit logs whole
+var auditMutex sync.Mutex
+
+// auditActor names who is saving profiles, as user@host
+var auditActor = sync.OnceValue(func() string {
+    name := "unknown"
+    if u, err := user.Current(); err == nil {
+        name = u.Username
+    }
+    host, err := os.Hostname()
+    if err != nil {
+        return name
+    }
+    return name + "@" + host
+})
+
+// auditLogPath returns the audit log of a profile
+func auditLogPath(id string) string {
+    return filepath.Join(DataDirectory(), auditDir, id+".jsonl")
+}
+
+// recordProfileChange appends what changed between two versions of a profile
+// to its audit log. previous is nil for a new profile. A log started for a
+// profile that existed before begins with the whole previous version.
+func recordProfileChange(previous, profile *models.Profile) error {
+    var before []byte
+    if previous != nil {
+        var err error
+        if before, err = json.Marshal(previous); err != nil {
+            return err
+        }
+    }
+    after, err := json.Marshal(profile)
+    if err != nil {
+        return err
+    }
+    changes, err := diffProfiles(before, after)
+    if err != nil || len(changes) == 0 {
+        return err
+    }
+
+    auditMutex.Lock()
+    defer auditMutex.Unlock()
+
+    var entries []AuditEntry
+    if _, err := os.Stat(auditLogPath(profile.ID)); errors.Is(err, os.ErrNotExist) && previous != nil {
+        baseline, err := diffProfiles(nil, before)
+        if err != nil {
+            return err
+        }
+        entries = append(entries, AuditEntry{Time: previous.LastModified, Actor: auditActor(), Changes: baseline})
+    }
+    entries = append(entries, AuditEntry{Time: profile.LastModified, Actor: auditActor(), Changes: changes})
+
+    return appendAuditEntries(profile.ID, entries)
+}
+
+// appendAuditEntries writes entries at the end of the audit log of a profile.
+// The caller must hold auditMutex.
+func appendAuditEntries(id string, entries []AuditEntry) error {
+    var lines bytes.Buffer
+    for i := range entries {
+        line, err := encodeAuditEntry(id, &entries[i])
+        if err != nil {
+            return err
+        }
+        lines.Write(line)
+        lines.WriteByte('\n')
+    }
+
+    return withFileLock(DataDirectory(), func() error {
+        if err := os.MkdirAll(filepath.Dir(auditLogPath(id)), 0755); err != nil {
+            return err
+        }
+        f, err := os.OpenFile(auditLogPath(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+        if err != nil {
+            return err
+        }
+        if _, err := f.Write(lines.Bytes()); err != nil {
+            f.Close()
+            return err
+        }
+        if err := f.Sync(); err != nil {
+            f.Close()
+            return err
+        }
+        return f.Close()
+    })
+}
+
+// encodeAuditEntry encodes an entry as a log line, sealed when the profile is encrypted
+func encodeAuditEntry(id string, entry *AuditEntry) ([]byte, error) {
+    plain, err := json.Marshal(entry)
+    if err != nil {
+        return nil, err
+    }
+    return sealRecord(id, auditDir, plain)
+}
+
+// readAuditEntries reads the whole audit log of a profile, oldest first.
+// Sealed entries need the profile to be unlocked.
+func readAuditEntries(id string) ([]AuditEntry, error) {
+    content, err := os.ReadFile(auditLogPath(id))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var entries []AuditEntry
+    scanner := bufio.NewScanner(bytes.NewReader(content))
+    scanner.Buffer(nil, len(content)+1)
+    for n := 1; scanner.Scan(); n++ {
+        plain, err := openRecord(id, auditDir, scanner.Bytes())
+        if errors.Is(err, ErrProfileLocked) {
+            return nil, err
+        }
+        var entry AuditEntry
+        if err == nil {
+            err = json.Unmarshal(plain, &entry)
+        }
+        if err != nil {
+            // A crash can leave the last line partial
+            log.Printf("Skipping line %d of the audit log of %s: %v", n, id, err)
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries, scanner.Err()
+}
+
+// rewriteAuditLog replaces the audit log of a profile, sealing the entries
+// with the profile's current key, after its passphrase changed
+func rewriteAuditLog(id string, entries []AuditEntry) error {
+    if len(entries) == 0 {
+        return nil
+    }
+
+    auditMutex.Lock()
+    defer auditMutex.Unlock()
+
+    var lines bytes.Buffer
+    for i := range entries {
+        line, err := encodeAuditEntry(id, &entries[i])
+        if err != nil {
+            return err
+        }
+        lines.Write(line)
+        lines.WriteByte('\n')
+    }
+    return withFileLock(DataDirectory(), func() error {
+        return writeFileAtomic(auditLogPath(id), lines.Bytes(), 0644)
+    })
+}
+
+// removeAuditLog deletes the audit log of a profile
+func removeAuditLog(id string) error {
+    auditMutex.Lock()
+    defer auditMutex.Unlock()
+
+    err := os.Remove(auditLogPath(id))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    return err
+}
+
+// ReadAuditLog returns the entries of the audit log of a profile matching a
+// filter, oldest first. With an account, entries only keep the changes made
+// to that account and entries without any are left out.
+func ReadAuditLog(profileID string, filter AuditFilter) ([]AuditEntry, error) {
+    entries, err := readAuditEntries(profileID)
+    if err != nil {
+        return nil, err
+    }
+
+    var matching []AuditEntry
+    for _, entry := range entries {
+        if !inTimeRange(entry.Time, filter.From, filter.To) {
+            continue
+        }
+        if filter.AccountID != "" {
+            entry.Changes = slices.DeleteFunc(slices.Clone(entry.Changes), func(change AuditChange) bool {
+                return len(change.Path) < 2 || change.Path[0] != "accounts" || change.Path[1] != filter.AccountID
+            })
+            if len(entry.Changes) == 0 {
+                continue
+            }
+        }
+        matching = append(matching, entry)
+    }
+    return matching, nil
+}
+
+// ProfileAsOf rebuilds a profile as it was saved at a point in time by
+// replaying its audit log
+func ProfileAsOf(profileID string, at time.Time) (*models.Profile, error) {
+    entries, err := readAuditEntries(profileID)
+    if err != nil {
+        return nil, err
+    }
+
+    var state any
+    var savedAt time.Time
+    for _, entry := range entries {
+        if entry.Time.After(at) {
+            break
+        }
+        for _, change := range entry.Changes {
+            if state, err = applyAuditChange(state, change.Path, change); err != nil {
+                return nil, fmt.Errorf("failed to replay the audit log of %s at %s: %w",
+                    profileID, entry.Time.Format(time.RFC3339), err)
+            }
+        }
+        savedAt = entry.Time
+    }
+    if state == nil {
+        return nil, fmt.Errorf("the audit log of %s has nothing before %s", profileID, at.Format("2006-01-02 15:04"))
+    }
+
+    content, err := json.Marshal(state)
+    if err != nil {
+        return nil, err
+    }
+    profile, err := decodeProfile(content)
+    if err != nil {
+        return nil, err
+    }
+    profile.LastModified = savedAt
+    return profile, nil
+}
@@ -0,0 +1,295 @@
+// File: internal/data/audit_diff.go
+package data
+
+import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "slices"
+)
+
+// Kinds of change recorded in the audit log
+const (
+    AuditAdd     = "add"
+    AuditRemove  = "remove"
+    AuditReplace = "replace"
+)
+
+// auditIgnoredFields change on every save and are left out of the diffs
+var auditIgnoredFields = []string{"last_modified", "schema_version"}
+
+// AuditChange is one difference between two versions of a profile. Path leads
+// to the changed value through object fields and, in lists of accounts,
+// positions, transactions or watchlists, through the ID or symbol of the
+// element. An empty path is the whole profile.
+type AuditChange struct {
+    Op    string          `json:"op"`
+    Path  []string        `json:"path"`
+    Index int             `json:"index,omitempty"` // position of an element added to a list
+    Old   json.RawMessage `json:"old,omitempty"`
+    New   json.RawMessage `json:"new,omitempty"`
+}
+
+// diffProfiles returns the changes turning the encoding of one profile into
+// another. A nil before profile yields a single change adding the whole profile.
+func diffProfiles(before, after []byte) ([]AuditChange, error) {
+    var a, b map[string]any
+    if before != nil {
+        if err := json.Unmarshal(before, &a); err != nil {
+            return nil, err
+        }
+    }
+    if err := json.Unmarshal(after, &b); err != nil {
+        return nil, err
+    }
+
+    if a == nil {
+        return []AuditChange{{Op: AuditAdd, Path: []string{}, New: after}}, nil
+    }
+    for _, field := range auditIgnoredFields {
+        delete(a, field)
+        delete(b, field)
+    }
+
+    var changes []AuditChange
+    if err := diffValues(nil, a, b, &changes); err != nil {
+        return nil, err
+    }
+    return changes, nil
+}
+
+// diffValues appends the changes from a to b found under path
+func diffValues(path []string, a, b any, changes *[]AuditChange) error {
+    switch a := a.(type) {
+    case map[string]any:
+        if b, ok := b.(map[string]any); ok {
+            return diffObjects(path, a, b, changes)
+        }
+    case []any:
+        if b, ok := b.([]any); ok && isKeyedList(a) && isKeyedList(b) {
+            return diffKeyedLists(path, a, b, changes)
+        }
+    }
+
+    if reflect.DeepEqual(a, b) {
+        return nil
+    }
+    change, err := newAuditChange(AuditReplace, path, a, b)
+    if err != nil {
+        return err
+    }
+    *changes = append(*changes, change)
+    return nil
+}
+
+// diffObjects compares the fields of two objects
+func diffObjects(path []string, a, b map[string]any, changes *[]AuditChange) error {
+    var keys []string
+    for key := range a {
+        keys = append(keys, key)
+    }
+    for key := range b {
+        if _, ok := a[key]; !ok {
+            keys = append(keys, key)
+        }
+    }
+    slices.Sort(keys)
+
+    for _, key := range keys {
+        av, inA := a[key]
+        bv, inB := b[key]
+        var err error
+        switch {
+        case !inB:
+            err = appendAuditChange(changes, AuditRemove, appendPath(path, key), av, nil)
+        case !inA:
+            err = appendAuditChange(changes, AuditAdd, appendPath(path, key), nil, bv)
+        default:
+            err = diffValues(appendPath(path, key), av, bv, changes)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// diffKeyedLists compares two lists of elements identified by their keys.
+// Removals come first and additions last, in increasing index, so applying
+// the changes in order rebuilds b. A reordered list is replaced as a whole.
+func diffKeyedLists(path []string, a, b []any, changes *[]AuditChange) error {
+    aKeys, bKeys := listKeys(a), listKeys(b)
+
+    // The elements kept must stay in the same order
+    var keptA, keptB []string
+    for _, key := range aKeys {
+        if slices.Contains(bKeys, key) {
+            keptA = append(keptA, key)
+        }
+    }
+    for _, key := range bKeys {
+        if slices.Contains(aKeys, key) {
+            keptB = append(keptB, key)
+        }
+    }
+    if !slices.Equal(keptA, keptB) {
+        return appendAuditChange(changes, AuditReplace, path, a, b)
+    }
+
+    for i, key := range aKeys {
+        if !slices.Contains(bKeys, key) {
+            if err := appendAuditChange(changes, AuditRemove, appendPath(path, key), a[i], nil); err != nil {
+                return err
+            }
+        }
+    }
+    for _, key := range keptA {
+        if err := diffValues(appendPath(path, key), a[slices.Index(aKeys, key)], b[slices.Index(bKeys, key)], changes); err != nil {
+            return err
+        }
+    }
+    for i, key := range bKeys {
+        if slices.Contains(aKeys, key) {
+            continue
+        }
+        change, err := newAuditChange(AuditAdd, appendPath(path, key), nil, b[i])
+        if err != nil {
+            return err
+        }
+        change.Index = i
+        *changes = append(*changes, change)
+    }
+    return nil
+}
+
+// elementKey returns what identifies an element of a list: its ID, or the
+// symbol of a position
+func elementKey(element any) (string, bool) {
+    object, ok := element.(map[string]any)
+    if !ok {
+        return "", false
+    }
+    for _, field := range []string{"id", "stock_symbol"} {
+        if key, ok := object[field].(string); ok && key != "" {
+            return key, true
+        }
+    }
+    return "", false
+}
+
+// isKeyedList reports whether every element of a list has a distinct key
+func isKeyedList(list []any) bool {
+    seen := make(map[string]bool)
+    for _, element := range list {
+        key, ok := elementKey(element)
+        if !ok || seen[key] {
+            return false
+        }
+        seen[key] = true
+    }
+    return true
+}
+
+// listKeys returns the keys of the elements of a keyed list
+func listKeys(list []any) []string {
+    keys := make([]string, len(list))
+    for i, element := range list {
+        keys[i], _ = elementKey(element)
+    }
+    return keys
+}
+
+// appendPath returns path with key added, never sharing path's backing array
+func appendPath(path []string, key string) []string {
+    return append(slices.Clip(path), key)
+}
+
+// appendAuditChange appends a change between two decoded values
+func appendAuditChange(changes *[]AuditChange, op string, path []string, before, after any) error {
+    change, err := newAuditChange(op, path, before, after)
+    if err != nil {
+        return err
+    }
+    *changes = append(*changes, change)
+    return nil
+}
+
+// newAuditChange encodes a change between two decoded values, nil being absent
+func newAuditChange(op string, path []string, before, after any) (AuditChange, error) {
+    change := AuditChange{Op: op, Path: path}
+    var err error
+    if before != nil {
+        if change.Old, err = json.Marshal(before); err != nil {
+            return change, err
+        }
+    }
+    if after != nil {
+        if change.New, err = json.Marshal(after); err != nil {
+            return change, err
+        }
+    }
+    return change, nil
+}
+
+// applyAuditChange applies a change to a decoded profile and returns the result
+func applyAuditChange(node any, path []string, change AuditChange) (any, error) {
+    if len(path) == 0 {
+        if change.Op == AuditRemove || len(change.New) == 0 {
+            return nil, nil
+        }
+        var value any
+        if err := json.Unmarshal(change.New, &value); err != nil {
+            return nil, err
+        }
+        return value, nil
+    }
+
+    key := path[0]
+    switch n := node.(type) {
+    case map[string]any:
+        if len(path) == 1 && change.Op == AuditRemove {
+            delete(n, key)
+            return n, nil
+        }
+        child, err := applyAuditChange(n[key], path[1:], change)
+        if err != nil {
+            return nil, err
+        }
+        n[key] = child
+        return n, nil
+
+    case []any:
+        i := slices.Index(listKeys(n), key)
+        if len(path) == 1 && change.Op == AuditAdd {
+            element, err := applyAuditChange(nil, nil, change)
+            if err != nil {
+                return nil, err
+            }
+            // An element added again, as merged changes can, replaces the first one
+            if i >= 0 {
+                n[i] = element
+                return n, nil
+            }
+            return slices.Insert(n, min(max(change.Index, 0), len(n)), element), nil
+        }
+        if i < 0 {
+            return nil, fmt.Errorf("no element %q in the list", key)
+        }
+        if len(path) == 1 && change.Op == AuditRemove {
+            return slices.Delete(n, i, i+1), nil
+        }
+        child, err := applyAuditChange(n[i], path[1:], change)
+        if err != nil {
+            return nil, err
+        }
+        n[i] = child
+        return n, nil
+
+    case nil:
+        // A field being added inside an object that doesn't exist yet
+        return applyAuditChange(map[string]any{}, path, change)
+
+    default:
+        return nil, fmt.Errorf("can't follow %q into a %T", key, node)
+    }
+}
@@ -0,0 +1,115 @@
+// File: internal/data/audit_test.go
+package data
+
+import (
+    "os"
+    "slices"
+    "testing"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// profileSummary is what the replay tests compare of a profile
+type profileSummary struct {
+    name     string
+    accounts []string
+    symbols  []string
+}
+
+// summarize returns the name, account IDs and watchlist symbols of a profile
+func summarize(profile *models.Profile) profileSummary {
+    summary := profileSummary{name: profile.Name}
+    for _, account := range profile.Accounts {
+        summary.accounts = append(summary.accounts, account.ID)
+    }
+    for _, watchlist := range profile.Watchlists {
+        summary.symbols = append(summary.symbols, watchlist.Symbols...)
+    }
+    return summary
+}
+
+func (s profileSummary) equal(other profileSummary) bool {
+    return s.name == other.name && slices.Equal(s.accounts, other.accounts) && slices.Equal(s.symbols, other.symbols)
+}
+
+func TestProfileAsOf(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    type version struct {
+        savedAt time.Time
+        summary profileSummary
+    }
+    versions := []version{{profile.LastModified, summarize(profile)}}
+    for _, edit := range []func(){
+        func() { profile.Name = "v1" },
+        func() {
+            if err := portfolio.AddAccount(profile, "cash", "Cash", models.AccountNonRegistered); err != nil {
+                t.Fatal(err)
+            }
+        },
+        func() { profile.Watchlists[0].Symbols = append(profile.Watchlists[0].Symbols, "XEQT", "VFV") },
+        func() { profile.Accounts = profile.Accounts[:0] },
+        func() { profile.Watchlists[0].Symbols = profile.Watchlists[0].Symbols[1:] },
+    } {
+        edit()
+        if err := SaveProfile(profile); err != nil {
+            t.Fatal(err)
+        }
+        versions = append(versions, version{profile.LastModified, summarize(profile)})
+    }
+
+    for i, v := range versions {
+        past, err := ProfileAsOf(profile.ID, v.savedAt)
+        if err != nil {
+            t.Fatalf("ProfileAsOf() version %d error = %v", i, err)
+        }
+        if got := summarize(past); !got.equal(v.summary) {
+            t.Errorf("ProfileAsOf() version %d = %+v, want %+v", i, got, v.summary)
+        }
+        if !past.LastModified.Equal(v.savedAt) {
+            t.Errorf("ProfileAsOf() version %d was saved at %s, want %s", i, past.LastModified, v.savedAt)
+        }
+    }
+
+    if _, err := ProfileAsOf(profile.ID, versions[0].savedAt.Add(-time.Second)); err == nil {
+        t.Error("ProfileAsOf() before the profile was created succeeded")
+    }
+}
+
+func TestProfileAsOfStartsFromBaseline(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    profile.Watchlists[0].Symbols = []string{"XEQT"}
+    if err := SaveProfile(profile); err != nil {
+        t.Fatal(err)
+    }
+    before := summarize(profile)
+    savedAt := profile.LastModified
+
+    // A log lost or started by an update begins with the whole previous version
+    if err := os.Remove(auditLogPath(profile.ID)); err != nil {
+        t.Fatal(err)
+    }
+    saveNames(t, profile, "v1")
+
+    past, err := ProfileAsOf(profile.ID, savedAt)
+    if err != nil {
+        t.Fatalf("ProfileAsOf() error = %v", err)
+    }
+    if got := summarize(past); !got.equal(before) {
+        t.Errorf("ProfileAsOf() = %+v, want the version before the log started %+v", got, before)
+    }
+    current, err := ProfileAsOf(profile.ID, time.Now())
+    if err != nil || current.Name != "v1" {
+        t.Errorf("ProfileAsOf() now = %+v, %v; want v1", current, err)
+    }
+}
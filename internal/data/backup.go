@@ -0,0 +1,248 @@
+// File: internal/data/backup.go
+package data
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// ProfileBackupCount is the number of previous versions kept for each profile
+const ProfileBackupCount = 5
+
+// backupsDir holds the backups of each profile, under the profiles directory
+const backupsDir = "backups"
+
+// trashDir holds deleted profiles until they are restored or purged, under the profiles directory
+const trashDir = "trash"
+
+// profilePath returns the primary file of a profile
+func profilePath(id string) string {
+    return filepath.Join(dataDirectory, profilesDir, id+".json")
+}
+
+// trashedProfilePath returns the file of a profile in the trash
+func trashedProfilePath(id string) string {
+    return filepath.Join(dataDirectory, profilesDir, trashDir, id+".json")
+}
+
+// profileBackupPath returns the n-th most recent backup of a profile, starting at 1
+func profileBackupPath(id string, n int) string {
+    return filepath.Join(dataDirectory, profilesDir, backupsDir, id, fmt.Sprintf("%s.json.%d", id, n))
+}
+
+// rotateProfileBackups shifts the backups of a profile by one and copies the
+// current primary file in as the most recent backup. A primary file that
+// doesn't decode is not backed up so it can't push out good versions.
+// The caller must hold storageMutex for writing.
+func rotateProfileBackups(id string) error {
+    current, err := os.ReadFile(profilePath(id))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if _, err := decodeProfile(current); err != nil {
+        return nil
+    }
+
+    if err := os.MkdirAll(filepath.Dir(profileBackupPath(id, 1)), 0755); err != nil {
+        return err
+    }
+
+    // Drop the oldest backup and shift the others
+    os.Remove(profileBackupPath(id, ProfileBackupCount))
+    for n := ProfileBackupCount - 1; n >= 1; n-- {
+        if err := os.Rename(profileBackupPath(id, n), profileBackupPath(id, n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+            return err
+        }
+    }
+
+    return writeFileAtomic(profileBackupPath(id, 1), current, 0644)
+}
+
+// dropPlaintextBackups removes the backups of a profile whose primary file
+// isn't encrypted, so a profile being encrypted leaves no readable copy
+// behind. It reports whether they were dropped, in which case the primary
+// file must not be backed up either. The caller must hold storageMutex for writing.
+func dropPlaintextBackups(id string) (bool, error) {
+    current, err := os.ReadFile(profilePath(id))
+    if errors.Is(err, os.ErrNotExist) {
+        return false, nil
+    }
+    if err != nil || isEncryptedProfile(current) {
+        return false, err
+    }
+
+    return true, os.RemoveAll(filepath.Join(dataDirectory, profilesDir, backupsDir, id))
+}
+
+// resealProfileBackups seals the backups of a profile, sealed with previous,
+// again with next, or leaves them in the clear when next is nil. Backups that
+// can't be sealed again are removed so none keeps needing the old passphrase.
+// The caller must hold storageMutex for writing.
+func resealProfileBackups(id string, previous, next *profileKey) {
+    dir := filepath.Join(dataDirectory, profilesDir, backupsDir, id)
+    files, err := os.ReadDir(dir)
+    if err != nil {
+        return
+    }
+
+    for _, file := range files {
+        if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+            continue
+        }
+        path := filepath.Join(dir, file.Name())
+        content, err := os.ReadFile(path)
+        if err == nil {
+            content, err = resealProfile(content, previous, next)
+        }
+        if err == nil {
+            err = writeFileAtomic(path, content, 0644)
+        }
+        if err != nil {
+            log.Printf("Removing backup %s of profile %s, it can't be sealed with the new passphrase: %v", file.Name(), id, err)
+            os.Remove(path)
+        }
+    }
+}
+
+// loadProfile reads a profile, migrating it to the current schema version and
+// recovering it from the most recent readable backup when the primary file is
+// corrupt. A recovered version replaces the primary file and the corrupt file
+// is kept next to it for inspection. The caller must hold storageMutex for writing.
+func loadProfile(id string) (*models.Profile, error) {
+    content, err := os.ReadFile(profilePath(id))
+    if err != nil {
+        return nil, err
+    }
+
+    profile, migrated, primaryErr := decodeStoredProfile(id, content)
+    if primaryErr == nil {
+        if migrated != nil {
+            if err := storeMigratedProfile(id, content, migrated); err != nil {
+                return nil, err
+            }
+        }
+        return profile, nil
+    }
+
+    // A profile from a newer version isn't corrupt, leave it alone
+    var versionErr *SchemaVersionError
+    if errors.As(primaryErr, &versionErr) {
+        return nil, primaryErr
+    }
+
+    // Try the backups from the most recent one
+    for n := 1; n <= ProfileBackupCount; n++ {
+        backup, err := os.ReadFile(profileBackupPath(id, n))
+        if err != nil {
+            continue
+        }
+        profile, migrated, err := decodeStoredProfile(id, backup)
+        if err != nil {
+            continue
+        }
+
+        log.Printf("Profile %s is corrupt (%v), recovered it from backup %d", id, primaryErr, n)
+        corruptPath := fmt.Sprintf("%s.corrupt-%s", profilePath(id), time.Now().Format("20060102-150405"))
+        if err := os.Rename(profilePath(id), corruptPath); err != nil {
+            return nil, err
+        }
+        if migrated != nil {
+            err = storeMigratedProfile(id, backup, migrated)
+        } else {
+            err = writeFileAtomic(profilePath(id), backup, 0644)
+        }
+        if err != nil {
+            return nil, err
+        }
+        return profile, nil
+    }
+
+    return nil, fmt.Errorf("profile %s is corrupt and has no readable backup: %w", id, primaryErr)
+}
+
+// decodeStoredProfile decodes a stored profile, decrypting it when it is
+// unlocked and migrating it first when it has an older schema version. The
+// migrated encoding, encrypted again if needed, is returned so it can be
+// stored. It is nil when no migration was needed. Locked profiles decode to
+// a stub with only their ID and name.
+func decodeStoredProfile(id string, content []byte) (*models.Profile, []byte, error) {
+    plain, stub, err := openProfile(id, content)
+    if errors.Is(err, ErrProfileLocked) {
+        return stub, nil, nil
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+
+    current, err := migrateProfile(id, plain)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    profile, err := decodeProfile(current)
+    if err != nil {
+        return nil, nil, err
+    }
+    profile.Encrypted = isEncryptedProfile(content)
+
+    if bytes.Equal(current, plain) {
+        return profile, nil, nil
+    }
+    if profile.Encrypted {
+        current, err = sealProfile(profile, current)
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+    return profile, current, nil
+}
+
+// storeMigratedProfile keeps the pre-migration version of a profile in its
+// backups directory, named after its schema version so rotation never drops
+// it, then replaces the primary file with the migrated version.
+// The caller must hold storageMutex for writing.
+func storeMigratedProfile(id string, original, migrated []byte) error {
+    plain, _, err := openProfile(id, original)
+    if err != nil {
+        return err
+    }
+    version, err := profileSchemaVersion(plain)
+    if err != nil {
+        return err
+    }
+
+    backupPath := filepath.Join(dataDirectory, profilesDir, backupsDir, id, fmt.Sprintf("%s.json.v%d", id, version))
+    if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+        return err
+    }
+    if err := writeFileAtomic(backupPath, original, 0644); err != nil {
+        return fmt.Errorf("failed to back up profile %s before migrating: %w", id, err)
+    }
+
+    log.Printf("Migrated profile %s from schema version %d to %d", id, version, ProfileSchemaVersion)
+    return writeFileAtomic(profilePath(id), migrated, 0644)
+}
+
+// decodeProfile decodes the JSON of a profile
+func decodeProfile(content []byte) (*models.Profile, error) {
+    var profile models.Profile
+    if err := json.Unmarshal(content, &profile); err != nil {
+        return nil, err
+    }
+    if profile.ID == "" {
+        return nil, errors.New("profile has no ID")
+    }
+    return &profile, nil
+}
@@ -0,0 +1,238 @@
+// File: internal/data/backup_test.go
+package data
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// useTempStorage points the storage at empty directories for the length of a
+// test, opening the given backend
+func useTempStorage(t *testing.T, name string) {
+    t.Helper()
+    storageMutex.Lock()
+    dataDir, cacheDir, backendName := dataDirectory, cacheDirectory, storageBackend
+    dataDirectory, cacheDirectory, storageBackend = t.TempDir(), t.TempDir(), name
+    storageMutex.Unlock()
+    previous := currentStorage()
+
+    t.Cleanup(func() {
+        setStorage(previous)
+        storageMutex.Lock()
+        dataDirectory, cacheDirectory, storageBackend = dataDir, cacheDir, backendName
+        storageMutex.Unlock()
+    })
+    if err := Initialize(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// saveNames saves a profile once under each name
+func saveNames(t *testing.T, profile *models.Profile, names ...string) {
+    t.Helper()
+    for _, name := range names {
+        profile.Name = name
+        if err := SaveProfile(profile); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+// backupName returns the name of the profile held by a backup, empty when there is none
+func backupName(t *testing.T, id string, n int) string {
+    t.Helper()
+    content, err := os.ReadFile(profileBackupPath(id, n))
+    if os.IsNotExist(err) {
+        return ""
+    }
+    if err != nil {
+        t.Fatal(err)
+    }
+    profile, err := decodeProfile(content)
+    if err != nil {
+        t.Fatalf("backup %d doesn't decode: %v", n, err)
+    }
+    return profile.Name
+}
+
+func TestSaveProfileRotatesBackups(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var names []string
+    for n := 1; n <= ProfileBackupCount+2; n++ {
+        names = append(names, fmt.Sprintf("v%d", n))
+    }
+    saveNames(t, profile, names...)
+
+    // The most recent backup is the version before the last save
+    for n := 1; n <= ProfileBackupCount; n++ {
+        want := fmt.Sprintf("v%d", len(names)-n)
+        if got := backupName(t, profile.ID, n); got != want {
+            t.Errorf("backup %d holds %q, want %q", n, got, want)
+        }
+    }
+    if got := backupName(t, profile.ID, ProfileBackupCount+1); got != "" {
+        t.Errorf("backup %d exists with %q, want only %d backups", ProfileBackupCount+1, got, ProfileBackupCount)
+    }
+}
+
+func TestRotateProfileBackupsSkipsCorruptPrimary(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    saveNames(t, profile, "v1")
+
+    if err := os.WriteFile(profilePath(profile.ID), []byte(`{"id": "`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    storageMutex.Lock()
+    err = rotateProfileBackups(profile.ID)
+    storageMutex.Unlock()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if got := backupName(t, profile.ID, 1); got != "v0" {
+        t.Errorf("backup 1 holds %q after rotating a corrupt primary, want v0", got)
+    }
+    if got := backupName(t, profile.ID, 2); got != "" {
+        t.Errorf("backup 2 exists with %q, want the backups left alone", got)
+    }
+}
+
+func TestLoadProfileRecoversTruncatedPrimary(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    saveNames(t, profile, "v1", "v2")
+
+    content, err := os.ReadFile(profilePath(profile.ID))
+    if err != nil {
+        t.Fatal(err)
+    }
+    truncated := content[:len(content)/2]
+    if err := os.WriteFile(profilePath(profile.ID), truncated, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    recovered, err := GetProfileByID(profile.ID)
+    if err != nil {
+        t.Fatalf("GetProfileByID() error = %v", err)
+    }
+    if recovered.Name != "v1" {
+        t.Errorf("recovered %q, want the most recent backup v1", recovered.Name)
+    }
+
+    // The primary holds the recovered version and the corrupt one is kept aside
+    primary, err := os.ReadFile(profilePath(profile.ID))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if stored, err := decodeProfile(primary); err != nil || stored.Name != "v1" {
+        t.Errorf("primary after recovery = %v, %v; want v1", stored, err)
+    }
+    corrupt, err := filepath.Glob(profilePath(profile.ID) + ".corrupt-*")
+    if err != nil || len(corrupt) != 1 {
+        t.Fatalf("corrupt copies = %v, %v; want one", corrupt, err)
+    }
+    if kept, err := os.ReadFile(corrupt[0]); err != nil || !bytes.Equal(kept, truncated) {
+        t.Errorf("corrupt copy doesn't hold the truncated primary (err = %v)", err)
+    }
+}
+
+func TestLoadProfileWithoutReadableBackup(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("v0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(profilePath(profile.ID), []byte("{"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := GetProfileByID(profile.ID); err == nil {
+        t.Fatal("GetProfileByID() of a corrupt profile without backups succeeded")
+    }
+    if content, err := os.ReadFile(profilePath(profile.ID)); err != nil || string(content) != "{" {
+        t.Errorf("corrupt primary was changed: %q, %v", content, err)
+    }
+}
+
+func TestLoadProfileKeepsPreMigrationVersion(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    original := legacyProfile(`{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 100, "positions": []}`)
+    if err := os.WriteFile(profilePath("profile"), original, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    profile, err := GetProfileByID("profile")
+    if err != nil {
+        t.Fatalf("GetProfileByID() error = %v", err)
+    }
+    if profile.SchemaVersion != ProfileSchemaVersion {
+        t.Errorf("loaded schema version %d, want %d", profile.SchemaVersion, ProfileSchemaVersion)
+    }
+
+    backup, err := os.ReadFile(filepath.Join(dataDirectory, profilesDir, backupsDir, "profile", "profile.json.v1"))
+    if err != nil || !bytes.Equal(backup, original) {
+        t.Errorf("pre-migration backup doesn't hold the original file (err = %v)", err)
+    }
+    primary, err := os.ReadFile(profilePath("profile"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if version, err := profileSchemaVersion(primary); err != nil || version != ProfileSchemaVersion {
+        t.Errorf("primary has schema version %d (err = %v), want %d", version, err, ProfileSchemaVersion)
+    }
+}
+
+func TestDeleteProfileKeepsTrashedCopy(t *testing.T) {
+    for _, backendName := range []string{StorageJSON, StorageBolt} {
+        t.Run(backendName, func(t *testing.T) {
+            useTempStorage(t, backendName)
+            first, err := CreateProfile("first")
+            if err != nil {
+                t.Fatal(err)
+            }
+            if err := DeleteProfile(first.ID); err != nil {
+                t.Fatal(err)
+            }
+
+            // New profiles don't take the ID of a trashed one
+            if created, err := CreateProfile("created"); err != nil || created.ID == first.ID {
+                t.Errorf("CreateProfile() = %v, %v; want an ID other than the trashed %s", created, err, first.ID)
+            }
+
+            // A profile with the trashed ID, as brought back by an import
+            second := &models.Profile{ID: first.ID, Name: "second"}
+            if err := SaveProfile(second); err != nil {
+                t.Fatal(err)
+            }
+            if err := DeleteProfile(first.ID); !errors.Is(err, ErrProfileExists) {
+                t.Errorf("DeleteProfile() over a trashed copy error = %v, want ErrProfileExists", err)
+            }
+
+            trashed, err := TrashedProfiles()
+            if err != nil || len(trashed) != 1 || trashed[0].Name != "first" {
+                t.Errorf("trash = %+v, %v; want only first", trashed, err)
+            }
+            if profile, err := GetProfileByID(first.ID); err != nil || profile.Name != "second" {
+                t.Errorf("GetProfileByID() = %+v, %v; want second kept in place", profile, err)
+            }
+        })
+    }
+}
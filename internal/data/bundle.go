@@ -0,0 +1,395 @@
+// File: internal/data/bundle.go
+package data
+
+import (
+    "archive/zip"
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path"
+    "slices"
+    "strings"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// BundleFormat is the version of the profile bundle layout
+const BundleFormat = 1
+
+// Files inside a profile bundle
+const (
+    bundleManifestFile = "manifest.json"
+    bundleProfileFile  = "profile.json"
+    bundleDrawingsDir  = "drawings"
+    bundleCandlesDir   = "candles"
+)
+
+// Limits on what is read from a bundle, well above what an export writes, so
+// a damaged or hostile archive can't exhaust memory
+const (
+    maxBundleFileSize  = 64 << 20  // each file, uncompressed
+    maxBundleTotalSize = 256 << 20 // all files together
+)
+
+// ErrProfileExists is returned when a bundle holds a profile whose ID is already in use
+var ErrProfileExists = errors.New("a profile with this ID already exists")
+
+// BundleManifest describes the content of a profile bundle
+type BundleManifest struct {
+    Format        int          `json:"format"`
+    ExportedAt    time.Time    `json:"exported_at"`
+    ProfileID     string       `json:"profile_id"`
+    ProfileName   string       `json:"profile_name"`
+    SchemaVersion int          `json:"schema_version"`
+    Encrypted     bool         `json:"encrypted"` // profile.json is sealed with the profile's passphrase
+    Files         []BundleFile `json:"files"`
+}
+
+// BundleFile is a file of a bundle with its SHA-256 checksum
+type BundleFile struct {
+    Path   string `json:"path"`
+    Size   int    `json:"size"`
+    SHA256 string `json:"sha256"`
+}
+
+// ProfileBundle is a profile bundle read into memory, with its checksums verified
+type ProfileBundle struct {
+    Manifest BundleManifest
+    files    map[string][]byte
+}
+
+// ImportOptions control how a bundle is imported
+type ImportOptions struct {
+    // Name, when set, imports the profile as a new profile with this name and
+    // a fresh ID, as needed when its ID is already in use
+    Name string
+
+    // Passphrase decrypts an encrypted profile, which keeps it afterwards
+    Passphrase string
+}
+
+// ExportProfile writes a zip archive holding a profile, the drawings of the
+// symbols it uses and, with includeCandles, their cached candles. An encrypted
+// profile must be unlocked and stays encrypted in the archive.
+func ExportProfile(profileID, target string, includeCandles bool) error {
+    profile, err := GetProfileByID(profileID)
+    if err != nil {
+        return err
+    }
+    if profile.Locked {
+        return fmt.Errorf("profile %s: %w", profileID, ErrProfileLocked)
+    }
+
+    files := make(map[string][]byte)
+    profileData, err := json.MarshalIndent(profile, "", "  ")
+    if err != nil {
+        return err
+    }
+    if files[bundleProfileFile], err = sealProfile(profile, profileData); err != nil {
+        return err
+    }
+
+    for _, symbol := range profileSymbols(profile) {
+        drawings, err := LoadDrawings(symbol)
+        if err == nil && len(drawings) > 0 {
+            if files[path.Join(bundleDrawingsDir, symbol+".json")], err = json.MarshalIndent(drawings, "", "  "); err != nil {
+                return err
+            }
+        }
+
+        if !includeCandles {
+            continue
+        }
+        for _, timeframe := range Timeframes {
+            candles, err := LoadCandleData(symbol, timeframe)
+            if err != nil || len(candles.Candles) == 0 {
+                continue
+            }
+            if files[path.Join(bundleCandlesDir, symbol+"_"+timeframe+".json")], err = json.Marshal(candles); err != nil {
+                return err
+            }
+        }
+    }
+
+    manifest := BundleManifest{
+        Format:        BundleFormat,
+        ExportedAt:    time.Now(),
+        ProfileID:     profile.ID,
+        ProfileName:   profile.Name,
+        SchemaVersion: profile.SchemaVersion,
+        Encrypted:     unlockedKey(profile.ID) != nil,
+    }
+    names := make([]string, 0, len(files))
+    for name := range files {
+        names = append(names, name)
+    }
+    slices.Sort(names)
+    for _, name := range names {
+        sum := sha256.Sum256(files[name])
+        manifest.Files = append(manifest.Files, BundleFile{
+            Path:   name,
+            Size:   len(files[name]),
+            SHA256: hex.EncodeToString(sum[:]),
+        })
+    }
+    manifestData, err := json.MarshalIndent(manifest, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    // Build the archive in memory so a failed export leaves no partial file
+    var archive bytes.Buffer
+    writer := zip.NewWriter(&archive)
+    for _, name := range append([]string{bundleManifestFile}, names...) {
+        content := manifestData
+        if name != bundleManifestFile {
+            content = files[name]
+        }
+        w, err := writer.Create(name)
+        if err != nil {
+            return err
+        }
+        if _, err := w.Write(content); err != nil {
+            return err
+        }
+    }
+    if err := writer.Close(); err != nil {
+        return err
+    }
+
+    return writeFileAtomic(target, archive.Bytes(), 0644)
+}
+
+// profileSymbols returns the symbols a profile holds or watches
+func profileSymbols(profile *models.Profile) []string {
+    var symbols []string
+    for _, account := range profile.Accounts {
+        for _, position := range account.Positions {
+            symbols = append(symbols, position.StockSymbol)
+        }
+    }
+    for _, watchlist := range profile.Watchlists {
+        symbols = append(symbols, watchlist.Symbols...)
+    }
+
+    slices.Sort(symbols)
+    return slices.Compact(symbols)
+}
+
+// OpenProfileBundle reads a bundle and checks every file against the
+// checksums of its manifest
+func OpenProfileBundle(source string) (*ProfileBundle, error) {
+    reader, err := zip.OpenReader(source)
+    if err != nil {
+        return nil, err
+    }
+    defer reader.Close()
+
+    contents := make(map[string][]byte)
+    total := 0
+    for _, file := range reader.File {
+        if file.UncompressedSize64 > maxBundleFileSize {
+            return nil, fmt.Errorf("%s in the bundle is larger than %d MB", file.Name, maxBundleFileSize>>20)
+        }
+        f, err := file.Open()
+        if err != nil {
+            return nil, err
+        }
+
+        // The recorded size can't be trusted, so the reads are capped as well
+        content, err := io.ReadAll(io.LimitReader(f, maxBundleFileSize+1))
+        f.Close()
+        if err != nil {
+            return nil, fmt.Errorf("failed to read %s from the bundle: %w", file.Name, err)
+        }
+        if len(content) > maxBundleFileSize {
+            return nil, fmt.Errorf("%s in the bundle is larger than %d MB", file.Name, maxBundleFileSize>>20)
+        }
+        if total += len(content); total > maxBundleTotalSize {
+            return nil, fmt.Errorf("the bundle holds more than %d MB", maxBundleTotalSize>>20)
+        }
+        contents[file.Name] = content
+    }
+
+    manifestData, ok := contents[bundleManifestFile]
+    if !ok {
+        return nil, errors.New("not a profile bundle, it has no manifest")
+    }
+    bundle := &ProfileBundle{files: make(map[string][]byte)}
+    if err := json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+        return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+    }
+    if bundle.Manifest.Format > BundleFormat {
+        return nil, fmt.Errorf("the bundle has format %d, this version reads up to %d, update Moose Market to import it",
+            bundle.Manifest.Format, BundleFormat)
+    }
+
+    // Only files listed in the manifest with a matching checksum are used
+    for _, file := range bundle.Manifest.Files {
+        content, ok := contents[file.Path]
+        if !ok {
+            return nil, fmt.Errorf("the bundle is missing %s", file.Path)
+        }
+        sum := sha256.Sum256(content)
+        if len(content) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+            return nil, fmt.Errorf("the bundle is damaged, %s doesn't match its checksum", file.Path)
+        }
+        bundle.files[file.Path] = content
+    }
+    if _, ok := bundle.files[bundleProfileFile]; !ok {
+        return nil, errors.New("the bundle holds no profile")
+    }
+
+    return bundle, nil
+}
+
+// ImportProfileBundle reads a bundle and imports it
+func ImportProfileBundle(source string, opts ImportOptions) (*models.Profile, error) {
+    bundle, err := OpenProfileBundle(source)
+    if err != nil {
+        return nil, err
+    }
+    return bundle.Import(opts)
+}
+
+// Import stores the profile of a bundle along with its drawings and candles.
+// When the profile's ID is already in use it fails with ErrProfileExists
+// unless opts.Name is set, which imports it under a fresh ID with new
+// watchlist IDs. Existing drawings are kept and cached candles are only
+// replaced by more recent ones.
+func (b *ProfileBundle) Import(opts ImportOptions) (*models.Profile, error) {
+    content := b.files[bundleProfileFile]
+
+    // Decrypt the profile without touching the keys of local profiles
+    var key *profileKey
+    if isEncryptedProfile(content) {
+        plain, k, err := openWithPassphrase(content, opts.Passphrase)
+        if err != nil {
+            return nil, err
+        }
+        content, key = plain, k
+    }
+
+    current, err := migrateProfile(b.Manifest.ProfileID, content)
+    if err != nil {
+        return nil, err
+    }
+    profile, err := decodeProfile(current)
+    if err != nil {
+        return nil, err
+    }
+    if profile.ID != b.Manifest.ProfileID {
+        return nil, fmt.Errorf("the bundle is damaged, it holds profile %s but its manifest lists %s", profile.ID, b.Manifest.ProfileID)
+    }
+
+    _, err = GetProfileByID(profile.ID)
+    exists := err == nil || !errors.Is(err, os.ErrNotExist)
+    if exists && opts.Name == "" {
+        return nil, fmt.Errorf("profile %s: %w", profile.ID, ErrProfileExists)
+    }
+    if opts.Name != "" {
+        profile.ID = newProfileID()
+        profile.Name = opts.Name
+        rekeyWatchlists(profile)
+    }
+
+    if key != nil {
+        setUnlockedKey(profile.ID, key)
+        profile.Encrypted = true
+    }
+    if err := SaveProfile(profile); err != nil {
+        return nil, err
+    }
+
+    for name, content := range b.files {
+        var err error
+        switch path.Dir(name) {
+        case bundleDrawingsDir:
+            err = importDrawings(strings.TrimSuffix(path.Base(name), ".json"), content)
+        case bundleCandlesDir:
+            err = importCandles(content)
+        }
+        if err != nil {
+            return nil, fmt.Errorf("failed to import %s: %w", name, err)
+        }
+    }
+
+    return profile, nil
+}
+
+// rekeyWatchlists gives the watchlists of a profile new IDs, keeping the default one
+func rekeyWatchlists(profile *models.Profile) {
+    used := make(map[string]bool)
+    for _, watchlist := range profile.Watchlists {
+        used[watchlist.ID] = true
+    }
+
+    n := time.Now().Unix()
+    for i := range profile.Watchlists {
+        for used[fmt.Sprintf("watchlist_%d", n)] {
+            n++
+        }
+        id := fmt.Sprintf("watchlist_%d", n)
+        used[id] = true
+
+        if profile.Settings.DefaultWatchlistID == profile.Watchlists[i].ID {
+            profile.Settings.DefaultWatchlistID = id
+        }
+        profile.Watchlists[i].ID = id
+    }
+}
+
+// importDrawings adds the drawings of a bundle to those already stored for a symbol
+func importDrawings(symbol string, content []byte) error {
+    if err := checkBundleSymbol(symbol); err != nil {
+        return err
+    }
+    var drawings []models.DrawingObject
+    if err := json.Unmarshal(content, &drawings); err != nil {
+        return err
+    }
+
+    existing, _ := LoadDrawings(symbol)
+    merged := existing
+    for _, drawing := range drawings {
+        if !slices.ContainsFunc(existing, func(d models.DrawingObject) bool { return d.ID == drawing.ID }) {
+            merged = append(merged, drawing)
+        }
+    }
+    if len(merged) == len(existing) {
+        return nil
+    }
+    return SaveDrawings(symbol, merged)
+}
+
+// importCandles stores the candles of a bundle unless more recent ones are cached
+func importCandles(content []byte) error {
+    var candles models.CandleData
+    if err := json.Unmarshal(content, &candles); err != nil {
+        return err
+    }
+    if err := checkBundleSymbol(candles.Symbol + candles.Timeframe); err != nil {
+        return err
+    }
+
+    existing, err := LoadCandleData(candles.Symbol, candles.Timeframe)
+    if err == nil && !existing.UpdatedAt.Before(candles.UpdatedAt) {
+        return nil
+    }
+    return SaveCandleData(candles)
+}
+
+// checkBundleSymbol rejects names from a bundle that would lead outside the
+// storage directories once used in a file name
+func checkBundleSymbol(name string) error {
+    if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+        return fmt.Errorf("invalid symbol %q", name)
+    }
+    return nil
+}
@@ -0,0 +1,134 @@
+// File: internal/data/bundle_test.go
+package data
+
+import (
+    "archive/zip"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// writeBundle writes a bundle holding files, listed in a manifest for profileID
+func writeBundle(t *testing.T, profileID string, files map[string][]byte) string {
+    t.Helper()
+    manifest := BundleManifest{Format: BundleFormat, ProfileID: profileID, SchemaVersion: ProfileSchemaVersion}
+    for name, content := range files {
+        sum := sha256.Sum256(content)
+        manifest.Files = append(manifest.Files, BundleFile{Path: name, Size: len(content), SHA256: hex.EncodeToString(sum[:])})
+    }
+    manifestData, err := json.Marshal(manifest)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    target := filepath.Join(t.TempDir(), "bundle.zip")
+    f, err := os.Create(target)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    writer := zip.NewWriter(f)
+    files[bundleManifestFile] = manifestData
+    for name, content := range files {
+        w, err := writer.Create(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if _, err := w.Write(content); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := writer.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return target
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile, err := CreateProfile("Exported")
+    if err != nil {
+        t.Fatal(err)
+    }
+    profile.Watchlists[0].Symbols = []string{"XEQT"}
+    if err := SaveProfile(profile); err != nil {
+        t.Fatal(err)
+    }
+    if err := SaveDrawings("XEQT", []models.DrawingObject{{ID: "d1", Symbol: "XEQT", Type: "line"}}); err != nil {
+        t.Fatal(err)
+    }
+    target := filepath.Join(t.TempDir(), "profile.zip")
+    if err := ExportProfile(profile.ID, target, false); err != nil {
+        t.Fatalf("ExportProfile() error = %v", err)
+    }
+
+    // The ID is in use where the bundle was exported
+    if _, err := ImportProfileBundle(target, ImportOptions{}); !errors.Is(err, ErrProfileExists) {
+        t.Errorf("ImportProfileBundle() over an existing profile error = %v, want ErrProfileExists", err)
+    }
+    copied, err := ImportProfileBundle(target, ImportOptions{Name: "Copy"})
+    if err != nil {
+        t.Fatalf("ImportProfileBundle() as a copy error = %v", err)
+    }
+    if copied.ID == profile.ID || copied.Watchlists[0].ID == profile.Watchlists[0].ID || copied.Name != "Copy" {
+        t.Errorf("copy = %s %q with watchlist %s, want a new ID and name", copied.ID, copied.Name, copied.Watchlists[0].ID)
+    }
+
+    // Another machine gets the same profile and its drawings
+    useTempStorage(t, StorageJSON)
+    imported, err := ImportProfileBundle(target, ImportOptions{})
+    if err != nil {
+        t.Fatalf("ImportProfileBundle() error = %v", err)
+    }
+    if imported.ID != profile.ID || imported.Name != "Exported" || len(imported.Watchlists) != 1 || imported.Watchlists[0].Symbols[0] != "XEQT" {
+        t.Errorf("imported %+v, want the exported profile", imported)
+    }
+    if drawings, err := LoadDrawings("XEQT"); err != nil || len(drawings) != 1 || drawings[0].ID != "d1" {
+        t.Errorf("imported drawings = %+v, %v; want d1", drawings, err)
+    }
+}
+
+func TestExportImportEncrypted(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile := newEncryptedProfile(t, "secret")
+    target := filepath.Join(t.TempDir(), "profile.zip")
+    if err := ExportProfile(profile.ID, target, false); err != nil {
+        t.Fatalf("ExportProfile() error = %v", err)
+    }
+    LockProfile(profile.ID)
+
+    useTempStorage(t, StorageJSON)
+    if _, err := ImportProfileBundle(target, ImportOptions{Passphrase: "wrong"}); !errors.Is(err, ErrWrongPassphrase) {
+        t.Errorf("ImportProfileBundle() with the wrong passphrase error = %v, want ErrWrongPassphrase", err)
+    }
+    imported, err := ImportProfileBundle(target, ImportOptions{Passphrase: "secret"})
+    if err != nil {
+        t.Fatalf("ImportProfileBundle() error = %v", err)
+    }
+    if !imported.Encrypted {
+        t.Error("imported profile lost its encryption")
+    }
+    content, err := os.ReadFile(profilePath(imported.ID))
+    if err != nil || !isEncryptedProfile(content) {
+        t.Errorf("imported profile is stored in the clear (err = %v)", err)
+    }
+}
+
+func TestImportRejectsProfileOtherThanManifest(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    content := legacyProfile(`{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 0, "positions": []}`)
+    source := writeBundle(t, "other", map[string][]byte{bundleProfileFile: content})
+
+    if _, err := ImportProfileBundle(source, ImportOptions{}); err == nil {
+        t.Fatal("ImportProfileBundle() accepted a profile whose ID differs from the manifest")
+    }
+    if profiles, err := GetProfiles(); err != nil || len(profiles) != 0 {
+        t.Errorf("profiles after the refused import = %d, %v; want none", len(profiles), err)
+    }
+}
@@ -0,0 +1,127 @@
+// File: internal/data/candle_cache.go
+package data
+
+import (
+    "context"
+    "errors"
+    "log"
+    "math"
+    "sort"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// CachedProvider keeps candles from another provider on disk and only asks it
+// again once the cached series for a timeframe is older than its TTL
+type CachedProvider struct {
+    MarketDataProvider
+}
+
+// NewCachedProvider wraps a provider with a read-through candle cache
+func NewCachedProvider(provider MarketDataProvider) *CachedProvider {
+    return &CachedProvider{MarketDataProvider: provider}
+}
+
+// Unwrap returns the provider behind the cache
+func (p *CachedProvider) Unwrap() MarketDataProvider {
+    return p.MarketDataProvider
+}
+
+// CandleCacheTTL returns how long cached candles of a timeframe stay fresh
+func CandleCacheTTL(timeframe string) time.Duration {
+    if IsIntradayTimeframe(timeframe) {
+        return time.Minute
+    }
+    return 24 * time.Hour
+}
+
+// GetCandles returns up to count candles, from the cache when it is fresh
+func (p *CachedProvider) GetCandles(ctx context.Context, symbol, timeframe string, count int) (*models.CandleData, error) {
+    cached, err := LoadCandleData(symbol, timeframe)
+    if err != nil {
+        cached = nil
+    }
+
+    // Serve from the cache while it is fresh
+    if cached != nil && time.Since(cached.UpdatedAt) < CandleCacheTTL(timeframe) {
+        return lastCandles(cached, count), nil
+    }
+
+    // Fetch everything the provider returns so the history keeps growing
+    fresh, err := p.MarketDataProvider.GetCandles(ctx, symbol, timeframe, 0)
+    if err != nil {
+        if cached != nil && !errors.Is(err, context.Canceled) {
+            // Stale candles are better than an empty chart
+            log.Printf("Using cached %s %s candles: %v", symbol, timeframe, err)
+            return lastCandles(cached, count), nil
+        }
+        return nil, err
+    }
+
+    merged := models.CandleData{
+        Symbol:    symbol,
+        Timeframe: timeframe,
+        Candles:   fresh.Candles,
+        UpdatedAt: time.Now(),
+    }
+    if cached != nil {
+        merged.Candles = mergeCandles(cached.Candles, fresh.Candles)
+    }
+
+    if err := SaveCandleData(merged); err != nil {
+        log.Printf("Error caching %s %s candles: %v", symbol, timeframe, err)
+    }
+
+    return lastCandles(&merged, count), nil
+}
+
+// mergeCandles combines two series, candles in newer replacing those at the same time.
+// When a split or dividend since the older series was fetched changed the adjusted
+// prices, the older candles are rescaled to line up with the newer ones.
+func mergeCandles(older, newer []models.CandleStick) []models.CandleStick {
+    byTime := make(map[int64]models.CandleStick, len(older)+len(newer))
+    for _, candle := range older {
+        byTime[candle.Time.Unix()] = candle
+    }
+
+    scale := 1.0
+    if len(newer) > 0 {
+        if overlap, ok := byTime[newer[0].Time.Unix()]; ok && overlap.Close > 0 {
+            scale = newer[0].Close / overlap.Close
+        }
+    }
+    if math.Abs(scale-1) > 1e-9 {
+        for key, candle := range byTime {
+            candle.Open *= scale
+            candle.High *= scale
+            candle.Low *= scale
+            candle.Close *= scale
+            byTime[key] = candle
+        }
+    }
+
+    for _, candle := range newer {
+        byTime[candle.Time.Unix()] = candle
+    }
+
+    merged := make([]models.CandleStick, 0, len(byTime))
+    for _, candle := range byTime {
+        merged = append(merged, candle)
+    }
+
+    sort.Slice(merged, func(i, j int) bool {
+        return merged[i].Time.Before(merged[j].Time)
+    })
+
+    return merged
+}
+
+// lastCandles returns a copy of the series holding only its most recent count candles
+func lastCandles(candleData *models.CandleData, count int) *models.CandleData {
+    result := *candleData
+    if count > 0 && len(result.Candles) > count {
+        result.Candles = result.Candles[len(result.Candles)-count:]
+    }
+    return &result
+}
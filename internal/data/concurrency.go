@@ -0,0 +1,54 @@
+// File: internal/data/concurrency.go
+package data
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// ErrProfileConflict is returned when a profile is saved over a version it
+// wasn't loaded from, usually one saved by another instance of the app
+var ErrProfileConflict = errors.New("the profile was changed by another instance of Moose Market, reload it and try again")
+
+// stampProfile sets the LastModified time of a profile and runs save with the
+// time it had when it was loaded. The previous time is restored when save
+// fails so the profile still matches the stored version.
+func stampProfile(profile *models.Profile, save func(loaded time.Time) error) error {
+    loaded := profile.LastModified
+    profile.LastModified = time.Now()
+
+    if err := save(loaded); err != nil {
+        profile.LastModified = loaded
+        return err
+    }
+    return nil
+}
+
+// checkProfileVersion makes sure the stored bytes of a profile are the version
+// it was loaded from, identified by the time it was last modified. Missing or
+// unreadable stored profiles are not checked.
+func checkProfileVersion(id string, stored []byte, loaded time.Time) error {
+    if stored == nil {
+        return nil
+    }
+    plain, _, err := openProfile(id, stored)
+    if err != nil {
+        return nil
+    }
+    var version struct {
+        LastModified time.Time `json:"last_modified"`
+    }
+    if json.Unmarshal(plain, &version) != nil {
+        return nil
+    }
+
+    if !version.LastModified.Equal(loaded) {
+        return fmt.Errorf("profile %s, saved at %s: %w",
+            id, version.LastModified.Local().Format("2006-01-02 15:04:05"), ErrProfileConflict)
+    }
+    return nil
+}
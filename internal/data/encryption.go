@@ -0,0 +1,422 @@
+// File: internal/data/encryption.go
+package data
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "sync"
+
+    "golang.org/x/crypto/scrypt"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Key derivation settings for new passphrases. Stored profiles record their
+// own settings so these can be raised later.
+const (
+    encryptionVersion = 1
+    scryptN           = 1 << 15
+    scryptR           = 8
+    scryptP           = 1
+    encryptionKeySize = 32 // AES-256
+    encryptionSaltLen = 16
+)
+
+var (
+    // ErrProfileLocked is returned when an encrypted profile is used before it is unlocked
+    ErrProfileLocked = errors.New("profile is locked, unlock it with its passphrase first")
+
+    // ErrWrongPassphrase is returned when a passphrase doesn't decrypt a profile
+    ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// encryptedProfile is how an encrypted profile is stored. The ID and name
+// stay readable so locked profiles can be listed.
+type encryptedProfile struct {
+    ID         string         `json:"id"`
+    Name       string         `json:"name"`
+    Encryption encryptionInfo `json:"encryption"`
+    Ciphertext []byte         `json:"ciphertext"`
+}
+
+// encryptionInfo describes how a profile was encrypted
+type encryptionInfo struct {
+    Version int    `json:"version"`
+    KDF     string `json:"kdf"`
+    N       int    `json:"n"`
+    R       int    `json:"r"`
+    P       int    `json:"p"`
+    Salt    []byte `json:"salt"`
+    Nonce   []byte `json:"nonce"`
+}
+
+// profileKey is the key of an unlocked profile with the settings it was derived with
+type profileKey struct {
+    key  []byte
+    info encryptionInfo // Salt and KDF settings, the nonce is fresh on every save
+}
+
+var (
+    // unlockedKeys holds the keys of the profiles unlocked in this session
+    unlockedKeys      = make(map[string]*profileKey)
+    unlockedKeysMutex sync.Mutex
+)
+
+// deriveProfileKey derives the key for a passphrase with the settings of info
+func deriveProfileKey(passphrase string, info encryptionInfo) ([]byte, error) {
+    if info.KDF != "scrypt" {
+        return nil, fmt.Errorf("unsupported key derivation %q", info.KDF)
+    }
+    return scrypt.Key([]byte(passphrase), info.Salt, info.N, info.R, info.P, encryptionKeySize)
+}
+
+// newProfileKey derives a key for a new passphrase with a fresh salt
+func newProfileKey(passphrase string) (*profileKey, error) {
+    info := encryptionInfo{
+        Version: encryptionVersion,
+        KDF:     "scrypt",
+        N:       scryptN,
+        R:       scryptR,
+        P:       scryptP,
+        Salt:    make([]byte, encryptionSaltLen),
+    }
+    if _, err := rand.Read(info.Salt); err != nil {
+        return nil, err
+    }
+
+    key, err := deriveProfileKey(passphrase, info)
+    if err != nil {
+        return nil, err
+    }
+    return &profileKey{key: key, info: info}, nil
+}
+
+// unlockedKey returns the key of an unlocked profile, nil when it isn't unlocked
+func unlockedKey(id string) *profileKey {
+    unlockedKeysMutex.Lock()
+    defer unlockedKeysMutex.Unlock()
+    return unlockedKeys[id]
+}
+
+// setUnlockedKey remembers or, with a nil key, forgets the key of a profile
+func setUnlockedKey(id string, key *profileKey) {
+    unlockedKeysMutex.Lock()
+    defer unlockedKeysMutex.Unlock()
+
+    if key == nil {
+        delete(unlockedKeys, id)
+    } else {
+        unlockedKeys[id] = key
+    }
+}
+
+// checkWritable refuses to save locked profile stubs, and encrypted profiles
+// whose key was forgotten since they were loaded, so they are never written
+// in the clear or over their encrypted data
+func checkWritable(profile *models.Profile) error {
+    if profile.Locked || (profile.Encrypted && unlockedKey(profile.ID) == nil) {
+        return fmt.Errorf("profile %s: %w", profile.ID, ErrProfileLocked)
+    }
+    return nil
+}
+
+// isEncryptedProfile reports whether stored profile bytes are encrypted
+func isEncryptedProfile(content []byte) bool {
+    var probe struct {
+        Encryption *encryptionInfo `json:"encryption"`
+    }
+    return json.Unmarshal(content, &probe) == nil && probe.Encryption != nil
+}
+
+// sealProfile encrypts the encoding of a profile when it has been given a
+// passphrase, and returns it unchanged otherwise
+func sealProfile(profile *models.Profile, plain []byte) ([]byte, error) {
+    key := unlockedKey(profile.ID)
+    if key == nil {
+        return plain, nil
+    }
+    return sealWithKey(key, profile.ID, profile.Name, plain)
+}
+
+// sealWithKey encrypts the encoding of a profile with a key
+func sealWithKey(key *profileKey, id, name string, plain []byte) ([]byte, error) {
+    // Bind the ciphertext to the profile ID so it can't be passed off as another profile
+    nonce, ciphertext, err := sealBytes(key.key, plain, []byte(id))
+    if err != nil {
+        return nil, err
+    }
+
+    info := key.info
+    info.Nonce = nonce
+    sealed := encryptedProfile{
+        ID:         id,
+        Name:       name,
+        Encryption: info,
+        Ciphertext: ciphertext,
+    }
+    return json.MarshalIndent(sealed, "", "  ")
+}
+
+// resealProfile decrypts stored profile bytes sealed with previous and seals
+// them again with next, or returns them in the clear when next is nil.
+// Unencrypted bytes are returned unchanged.
+func resealProfile(content []byte, previous, next *profileKey) ([]byte, error) {
+    if !isEncryptedProfile(content) {
+        return content, nil
+    }
+
+    var sealed encryptedProfile
+    if err := json.Unmarshal(content, &sealed); err != nil {
+        return nil, err
+    }
+    if previous == nil {
+        return nil, fmt.Errorf("profile %s: %w", sealed.ID, ErrProfileLocked)
+    }
+    plain, err := decryptProfile(sealed, previous.key)
+    if err != nil {
+        return nil, err
+    }
+    if next == nil {
+        return plain, nil
+    }
+    return sealWithKey(next, sealed.ID, sealed.Name, plain)
+}
+
+// sealBytes encrypts plain with AES-GCM under a fresh nonce, authenticating aad with it
+func sealBytes(key, plain, aad []byte) (nonce, ciphertext []byte, err error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, nil, err
+    }
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    nonce = make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, nil, err
+    }
+    return nonce, gcm.Seal(nil, nonce, plain, aad), nil
+}
+
+// openBytes decrypts what sealBytes encrypted, failing with
+// ErrWrongPassphrase when the key or aad don't match
+func openBytes(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+    if len(nonce) != gcm.NonceSize() {
+        return nil, errors.New("invalid nonce")
+    }
+
+    plain, err := gcm.Open(nil, nonce, ciphertext, aad)
+    if err != nil {
+        return nil, ErrWrongPassphrase
+    }
+    return plain, nil
+}
+
+// sealedRecord is a JSON record written alongside an encrypted profile,
+// such as an audit log entry, sealed with the profile's key
+type sealedRecord struct {
+    Nonce      []byte `json:"nonce,omitempty"`
+    Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+// sealRecord seals the JSON encoding of a record belonging to a profile when
+// the profile is encrypted, and returns it unchanged otherwise. kind names
+// the records so one kind can't be passed off as another.
+func sealRecord(id, kind string, plain []byte) ([]byte, error) {
+    key := unlockedKey(id)
+    if key == nil {
+        return plain, nil
+    }
+
+    nonce, ciphertext, err := sealBytes(key.key, plain, []byte(id+"/"+kind))
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(sealedRecord{Nonce: nonce, Ciphertext: ciphertext})
+}
+
+// openRecord returns the JSON encoding of a record written by sealRecord.
+// Sealed records need the profile to be unlocked with the key that sealed them.
+func openRecord(id, kind string, content []byte) ([]byte, error) {
+    var sealed sealedRecord
+    if err := json.Unmarshal(content, &sealed); err != nil {
+        return nil, err
+    }
+    if sealed.Ciphertext == nil {
+        return content, nil
+    }
+
+    key := unlockedKey(id)
+    if key == nil {
+        return nil, fmt.Errorf("profile %s: %w", id, ErrProfileLocked)
+    }
+    return openBytes(key.key, sealed.Nonce, sealed.Ciphertext, []byte(id+"/"+kind))
+}
+
+// openProfile decrypts stored profile bytes with the key of the unlocked
+// profile. Unencrypted bytes are returned unchanged. A locked profile returns
+// ErrProfileLocked along with a stub holding its ID and name.
+func openProfile(id string, content []byte) ([]byte, *models.Profile, error) {
+    if !isEncryptedProfile(content) {
+        return content, nil, nil
+    }
+
+    var sealed encryptedProfile
+    if err := json.Unmarshal(content, &sealed); err != nil {
+        return nil, nil, err
+    }
+
+    key := unlockedKey(id)
+    if key == nil {
+        return nil, lockedStub(sealed), ErrProfileLocked
+    }
+
+    plain, err := decryptProfile(sealed, key.key)
+    if err != nil {
+        return nil, lockedStub(sealed), err
+    }
+    return plain, nil, nil
+}
+
+// decryptProfile decrypts a sealed profile with a key
+func decryptProfile(sealed encryptedProfile, key []byte) ([]byte, error) {
+    plain, err := openBytes(key, sealed.Encryption.Nonce, sealed.Ciphertext, []byte(sealed.ID))
+    if err != nil && !errors.Is(err, ErrWrongPassphrase) {
+        return nil, fmt.Errorf("profile %s: %w", sealed.ID, err)
+    }
+    return plain, err
+}
+
+// lockedStub is what is shown of a profile that hasn't been unlocked
+func lockedStub(sealed encryptedProfile) *models.Profile {
+    return &models.Profile{
+        SchemaVersion: ProfileSchemaVersion,
+        ID:            sealed.ID,
+        Name:          sealed.Name,
+        Encrypted:     true,
+        Locked:        true,
+    }
+}
+
+// unlockStoredProfile checks a passphrase against the stored bytes of an
+// encrypted profile and remembers the key when it matches
+func unlockStoredProfile(id string, content []byte, passphrase string) error {
+    if !isEncryptedProfile(content) {
+        return nil
+    }
+
+    _, key, err := openWithPassphrase(content, passphrase)
+    if err != nil {
+        return err
+    }
+    setUnlockedKey(id, key)
+    return nil
+}
+
+// openWithPassphrase decrypts the bytes of an encrypted profile with a
+// passphrase, returning the plain encoding and the key it was sealed with
+func openWithPassphrase(content []byte, passphrase string) ([]byte, *profileKey, error) {
+    var sealed encryptedProfile
+    if err := json.Unmarshal(content, &sealed); err != nil {
+        return nil, nil, err
+    }
+    key, err := deriveProfileKey(passphrase, sealed.Encryption)
+    if err != nil {
+        return nil, nil, err
+    }
+    plain, err := decryptProfile(sealed, key)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    info := sealed.Encryption
+    info.Nonce = nil
+    return plain, &profileKey{key: key, info: info}, nil
+}
+
+// UnlockProfile decrypts a profile with its passphrase and keeps it unlocked
+// for the rest of the session. Unencrypted profiles are simply loaded.
+func UnlockProfile(id, passphrase string) (*models.Profile, error) {
+    content, err := currentStorage().LoadRawProfile(id)
+    if err != nil {
+        return nil, err
+    }
+    if err := unlockStoredProfile(id, content, passphrase); err != nil {
+        return nil, err
+    }
+    return GetProfileByID(id)
+}
+
+// LockProfile forgets the key of a profile until it is unlocked again
+func LockProfile(id string) {
+    setUnlockedKey(id, nil)
+}
+
+// ChangeProfilePassphrase sets the passphrase of a profile. current must
+// match the existing passphrase of an encrypted profile and is ignored for
+// unencrypted ones. An empty next removes the encryption. The audit log and
+// the profile's operations in the sync folder are sealed again with it.
+func ChangeProfilePassphrase(id, current, next string) error {
+    // No sync may write or read operations with the old key meanwhile
+    folder, resume := pauseSync()
+    defer resume()
+
+    profile, err := UnlockProfile(id, current)
+    if err != nil {
+        return err
+    }
+
+    // Read what is sealed with the current key while it is still in use
+    entries, err := readAuditEntries(id)
+    if err != nil {
+        return err
+    }
+    operations, err := openSyncRecords(folder, id)
+    if err != nil {
+        return err
+    }
+
+    // Derive the new key before touching the session
+    var key *profileKey
+    if next != "" {
+        if key, err = newProfileKey(next); err != nil {
+            return err
+        }
+    }
+
+    // The storage switches the session key once the stored version checks
+    // out, so the profile is never loaded with a key it wasn't sealed with
+    previous := *profile
+    if err := currentStorage().rekeyProfile(profile, key); err != nil {
+        return err
+    }
+    profile.Encrypted = next != ""
+
+    // Seal the audit log with the new key, then record the save at its end
+    if err := rewriteAuditLog(id, entries); err != nil {
+        return err
+    }
+    if err := sealSyncRecords(id, operations); err != nil {
+        return fmt.Errorf("failed to seal the sync operations of profile %s with the new passphrase: %w", id, err)
+    }
+    if err := recordProfileChange(&previous, profile); err != nil {
+        log.Printf("Failed to record the changes of profile %s in its audit log: %v", id, err)
+    }
+    notifySync(id)
+    return nil
+}
@@ -0,0 +1,157 @@
+// File: internal/data/encryption_test.go
+package data
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// newEncryptedProfile creates a profile and encrypts it with a passphrase
+func newEncryptedProfile(t *testing.T, passphrase string) *models.Profile {
+    t.Helper()
+    profile, err := CreateProfile("Secret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { LockProfile(profile.ID) })
+    if err := ChangeProfilePassphrase(profile.ID, "", passphrase); err != nil {
+        t.Fatalf("ChangeProfilePassphrase() error = %v", err)
+    }
+    if profile, err = GetProfileByID(profile.ID); err != nil {
+        t.Fatal(err)
+    }
+    return profile
+}
+
+func TestSealRoundTrip(t *testing.T) {
+    key, err := newProfileKey("passphrase")
+    if err != nil {
+        t.Fatal(err)
+    }
+    setUnlockedKey("profile", key)
+    t.Cleanup(func() { LockProfile("profile") })
+
+    profile := &models.Profile{ID: "profile", Name: "Secret"}
+    plain := []byte(`{"id": "profile", "name": "Secret"}`)
+    sealed, err := sealProfile(profile, plain)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if bytes.Contains(sealed, []byte(`"Secret"}`)) || !isEncryptedProfile(sealed) {
+        t.Fatalf("sealProfile() = %s, want it encrypted", sealed)
+    }
+    opened, _, err := openProfile("profile", sealed)
+    if err != nil || !bytes.Equal(opened, plain) {
+        t.Errorf("openProfile() = %s, %v; want the sealed encoding", opened, err)
+    }
+
+    // Records are bound to their profile and kind
+    record, err := sealRecord("profile", auditDir, []byte(`{"changes": []}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if opened, err := openRecord("profile", auditDir, record); err != nil || string(opened) != `{"changes": []}` {
+        t.Errorf("openRecord() = %s, %v; want the sealed record", opened, err)
+    }
+    if _, err := openRecord("profile", syncRecordKind, record); !errors.Is(err, ErrWrongPassphrase) {
+        t.Errorf("openRecord() of another kind error = %v, want ErrWrongPassphrase", err)
+    }
+
+    LockProfile("profile")
+    if _, stub, err := openProfile("profile", sealed); !errors.Is(err, ErrProfileLocked) || stub == nil || stub.Name != "Secret" {
+        t.Errorf("openProfile() of a locked profile = %+v, %v; want a stub and ErrProfileLocked", stub, err)
+    }
+}
+
+func TestUnlockProfileWrongPassphrase(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile := newEncryptedProfile(t, "right")
+    LockProfile(profile.ID)
+
+    if _, err := UnlockProfile(profile.ID, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+        t.Fatalf("UnlockProfile() with the wrong passphrase error = %v, want ErrWrongPassphrase", err)
+    }
+    if locked, err := GetProfileByID(profile.ID); err != nil || !locked.Locked {
+        t.Errorf("profile after a wrong passphrase = %+v, %v; want it still locked", locked, err)
+    }
+    if unlocked, err := UnlockProfile(profile.ID, "right"); err != nil || unlocked.Locked || unlocked.Name != "Secret" {
+        t.Errorf("UnlockProfile() = %+v, %v; want the decrypted profile", unlocked, err)
+    }
+}
+
+func TestChangeProfilePassphrase(t *testing.T) {
+    for _, backendName := range []string{StorageJSON, StorageBolt} {
+        t.Run(backendName, func(t *testing.T) {
+            useTempStorage(t, backendName)
+            profile := newEncryptedProfile(t, "first")
+            saveNames(t, profile, "v1", "v2")
+            before, err := readAuditEntries(profile.ID)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if err := ChangeProfilePassphrase(profile.ID, "first", "second"); err != nil {
+                t.Fatalf("ChangeProfilePassphrase() error = %v", err)
+            }
+            LockProfile(profile.ID)
+            if _, err := UnlockProfile(profile.ID, "first"); !errors.Is(err, ErrWrongPassphrase) {
+                t.Errorf("UnlockProfile() with the old passphrase error = %v, want ErrWrongPassphrase", err)
+            }
+            unlocked, err := UnlockProfile(profile.ID, "second")
+            if err != nil || unlocked.Name != "v2" {
+                t.Fatalf("UnlockProfile() with the new passphrase = %+v, %v; want v2", unlocked, err)
+            }
+
+            // The audit log was sealed again without losing entries
+            after, err := readAuditEntries(profile.ID)
+            if err != nil || len(before) == 0 || len(after) != len(before) {
+                t.Errorf("audit log holds %d entries (err = %v) after the change, want %d", len(after), err, len(before))
+            }
+
+            if backendName != StorageJSON {
+                return
+            }
+            corrupt, _ := filepath.Glob(profilePath(profile.ID) + ".corrupt-*")
+            if len(corrupt) > 0 {
+                t.Errorf("changing the passphrase set the profile aside as corrupt: %v", corrupt)
+            }
+            backups, err := filepath.Glob(filepath.Join(dataDirectory, profilesDir, backupsDir, profile.ID, "*"))
+            if err != nil || len(backups) == 0 {
+                t.Fatalf("backups = %v, %v; want some", backups, err)
+            }
+            for _, path := range backups {
+                content, err := os.ReadFile(path)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                if _, _, err := openProfile(profile.ID, content); err != nil {
+                    t.Errorf("backup %s doesn't open with the new passphrase: %v", filepath.Base(path), err)
+                }
+            }
+        })
+    }
+}
+
+func TestRemoveProfilePassphrase(t *testing.T) {
+    useTempStorage(t, StorageJSON)
+    profile := newEncryptedProfile(t, "first")
+    saveNames(t, profile, "v1")
+
+    if err := ChangeProfilePassphrase(profile.ID, "first", ""); err != nil {
+        t.Fatalf("ChangeProfilePassphrase() error = %v", err)
+    }
+    for _, path := range []string{profilePath(profile.ID), profileBackupPath(profile.ID, 1)} {
+        content, err := os.ReadFile(path)
+        if err != nil || isEncryptedProfile(content) {
+            t.Errorf("%s is still encrypted (err = %v)", filepath.Base(path), err)
+        }
+    }
+    if entries, err := readAuditEntries(profile.ID); err != nil || len(entries) == 0 {
+        t.Errorf("audit log = %d entries, %v; want it readable in the clear", len(entries), err)
+    }
+}
@@ -0,0 +1,29 @@
+// File: internal/data/filelock.go
+package data
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// lockFileName is the file locked in a storage directory while it is written
+const lockFileName = ".lock"
+
+// withFileLock runs fn while holding an exclusive advisory lock on dir, so
+// other instances of the app using the same directory wait for it.
+// storageMutex only covers this process, callers still hold it as well.
+func withFileLock(dir string, fn func() error) error {
+    f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return fmt.Errorf("failed to open lock file in %s: %w", dir, err)
+    }
+    defer f.Close()
+
+    if err := lockFile(f); err != nil {
+        return fmt.Errorf("failed to lock %s: %w", dir, err)
+    }
+    defer unlockFile(f)
+
+    return fn()
+}
@@ -0,0 +1,17 @@
+// File: internal/data/filelock_other.go
+
+//go:build !unix && !windows
+
+package data
+
+import "os"
+
+// lockFile does nothing where advisory locks aren't available
+func lockFile(f *os.File) error {
+    return nil
+}
+
+// unlockFile does nothing where advisory locks aren't available
+func unlockFile(f *os.File) error {
+    return nil
+}
@@ -0,0 +1,27 @@
+// File: internal/data/filelock_unix.go
+
+//go:build unix
+
+package data
+
+import (
+    "errors"
+    "os"
+
+    "golang.org/x/sys/unix"
+)
+
+// lockFile blocks until it holds an exclusive lock on f
+func lockFile(f *os.File) error {
+    for {
+        err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+        if !errors.Is(err, unix.EINTR) {
+            return err
+        }
+    }
+}
+
+// unlockFile releases the lock on f
+func unlockFile(f *os.File) error {
+    return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,23 @@
+// File: internal/data/filelock_windows.go
+
+//go:build windows
+
+package data
+
+import (
+    "os"
+
+    "golang.org/x/sys/windows"
+)
+
+// lockFile blocks until it holds an exclusive lock on f
+func lockFile(f *os.File) error {
+    var overlapped windows.Overlapped
+    return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &overlapped)
+}
+
+// unlockFile releases the lock on f
+func unlockFile(f *os.File) error {
+    var overlapped windows.Overlapped
+    return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &overlapped)
+}
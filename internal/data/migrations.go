@@ -0,0 +1,280 @@
+// File: internal/data/migrations.go
+package data
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "time"
+)
+
+// ProfileSchemaVersion is the profile layout written by this version of the
+// app. It must match the Version of the last entry of profileMigrations.
+// Bump it whenever profiles gain data older versions would drop, so they
+// refuse the profile instead.
+const ProfileSchemaVersion = 2
+
+// SchemaVersionError is returned for profiles written by a newer version of the app
+type SchemaVersionError struct {
+    ID        string
+    Version   int
+    Supported int
+}
+
+// Error describes the unsupported schema version
+func (e *SchemaVersionError) Error() string {
+    return fmt.Sprintf("profile %s uses schema version %d but this version of Moose Market only supports up to %d, please update the app",
+        e.ID, e.Version, e.Supported)
+}
+
+// openingBalanceNote marks the transactions standing in for positions and
+// cash held before they had transactions, as the app notes them
+const openingBalanceNote = "Opening balance"
+
+// profileDocument is a profile as raw JSON, so migrations can work on
+// layouts that no longer match models.Profile
+type profileDocument map[string]any
+
+// profileMigration upgrades a profile document to Version from the version before it
+type profileMigration struct {
+    Version     int
+    Description string
+    Migrate     func(doc profileDocument) error
+}
+
+// profileMigrations holds every migration in order. Append new migrations at
+// the end and bump ProfileSchemaVersion, never change released ones.
+var profileMigrations = []profileMigration{
+    {
+        Version:     1,
+        Description: "fill in settings and lists missing from profiles written before versioning",
+        Migrate:     migrateProfileToV1,
+    },
+    {
+        Version:     2,
+        Description: "add the contribution settings and turn the cash balance of accounts into opening deposits, for cash derived from transactions",
+        Migrate:     migrateProfileToV2,
+    },
+}
+
+// migrateProfileToV1 gives unversioned profiles the defaults of CreateProfile
+// so that zero values aren't mistaken for user choices
+func migrateProfileToV1(doc profileDocument) error {
+    for _, key := range []string{"accounts", "watchlists"} {
+        if doc[key] == nil {
+            doc[key] = []any{}
+        }
+    }
+
+    settings, _ := doc["settings"].(map[string]any)
+    if settings == nil {
+        settings = map[string]any{"dark_mode": true}
+        doc["settings"] = settings
+    }
+    if currency, _ := settings["currency"].(string); currency == "" {
+        settings["currency"] = "CAD"
+    }
+    if interval, _ := settings["refresh_interval"].(float64); interval <= 0 {
+        settings["refresh_interval"] = 60
+    }
+    return nil
+}
+
+// migrateProfileToV2 gives profiles the contribution settings. Accounts gain
+// cash movements, and their cash is now derived from their transactions
+// instead of entered by hand, so the balance they stored becomes cash
+// movements.
+func migrateProfileToV2(doc profileDocument) error {
+    settings, _ := doc["settings"].(map[string]any)
+    if settings != nil && settings["contributions"] == nil {
+        settings["contributions"] = map[string]any{}
+    }
+
+    profileCreated, err := documentDate(doc["created_at"])
+    if err != nil {
+        return fmt.Errorf("profile creation date: %w", err)
+    }
+    accounts, _ := doc["accounts"].([]any)
+    for _, a := range accounts {
+        account, _ := a.(map[string]any)
+        if account == nil {
+            continue
+        }
+        if err := migrateAccountCashToV2(account, profileCreated); err != nil {
+            return fmt.Errorf("account %v: %w", account["id"], err)
+        }
+    }
+    return nil
+}
+
+// migrateAccountCashToV2 keeps the cash balance an account stored by turning
+// it into an opening deposit, or a withdrawal, dated when the account was
+// opened or at its first trade. Positions held without transactions get the
+// opening buy the app would give them, and each opening buy gets a deposit
+// paying for it. Accounts with cash movements already derive their balance.
+// The IDs and dates only depend on the account, so every machine migrating
+// the same profile agrees.
+func migrateAccountCashToV2(account map[string]any, profileCreated time.Time) error {
+    if movements, _ := account["transactions"].([]any); len(movements) > 0 {
+        return nil
+    }
+    const (
+        balanceEpsilon  = 0.005
+        defaultCurrency = "CAD"
+    )
+    id, _ := account["id"].(string)
+    opened, err := documentDate(account["created_at"])
+    if err != nil {
+        return fmt.Errorf("creation date: %w", err)
+    }
+    if opened.IsZero() {
+        opened = profileCreated
+    }
+
+    var movements []any
+    var cash float64 // CAD the trades and opening deposits move
+    start := opened
+    positions, _ := account["positions"].([]any)
+    for _, p := range positions {
+        position, _ := p.(map[string]any)
+        if position == nil {
+            continue
+        }
+        trades, _ := position["transactions"].([]any)
+        if quantity, _ := position["quantity"].(float64); len(trades) == 0 && quantity > 0 {
+            symbol, _ := position["stock_symbol"].(string)
+            price, _ := position["average_cost"].(float64)
+            trades = []any{map[string]any{
+                "id":         "transaction_opening_buy_" + id + "_" + symbol,
+                "type":       "buy",
+                "quantity":   quantity,
+                "price":      price,
+                "date":       opened.Format(time.RFC3339Nano),
+                "commission": 0.0,
+                "notes":      openingBalanceNote,
+            }}
+            position["transactions"] = trades
+        }
+
+        for _, t := range trades {
+            trade, _ := t.(map[string]any)
+            if trade == nil {
+                continue
+            }
+            date, err := documentDate(trade["date"])
+            if err != nil {
+                return fmt.Errorf("transaction %v: %w", trade["id"], err)
+            }
+            if start.IsZero() || date.Before(start) {
+                start = date
+            }
+            if currency, _ := trade["currency"].(string); currency != "" && currency != defaultCurrency {
+                continue
+            }
+
+            // The cash of a trade as the app derived it when this migration was written
+            kind, _ := trade["type"].(string)
+            quantity, _ := trade["quantity"].(float64)
+            price, _ := trade["price"].(float64)
+            commission, _ := trade["commission"].(float64)
+            switch kind {
+            case "buy":
+                cost := quantity*price + commission
+                cash -= cost
+                if notes, _ := trade["notes"].(string); notes == openingBalanceNote && cost > 0 {
+                    tradeID, _ := trade["id"].(string)
+                    movements = append(movements, openingMovement(tradeID+"_funding", "deposit", cost, trade["date"]))
+                    cash += cost
+                }
+            case "sell", "return_of_capital":
+                cash += quantity*price - commission
+            default:
+                cash -= commission
+            }
+        }
+    }
+
+    balance, _ := account["balance"].(float64)
+    if amount := balance - cash; math.Abs(amount) >= balanceEpsilon {
+        kind := "deposit"
+        if amount < 0 {
+            kind, amount = "withdrawal", -amount
+        }
+        opening := openingMovement("transaction_opening_cash_"+id, kind, amount, start.Format(time.RFC3339Nano))
+        movements = append([]any{opening}, movements...)
+    }
+    if len(movements) > 0 {
+        account["transactions"] = movements
+    }
+    return nil
+}
+
+// openingMovement returns a cash movement standing in for cash held before
+// the account had transactions
+func openingMovement(id, kind string, amount float64, date any) map[string]any {
+    return map[string]any{
+        "id":         id,
+        "type":       kind,
+        "quantity":   0.0,
+        "price":      0.0,
+        "amount":     amount,
+        "date":       date,
+        "commission": 0.0,
+        "notes":      openingBalanceNote,
+    }
+}
+
+// documentDate parses a date of a profile document, zero when it is missing
+func documentDate(value any) (time.Time, error) {
+    text, _ := value.(string)
+    if text == "" {
+        return time.Time{}, nil
+    }
+    return time.Parse(time.RFC3339Nano, text)
+}
+
+// profileSchemaVersion returns the schema version of an encoded profile, 0
+// for profiles written before versioning
+func profileSchemaVersion(content []byte) (int, error) {
+    var header struct {
+        SchemaVersion int `json:"schema_version"`
+    }
+    if err := json.Unmarshal(content, &header); err != nil {
+        return 0, err
+    }
+    return header.SchemaVersion, nil
+}
+
+// migrateProfile upgrades an encoded profile to ProfileSchemaVersion and
+// returns the migrated encoding. Profiles from newer versions are refused.
+func migrateProfile(id string, content []byte) ([]byte, error) {
+    version, err := profileSchemaVersion(content)
+    if err != nil {
+        return nil, err
+    }
+    if version > ProfileSchemaVersion {
+        return nil, &SchemaVersionError{ID: id, Version: version, Supported: ProfileSchemaVersion}
+    }
+    if version == ProfileSchemaVersion {
+        return content, nil
+    }
+
+    var doc profileDocument
+    if err := json.Unmarshal(content, &doc); err != nil {
+        return nil, err
+    }
+
+    // Run the migrations after the document's version, in order
+    for _, migration := range profileMigrations {
+        if migration.Version <= version {
+            continue
+        }
+        if err := migration.Migrate(doc); err != nil {
+            return nil, fmt.Errorf("failed to migrate profile %s to schema version %d (%s): %w",
+                id, migration.Version, migration.Description, err)
+        }
+        doc["schema_version"] = migration.Version
+    }
+
+    return json.MarshalIndent(doc, "", "  ")
+}
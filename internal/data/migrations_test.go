@@ -0,0 +1,154 @@
+// File: internal/data/migrations_test.go
+package data
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "math"
+    "testing"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// legacyProfile returns a version 1 profile holding one account
+func legacyProfile(account string) []byte {
+    return []byte(`{"schema_version": 1, "id": "profile", "name": "Legacy", "created_at": "2022-06-01T00:00:00Z",
+        "accounts": [` + account + `], "watchlists": [], "settings": {"dark_mode": true, "currency": "CAD", "refresh_interval": 60}}`)
+}
+
+func TestMigrateProfileToV2Cash(t *testing.T) {
+    type movement struct {
+        id     string
+        kind   string
+        amount float64
+        date   string
+    }
+    tests := []struct {
+        name      string
+        account   string
+        movements []movement
+    }{
+        {
+            name: "balance and positions without transactions",
+            account: `{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 1500, "created_at": "2023-01-05T00:00:00Z",
+                "positions": [{"stock_symbol": "XEQT", "quantity": 10, "average_cost": 100, "transactions": []}]}`,
+            movements: []movement{
+                {"transaction_opening_cash_cash", "deposit", 1500, "2023-01-05"},
+                {"transaction_opening_buy_cash_XEQT_funding", "deposit", 1000, "2023-01-05"},
+            },
+        },
+        {
+            name: "opening buy added by an older version",
+            account: `{"id": "tfsa", "name": "TFSA", "type": "TFSA", "balance": 0, "created_at": "2023-01-05T00:00:00Z",
+                "positions": [{"stock_symbol": "VFV", "quantity": 4, "average_cost": 100, "transactions": [
+                    {"id": "t1", "type": "buy", "quantity": 4, "price": 100, "date": "2023-01-05T00:00:00Z", "commission": 0, "notes": "Opening balance"}]}]}`,
+            movements: []movement{
+                {"t1_funding", "deposit", 400, "2023-01-05"},
+            },
+        },
+        {
+            name: "trades paid from the balance",
+            account: `{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 200, "created_at": "2023-01-05T00:00:00Z",
+                "positions": [{"stock_symbol": "XEQT", "quantity": 5, "average_cost": 50.5, "transactions": [
+                    {"id": "t1", "type": "buy", "quantity": 10, "price": 50, "date": "2023-02-01T00:00:00Z", "commission": 5, "notes": ""},
+                    {"id": "t2", "type": "sell", "quantity": 5, "price": 60, "date": "2023-03-01T00:00:00Z", "commission": 5, "notes": ""}]}]}`,
+            movements: []movement{
+                {"transaction_opening_cash_cash", "deposit", 410, "2023-01-05"},
+            },
+        },
+        {
+            name: "trades that added more than the balance",
+            account: `{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 0, "created_at": "2023-01-05T00:00:00Z",
+                "positions": [{"stock_symbol": "XEQT", "quantity": 0, "average_cost": 0, "transactions": [
+                    {"id": "t1", "type": "buy", "quantity": 10, "price": 10, "date": "2023-02-01T00:00:00Z", "commission": 0, "notes": ""},
+                    {"id": "t2", "type": "sell", "quantity": 10, "price": 20, "date": "2023-03-01T00:00:00Z", "commission": 0, "notes": ""}]}]}`,
+            movements: []movement{
+                {"transaction_opening_cash_cash", "withdrawal", 100, "2023-01-05"},
+            },
+        },
+        {
+            name: "trades before the account was opened",
+            account: `{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 50,
+                "positions": [{"stock_symbol": "XEQT", "quantity": 1, "average_cost": 10, "transactions": [
+                    {"id": "t1", "type": "buy", "quantity": 1, "price": 10, "date": "2021-03-01T00:00:00Z", "commission": 0, "notes": ""}]}]}`,
+            movements: []movement{
+                {"transaction_opening_cash_cash", "deposit", 60, "2021-03-01"},
+            },
+        },
+        {
+            name: "cash balance with nothing else",
+            account: `{"id": "rrsp", "name": "RRSP", "type": "RRSP", "balance": 250.25, "positions": []}`,
+            movements: []movement{
+                {"transaction_opening_cash_rrsp", "deposit", 250.25, "2022-06-01"},
+            },
+        },
+        {
+            name: "balance already derived from cash movements",
+            account: `{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 300, "positions": [], "transactions": [
+                {"id": "d1", "type": "deposit", "quantity": 0, "price": 0, "amount": 300, "date": "2024-01-02T00:00:00Z", "commission": 0, "notes": ""}]}`,
+            movements: []movement{
+                {"d1", "deposit", 300, "2024-01-02"},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            content := legacyProfile(tt.account)
+            migrated, err := migrateProfile("profile", content)
+            if err != nil {
+                t.Fatalf("migrateProfile() error = %v", err)
+            }
+            var profile models.Profile
+            if err := json.Unmarshal(migrated, &profile); err != nil {
+                t.Fatal(err)
+            }
+            if profile.SchemaVersion != ProfileSchemaVersion {
+                t.Errorf("migrated profile has schema version %d, want %d", profile.SchemaVersion, ProfileSchemaVersion)
+            }
+
+            account := profile.Accounts[0]
+            if len(account.Transactions) != len(tt.movements) {
+                t.Fatalf("migrated account has %d cash movements, want %d: %+v", len(account.Transactions), len(tt.movements), account.Transactions)
+            }
+            for i, want := range tt.movements {
+                got := account.Transactions[i]
+                date, err := time.Parse("2006-01-02", want.date)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                if got.ID != want.id || got.Type != want.kind || math.Abs(got.Amount-want.amount) > 1e-9 || !got.Date.Equal(date) {
+                    t.Errorf("cash movement %d = %s %s of %g on %s, want %s %s of %g on %s", i,
+                        got.ID, got.Type, got.Amount, got.Date.Format("2006-01-02"), want.id, want.kind, want.amount, want.date)
+                }
+            }
+
+            if derived := portfolio.CashBalances(account, time.Time{})[portfolio.DefaultCurrency]; math.Abs(derived-account.Balance) > 1e-9 {
+                t.Errorf("migrated account derives %g of cash, want the stored %g", derived, account.Balance)
+            }
+            if issues := portfolio.CheckProfile(&profile); len(issues) > 0 {
+                t.Errorf("CheckProfile() of the migrated profile = %+v, want none", issues)
+            }
+
+            // Machines migrating the same profile must agree so sync sees no conflict
+            again, err := migrateProfile("profile", content)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !bytes.Equal(migrated, again) {
+                t.Error("migrating the same profile twice gives different documents")
+            }
+        })
+    }
+}
+
+func TestMigrateProfileRefusesNewerVersions(t *testing.T) {
+    _, err := migrateProfile("profile", []byte(`{"schema_version": 99, "id": "profile"}`))
+    var versionErr *SchemaVersionError
+    if !errors.As(err, &versionErr) || versionErr.Version != 99 || versionErr.Supported != ProfileSchemaVersion {
+        t.Errorf("migrateProfile() error = %v, want a SchemaVersionError for version 99", err)
+    }
+}
@@ -0,0 +1,262 @@
+// File: internal/data/paths.go
+package data
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "log"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "time"
+)
+
+// Storage location settings
+const (
+    appDirName         = "moosemarket"
+    legacyDataDirName  = "moosemarket_data"
+    legacyMigratedFile = ".legacy_migrated"
+
+    envStorageName  = "MOOSEMARKET_STORAGE"
+    envDataDirName  = "MOOSEMARKET_DATA_DIR"
+    envCacheDirName = "MOOSEMARKET_CACHE_DIR"
+)
+
+var (
+    // dataDirectory holds profiles and drawings, the user's own data
+    dataDirectory = defaultDataDirectory()
+
+    // cacheDirectory holds market data that can be fetched again
+    cacheDirectory = defaultCacheDirectory()
+
+    // storageBackend names the Storage opened by Initialize
+    storageBackend = StorageJSON
+)
+
+// StorageConfig selects how and where data is stored. Empty fields use the defaults.
+type StorageConfig struct {
+    Backend  string // json or bolt
+    DataDir  string
+    CacheDir string
+}
+
+// StorageConfigFromEnv reads the storage settings from environment variables
+func StorageConfigFromEnv() StorageConfig {
+    return StorageConfig{
+        Backend:  strings.ToLower(strings.TrimSpace(os.Getenv(envStorageName))),
+        DataDir:  strings.TrimSpace(os.Getenv(envDataDirName)),
+        CacheDir: strings.TrimSpace(os.Getenv(envCacheDirName)),
+    }
+}
+
+// ConfigureStorage applies storage settings. It must be called before Initialize.
+func ConfigureStorage(cfg StorageConfig) {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    if cfg.Backend != "" {
+        storageBackend = cfg.Backend
+    }
+    if cfg.DataDir != "" {
+        dataDirectory = cfg.DataDir
+    }
+    if cfg.CacheDir != "" {
+        cacheDirectory = cfg.CacheDir
+    }
+}
+
+// DataDirectory returns the directory holding profiles and drawings
+func DataDirectory() string {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+    return dataDirectory
+}
+
+// CacheDirectory returns the directory holding cached market data
+func CacheDirectory() string {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+    return cacheDirectory
+}
+
+// defaultDataDirectory returns $XDG_DATA_HOME/moosemarket, falling back to
+// the platform's usual location when XDG_DATA_HOME isn't set
+func defaultDataDirectory() string {
+    if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+        return filepath.Join(dir, appDirName)
+    }
+
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return legacyDataDirName
+    }
+
+    switch runtime.GOOS {
+    case "windows":
+        if dir := os.Getenv("LocalAppData"); dir != "" {
+            return filepath.Join(dir, appDirName)
+        }
+        return filepath.Join(home, "AppData", "Local", appDirName)
+    case "darwin":
+        return filepath.Join(home, "Library", "Application Support", appDirName)
+    default:
+        return filepath.Join(home, ".local", "share", appDirName)
+    }
+}
+
+// defaultCacheDirectory returns $XDG_CACHE_HOME/moosemarket, falling back to
+// the platform's usual cache location
+func defaultCacheDirectory() string {
+    if dir := os.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+        return filepath.Join(dir, appDirName)
+    }
+
+    dir, err := os.UserCacheDir()
+    if err != nil {
+        return filepath.Join(legacyDataDirName, "cache")
+    }
+    return filepath.Join(dir, appDirName)
+}
+
+// legacyDataDirectories returns the directories older versions stored data
+// in, which were relative to the working directory the app was started from
+func legacyDataDirectories() []string {
+    candidates := []string{legacyDataDirName}
+    if exe, err := os.Executable(); err == nil {
+        candidates = append(candidates, filepath.Join(filepath.Dir(exe), legacyDataDirName))
+    }
+
+    var dirs []string
+    seen := make(map[string]bool)
+    for _, candidate := range candidates {
+        abs, err := filepath.Abs(candidate)
+        if err != nil || seen[abs] {
+            continue
+        }
+        seen[abs] = true
+
+        if info, err := os.Stat(abs); err == nil && info.IsDir() {
+            dirs = append(dirs, abs)
+        }
+    }
+    return dirs
+}
+
+// migrateLegacyData copies data from the old relative directories into the
+// configured ones, once. Profiles found in several places keep the most
+// recently modified version. The old directories are left in place.
+// The caller must hold storageMutex for writing.
+func migrateLegacyData() error {
+    marker := filepath.Join(dataDirectory, legacyMigratedFile)
+    if _, err := os.Stat(marker); err == nil {
+        return nil
+    }
+
+    dataAbs, _ := filepath.Abs(dataDirectory)
+    for _, legacy := range legacyDataDirectories() {
+        if legacy == dataAbs {
+            continue
+        }
+
+        log.Printf("Migrating data from %s to %s", legacy, dataDirectory)
+        if err := copyLegacyProfiles(filepath.Join(legacy, profilesDir)); err != nil {
+            return err
+        }
+        copies := []struct{ from, to string }{
+            {filepath.Join(legacy, drawingsDir), filepath.Join(dataDirectory, drawingsDir)},
+            {filepath.Join(legacy, candlesDir), filepath.Join(cacheDirectory, candlesDir)},
+            {filepath.Join(legacy, stocksFile), filepath.Join(cacheDirectory, stocksFile)},
+        }
+        for _, c := range copies {
+            if err := copyMissing(c.from, c.to); err != nil {
+                return err
+            }
+        }
+    }
+
+    note := fmt.Sprintf("Legacy data migrated on %s\n", time.Now().Format(time.RFC3339))
+    return writeFileAtomic(marker, []byte(note), 0644)
+}
+
+// copyLegacyProfiles copies the profiles of a legacy profiles directory,
+// replacing existing ones only when the legacy copy was modified later
+func copyLegacyProfiles(dir string) error {
+    entries, err := os.ReadDir(dir)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    for _, entry := range entries {
+        if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+            continue
+        }
+
+        content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+        if err != nil {
+            return err
+        }
+        target := filepath.Join(dataDirectory, profilesDir, entry.Name())
+        if existing, err := os.ReadFile(target); err == nil && !modifiedLater(content, existing) {
+            continue
+        }
+        if err := writeFileAtomic(target, content, 0644); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// modifiedLater reports whether profile a was modified after profile b.
+// Profiles that can't be read count as never modified.
+func modifiedLater(a, b []byte) bool {
+    var pa, pb struct {
+        LastModified time.Time `json:"last_modified"`
+    }
+    if json.Unmarshal(a, &pa) != nil {
+        return false
+    }
+    if json.Unmarshal(b, &pb) != nil {
+        return true
+    }
+    return pa.LastModified.After(pb.LastModified)
+}
+
+// copyMissing copies a file or directory tree, skipping files that already exist at the target
+func copyMissing(from, to string) error {
+    err := filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+
+        rel, err := filepath.Rel(from, path)
+        if err != nil {
+            return err
+        }
+        target := filepath.Join(to, rel)
+
+        if entry.IsDir() {
+            return os.MkdirAll(target, 0755)
+        }
+        if _, err := os.Stat(target); err == nil {
+            return nil
+        }
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return err
+        }
+        if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+            return err
+        }
+        return writeFileAtomic(target, content, 0644)
+    })
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,208 @@
+// File: internal/data/provider.go
+package data
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Provider names accepted in configuration
+const (
+    ProviderMock         = "mock"
+    ProviderAlphaVantage = "alphavantage"
+
+    envProviderName = "MOOSEMARKET_PROVIDER"
+    envSeedName     = "MOOSEMARKET_SEED"
+    envStreamURL    = "MOOSEMARKET_STREAM_URL"
+)
+
+// MarketDataProvider is a source of market data for the UI. Every request
+// takes a context so that superseded requests can be cancelled.
+type MarketDataProvider interface {
+    // Name returns the configuration name of the provider
+    Name() string
+
+    // GetQuote returns the latest quote for a symbol
+    GetQuote(ctx context.Context, symbol string) (*models.Stock, error)
+
+    // GetCandles returns up to count candles for a symbol and timeframe
+    GetCandles(ctx context.Context, symbol, timeframe string, count int) (*models.CandleData, error)
+
+    // SearchSymbols returns symbols matching a keyword
+    SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error)
+
+    // GetCompanyInfo returns descriptive data about a company
+    GetCompanyInfo(ctx context.Context, symbol string) (*models.CompanyInfo, error)
+}
+
+// QuotaReporter is implemented by providers with a limited daily request budget
+type QuotaReporter interface {
+    // RemainingRequests returns the requests left today and the daily limit
+    RemainingRequests() (int, int)
+}
+
+// QuotaOf returns the quota reporter of a provider or of the provider it wraps
+func QuotaOf(provider MarketDataProvider) (QuotaReporter, bool) {
+    for provider != nil {
+        if reporter, ok := provider.(QuotaReporter); ok {
+            return reporter, true
+        }
+        wrapper, ok := provider.(interface{ Unwrap() MarketDataProvider })
+        if !ok {
+            break
+        }
+        provider = wrapper.Unwrap()
+    }
+    return nil, false
+}
+
+// ProviderConfig selects and configures a market data provider
+type ProviderConfig struct {
+    Name   string
+    APIKey string
+    Seed   int64 // seed of the mock simulator, 0 picks one from the clock
+
+    // StreamURL is the WebSocket quote stream to use. When empty the mock
+    // provider streams from a local server replaying its simulator.
+    StreamURL string
+}
+
+// ProviderConfigFromEnv builds a provider configuration from environment variables.
+// Alpha Vantage is used when an API key is set and no provider is named explicitly.
+func ProviderConfigFromEnv() ProviderConfig {
+    cfg := ProviderConfig{
+        Name:      strings.ToLower(strings.TrimSpace(os.Getenv(envProviderName))),
+        APIKey:    os.Getenv(envAPIKeyName),
+        StreamURL: strings.TrimSpace(os.Getenv(envStreamURL)),
+    }
+    
+    if seed, err := strconv.ParseInt(os.Getenv(envSeedName), 10, 64); err == nil {
+        cfg.Seed = seed
+    }
+
+    if cfg.Name == "" {
+        if cfg.APIKey != "" {
+            cfg.Name = ProviderAlphaVantage
+        } else {
+            cfg.Name = ProviderMock
+        }
+    }
+
+    return cfg
+}
+
+// NewProvider creates the market data provider described by the configuration
+func NewProvider(cfg ProviderConfig) (MarketDataProvider, error) {
+    switch cfg.Name {
+    case "", ProviderMock:
+        seed := cfg.Seed
+        if seed == 0 {
+            seed = time.Now().UnixNano()
+        }
+        return NewMockProvider(NewMarketSimulator(seed)), nil
+    case ProviderAlphaVantage:
+        if cfg.APIKey == "" {
+            return nil, fmt.Errorf("alpha vantage API key not set, please set the %s environment variable", envAPIKeyName)
+        }
+        client := NewAlphaVantageClient()
+        client.APIKey = cfg.APIKey
+        return NewCachedProvider(NewAlphaVantageProvider(client)), nil
+    default:
+        return nil, fmt.Errorf("unknown market data provider %q", cfg.Name)
+    }
+}
+
+// MockProvider serves simulated data for the built-in NYSE and TSX symbols
+type MockProvider struct {
+    simulator *MarketSimulator
+}
+
+// NewMockProvider creates a provider backed by a market simulator
+func NewMockProvider(simulator *MarketSimulator) *MockProvider {
+    return &MockProvider{simulator: simulator}
+}
+
+// Simulator returns the simulator backing the provider
+func (p *MockProvider) Simulator() *MarketSimulator {
+    return p.simulator
+}
+
+// Name returns the configuration name of the provider
+func (p *MockProvider) Name() string {
+    return ProviderMock
+}
+
+// IsOpen reports that the simulated market trades around the clock
+func (p *MockProvider) IsOpen(t time.Time) bool {
+    return true
+}
+
+// GetQuote returns the latest quote for a symbol
+func (p *MockProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    return p.simulator.Quote(symbol)
+}
+
+// GetCandles returns up to count candles for a symbol and timeframe
+func (p *MockProvider) GetCandles(ctx context.Context, symbol, timeframe string, count int) (*models.CandleData, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    return p.simulator.Candles(symbol, timeframe, count)
+}
+
+// SearchSymbols returns symbols matching a keyword
+func (p *MockProvider) SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    var matches []models.SymbolMatch
+    for _, symbol := range matchSymbols(query) {
+        matches = append(matches, models.SymbolMatch{
+            Symbol:   symbol,
+            Name:     companyNames[symbol],
+            Exchange: exchanges[symbol],
+            Currency: currencyForExchange(exchanges[symbol]),
+        })
+    }
+    return matches, nil
+}
+
+// GetCompanyInfo returns descriptive data about a company
+func (p *MockProvider) GetCompanyInfo(ctx context.Context, symbol string) (*models.CompanyInfo, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    
+    stock, err := p.simulator.Quote(symbol)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.CompanyInfo{
+        Symbol:        stock.Symbol,
+        Name:          stock.Name,
+        Exchange:      stock.Exchange,
+        Currency:      currencyForExchange(stock.Exchange),
+        MarketCap:     stock.MarketCap,
+        PE:            stock.PE,
+        DividendYield: stock.Dividend / stock.Price,
+    }, nil
+}
+
+// currencyForExchange returns the trading currency of a mock exchange
+func currencyForExchange(exchange string) string {
+    if exchange == "TSX" {
+        return "CAD"
+    }
+    return "USD"
+}
@@ -0,0 +1,102 @@
+// File: internal/data/ratelimit.go
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDailyQuotaExhausted is returned when no request budget is left for today
+var ErrDailyQuotaExhausted = errors.New("daily request quota exhausted")
+
+// RateLimiter is a token bucket with an additional daily request budget
+type RateLimiter struct {
+	mu         sync.Mutex
+	capacity   float64
+	tokens     float64
+	refillRate float64 // tokens per second
+	lastRefill time.Time
+	dailyLimit int
+	dailyUsed  int
+	day        string
+}
+
+// NewRateLimiter creates a limiter allowing perMinute calls per minute and perDay calls per day
+func NewRateLimiter(perMinute, perDay int) *RateLimiter {
+	return &RateLimiter{
+		capacity:   float64(perMinute),
+		tokens:     float64(perMinute),
+		refillRate: float64(perMinute) / 60.0,
+		lastRefill: time.Now(),
+		dailyLimit: perDay,
+	}
+}
+
+// Wait blocks until a call may be made and consumes it from the budget,
+// giving up when the context is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.resetDay(now)
+
+		if l.dailyUsed >= l.dailyLimit {
+			l.mu.Unlock()
+			return ErrDailyQuotaExhausted
+		}
+
+		// Refill the bucket for the time elapsed since the last call
+		l.tokens += now.Sub(l.lastRefill).Seconds() * l.refillRate
+		if l.tokens > l.capacity {
+			l.tokens = l.capacity
+		}
+		l.lastRefill = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.dailyUsed++
+			l.mu.Unlock()
+			return nil
+		}
+
+		// Sleep until the next token is available
+		wait := time.Duration((1 - l.tokens) / l.refillRate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Exhaust marks today's budget as used up, for when the server says so first
+func (l *RateLimiter) Exhaust() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resetDay(time.Now())
+	l.dailyUsed = l.dailyLimit
+}
+
+// Remaining returns the number of calls left today and the daily limit
+func (l *RateLimiter) Remaining() (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resetDay(time.Now())
+	return l.dailyLimit - l.dailyUsed, l.dailyLimit
+}
+
+// resetDay starts a new daily budget when the UTC date changes.
+// The caller must hold l.mu.
+func (l *RateLimiter) resetDay(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if day != l.day {
+		l.day = day
+		l.dailyUsed = 0
+	}
+}
@@ -0,0 +1,295 @@
+// File: internal/data/scheduler.go
+package data
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// DefaultRefreshInterval is used when a profile doesn't set one
+const DefaultRefreshInterval = 60 * time.Second
+
+// TradingHours is implemented by providers whose market isn't open on the
+// regular exchange schedule
+type TradingHours interface {
+    // IsOpen reports whether quotes change at t
+    IsOpen(t time.Time) bool
+}
+
+// QuoteScheduler refreshes quotes for every subscriber from a single loop.
+// On each tick the symbols of all subscribers are merged so that every symbol
+// is fetched once, then each subscriber receives the quotes it asked for.
+// Ticks are skipped while the market is closed.
+type QuoteScheduler struct {
+    provider MarketDataProvider
+
+    mu          sync.Mutex
+    interval    time.Duration
+    subscribers map[int]*quoteSubscriber
+    nextID      int
+    quotes      map[string]*models.Stock // latest quote of every symbol
+    cancel      context.CancelFunc
+
+    reschedule chan struct{} // the interval changed
+    refresh    chan struct{} // symbols without a quote must be fetched now
+}
+
+// quoteSubscriber is a consumer of scheduled quotes
+type quoteSubscriber struct {
+    symbols  []string
+    onQuotes func(map[string]*models.Stock)
+}
+
+// NewQuoteScheduler creates a scheduler fetching quotes from provider every interval
+func NewQuoteScheduler(provider MarketDataProvider, interval time.Duration) *QuoteScheduler {
+    if interval <= 0 {
+        interval = DefaultRefreshInterval
+    }
+
+    return &QuoteScheduler{
+        provider:    provider,
+        interval:    interval,
+        subscribers: make(map[int]*quoteSubscriber),
+        quotes:      make(map[string]*models.Stock),
+        reschedule:  make(chan struct{}, 1),
+        refresh:     make(chan struct{}, 1),
+    }
+}
+
+// Subscribe registers a consumer for the quotes of symbols and returns its ID.
+// onQuotes is called from the scheduler goroutine.
+func (s *QuoteScheduler) Subscribe(symbols []string, onQuotes func(map[string]*models.Stock)) int {
+    s.mu.Lock()
+    s.nextID++
+    id := s.nextID
+    s.subscribers[id] = &quoteSubscriber{onQuotes: onQuotes}
+    s.mu.Unlock()
+
+    s.SetSymbols(id, symbols)
+    return id
+}
+
+// SetSymbols replaces the symbols of a subscriber. Quotes already known are
+// delivered right away, the missing ones are fetched without waiting for a tick.
+func (s *QuoteScheduler) SetSymbols(id int, symbols []string) {
+    s.mu.Lock()
+    sub, ok := s.subscribers[id]
+    if !ok {
+        s.mu.Unlock()
+        return
+    }
+    sub.symbols = append([]string(nil), symbols...)
+
+    known := make(map[string]*models.Stock)
+    missing := false
+    for _, symbol := range symbols {
+        if quote, ok := s.quotes[symbol]; ok {
+            known[symbol] = quote
+        } else {
+            missing = true
+        }
+    }
+    s.mu.Unlock()
+
+    if len(known) > 0 {
+        go sub.onQuotes(known)
+    }
+    if missing {
+        signal(s.refresh)
+    }
+}
+
+// Unsubscribe removes a subscriber
+func (s *QuoteScheduler) Unsubscribe(id int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.subscribers, id)
+}
+
+// SetInterval changes the refresh interval, taking effect immediately.
+// A zero or negative interval selects DefaultRefreshInterval.
+func (s *QuoteScheduler) SetInterval(interval time.Duration) {
+    if interval <= 0 {
+        interval = DefaultRefreshInterval
+    }
+
+    s.mu.Lock()
+    changed := interval != s.interval
+    s.interval = interval
+    s.mu.Unlock()
+
+    if changed {
+        signal(s.reschedule)
+    }
+}
+
+// Interval returns the current refresh interval
+func (s *QuoteScheduler) Interval() time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.interval
+}
+
+// Start runs the refresh loop in the background until Stop is called
+func (s *QuoteScheduler) Start() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.cancel != nil {
+        return
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    s.cancel = cancel
+    go s.run(ctx)
+}
+
+// Stop ends the refresh loop and cancels any fetch in flight
+func (s *QuoteScheduler) Stop() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.cancel != nil {
+        s.cancel()
+        s.cancel = nil
+    }
+}
+
+// run is the refresh loop
+func (s *QuoteScheduler) run(ctx context.Context) {
+    // Always start with fresh quotes, even when the market is closed
+    s.tick(ctx, false)
+
+    for {
+        timer := time.NewTimer(s.Interval())
+
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-s.reschedule:
+            // Restart the wait with the new interval
+            timer.Stop()
+        case <-s.refresh:
+            timer.Stop()
+            s.tick(ctx, true)
+        case <-timer.C:
+            if s.isOpen(time.Now()) {
+                s.tick(ctx, false)
+            }
+        }
+    }
+}
+
+// tick fetches each subscribed symbol once and publishes the quotes.
+// With onlyMissing set, only symbols without a known quote are fetched.
+func (s *QuoteScheduler) tick(ctx context.Context, onlyMissing bool) {
+    // Merge the symbols of every subscriber
+    s.mu.Lock()
+    var symbols []string
+    seen := make(map[string]bool)
+    for _, sub := range s.subscribers {
+        for _, symbol := range sub.symbols {
+            if seen[symbol] {
+                continue
+            }
+            seen[symbol] = true
+            if _, known := s.quotes[symbol]; onlyMissing && known {
+                continue
+            }
+            symbols = append(symbols, symbol)
+        }
+    }
+    s.mu.Unlock()
+
+    if len(symbols) == 0 {
+        return
+    }
+
+    fetched := make(map[string]*models.Stock, len(symbols))
+    for _, symbol := range symbols {
+        quote, err := s.provider.GetQuote(ctx, symbol)
+        if ctx.Err() != nil {
+            return
+        }
+        if err != nil {
+            log.Printf("Error refreshing %s: %v", symbol, err)
+            continue
+        }
+        fetched[symbol] = quote
+    }
+
+    // Record the quotes and work out what each subscriber gets
+    type delivery struct {
+        onQuotes func(map[string]*models.Stock)
+        quotes   map[string]*models.Stock
+    }
+    var deliveries []delivery
+
+    s.mu.Lock()
+    for symbol, quote := range fetched {
+        s.quotes[symbol] = quote
+    }
+    for _, sub := range s.subscribers {
+        quotes := make(map[string]*models.Stock)
+        for _, symbol := range sub.symbols {
+            if quote, ok := fetched[symbol]; ok {
+                quotes[symbol] = quote
+            }
+        }
+        if len(quotes) > 0 {
+            deliveries = append(deliveries, delivery{sub.onQuotes, quotes})
+        }
+    }
+    s.mu.Unlock()
+
+    // Call subscribers without holding the lock
+    for _, d := range deliveries {
+        d.onQuotes(d.quotes)
+    }
+}
+
+// isOpen reports whether the provider's market is open at t
+func (s *QuoteScheduler) isOpen(t time.Time) bool {
+    if hours, ok := s.provider.(TradingHours); ok {
+        return hours.IsOpen(t)
+    }
+    return IsMarketOpen(t)
+}
+
+// exchangeLocation is the time zone of the NYSE and TSX regular sessions
+var exchangeLocation = loadExchangeLocation()
+
+// loadExchangeLocation loads the Toronto time zone, falling back to a fixed
+// Eastern Standard Time offset when no time zone database is installed
+func loadExchangeLocation() *time.Location {
+    location, err := time.LoadLocation("America/Toronto")
+    if err != nil {
+        return time.FixedZone("EST", -5*60*60)
+    }
+    return location
+}
+
+// IsMarketOpen reports whether the NYSE and TSX regular sessions, weekdays
+// from 9:30 to 16:00 Eastern time, are open at t. Holidays are not considered.
+func IsMarketOpen(t time.Time) bool {
+    local := t.In(exchangeLocation)
+    if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+        return false
+    }
+
+    minutes := local.Hour()*60 + local.Minute()
+    return minutes >= 9*60+30 && minutes < 16*60
+}
+
+// signal wakes a loop waiting on ch without blocking when it is already signalled
+func signal(ch chan struct{}) {
+    select {
+    case ch <- struct{}{}:
+    default:
+    }
+}
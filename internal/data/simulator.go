@@ -0,0 +1,459 @@
+// File: internal/data/simulator.go
+package data
+
+import (
+    "fmt"
+    "hash/fnv"
+    "math"
+    "math/rand"
+    "sync"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+const (
+    minutesPerDay = 24 * 60
+
+    // maxCachedMinutePaths bounds how many intraday paths are kept per symbol
+    maxCachedMinutePaths = 16
+)
+
+// simulatorEpoch is the first simulated day, no candles exist before it
+var simulatorEpoch = time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// MarketSimulator generates a persistent price path for every mock symbol.
+// Daily closes follow a geometric Brownian motion and each day is filled in
+// minute by minute with a Brownian bridge, so quotes and candles of every
+// timeframe agree with each other. The same seed always yields the same market.
+type MarketSimulator struct {
+    mu    sync.Mutex
+    seed  int64
+    now   func() time.Time
+    paths map[string]*pricePath
+}
+
+// pricePath holds the simulated history of a single symbol
+type pricePath struct {
+    seed          int64
+    startPrice    float64
+    drift         float64 // daily log drift
+    volatility    float64 // daily log volatility
+    dailyVolume   float64
+    shares        float64
+    pe            float64
+    dividendYield float64
+
+    closeRNG *rand.Rand
+    closes   []float64             // daily closes, index 0 is the epoch day
+    days     map[int]simulatedDay  // summaries of completed days
+    minutes  map[int]*minutePath   // recently used intraday paths
+}
+
+// minutePath holds one simulated day at minute resolution
+type minutePath struct {
+    prices  []float64 // prices[0] is the open, prices[k] the close of minute k-1
+    volumes []int64
+}
+
+// simulatedDay summarizes a day, or the part of it simulated so far
+type simulatedDay struct {
+    open, high, low, close float64
+    volume                 int64
+}
+
+// NewMarketSimulator creates a simulator for the built-in NYSE and TSX symbols
+func NewMarketSimulator(seed int64) *MarketSimulator {
+    return &MarketSimulator{
+        seed:  seed,
+        now:   time.Now,
+        paths: make(map[string]*pricePath),
+    }
+}
+
+// Seed returns the seed the simulator was created with
+func (s *MarketSimulator) Seed() int64 {
+    return s.seed
+}
+
+// SetClock replaces the clock used to decide the current simulated minute
+func (s *MarketSimulator) SetClock(now func() time.Time) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.now = now
+}
+
+// Quote returns the simulated quote of a symbol at the current time
+func (s *MarketSimulator) Quote(symbol string) (*models.Stock, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    path, err := s.pathFor(symbol)
+    if err != nil {
+        return nil, err
+    }
+
+    now := s.now()
+    day, minute := simulatorPosition(now)
+    if day < 0 {
+        return nil, fmt.Errorf("no simulated data for %s before %s", symbol, simulatorEpoch.Format("2006-01-02"))
+    }
+
+    // Summarize today up to and including the current minute
+    today := summarizeMinutes(path.minutePath(day), 0, minute+1)
+    prevClose := path.dayOpen(day)
+    change := today.close - prevClose
+
+    return &models.Stock{
+        Symbol:        symbol,
+        Name:          companyNames[symbol],
+        Price:         today.close,
+        Change:        change,
+        ChangePercent: (change / prevClose) * 100,
+        Open:          today.open,
+        High:          today.high,
+        Low:           today.low,
+        Volume:        today.volume,
+        MarketCap:     path.shares * today.close,
+        PE:            path.pe,
+        Dividend:      today.close * path.dividendYield,
+        Exchange:      exchanges[symbol],
+        Timestamp:     now.Unix(),
+    }, nil
+}
+
+// Trade returns a simulated trade and the surrounding bid and ask at the
+// current time. Within a minute the price moves from the minute's open to its
+// close with a small deterministic wobble, so streamed trades end on the same
+// close as the minute candles.
+func (s *MarketSimulator) Trade(symbol string) (*models.Tick, *models.Tick, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    path, err := s.pathFor(symbol)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    now := s.now()
+    day, minute := simulatorPosition(now)
+    if day < 0 {
+        return nil, nil, fmt.Errorf("no simulated data for %s before %s", symbol, simulatorEpoch.Format("2006-01-02"))
+    }
+
+    // Interpolate inside the minute, the wobble vanishes at both ends
+    minutes := path.minutePath(day)
+    from, to := minutes.prices[minute], minutes.prices[minute+1]
+    f := float64(now.Second()*1000+now.Nanosecond()/1e6) / 60000
+    wobble := math.Sin(f*math.Pi*7+float64(mixSeed(path.seed, int64(minute))%100)) * f * (1 - f) * 0.002
+    price := (from + (to-from)*f) * (1 + wobble)
+
+    // Spread a tenth of a percent around the trade, at least a cent
+    halfSpread := math.Max(price*0.0005, 0.01)
+    size := minutes.volumes[minute] / 60
+    if size < 1 {
+        size = 1
+    }
+
+    trade := &models.Tick{Type: "trade", Symbol: symbol, Price: price, Size: size, Time: now}
+    quote := &models.Tick{Type: "quote", Symbol: symbol, Bid: price - halfSpread, Ask: price + halfSpread, Time: now}
+    return trade, quote, nil
+}
+
+// Candles returns the most recent count candles of a symbol, the last one
+// covering the current time and therefore possibly incomplete
+func (s *MarketSimulator) Candles(symbol, timeframe string, count int) (*models.CandleData, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    path, err := s.pathFor(symbol)
+    if err != nil {
+        return nil, err
+    }
+
+    now := s.now()
+    day, minute := simulatorPosition(now)
+    if day < 0 {
+        return nil, fmt.Errorf("no simulated data for %s before %s", symbol, simulatorEpoch.Format("2006-01-02"))
+    }
+
+    var candles []models.CandleStick
+    if IsIntradayTimeframe(timeframe) {
+        candles = path.intradayCandles(timeframe, count, day*minutesPerDay+minute)
+    } else {
+        candles = path.dailyCandles(timeframe, count, day, minute)
+    }
+
+    return &models.CandleData{
+        Symbol:    symbol,
+        Timeframe: timeframe,
+        Candles:   candles,
+    }, nil
+}
+
+// pathFor returns the price path of a symbol, creating it on first use.
+// The caller must hold s.mu.
+func (s *MarketSimulator) pathFor(symbol string) (*pricePath, error) {
+    if path, ok := s.paths[symbol]; ok {
+        return path, nil
+    }
+
+    // The hardcoded symbol tables are the simulator's universe
+    if _, exists := companyNames[symbol]; !exists {
+        return nil, fmt.Errorf("symbol %s not found", symbol)
+    }
+
+    hash := fnv.New64a()
+    hash.Write([]byte(symbol))
+    seed := mixSeed(s.seed, int64(hash.Sum64()))
+    rng := rand.New(rand.NewSource(seed))
+
+    path := &pricePath{
+        seed:        seed,
+        startPrice:  20.0 + rng.Float64()*280.0,
+        drift:       (rng.Float64() - 0.4) * 0.001,
+        volatility:  0.008 + rng.Float64()*0.017,
+        dailyVolume: 1000000 + rng.Float64()*9000000,
+        shares:      100000000 + rng.Float64()*10000000000,
+        pe:          15.0 + rng.Float64()*25.0,
+        days:        make(map[int]simulatedDay),
+        minutes:     make(map[int]*minutePath),
+    }
+
+    // 70% chance of paying a 0-3% dividend
+    if rng.Float64() > 0.3 {
+        path.dividendYield = rng.Float64() * 0.03
+    }
+
+    path.closeRNG = rand.New(rand.NewSource(mixSeed(seed, -1)))
+    s.paths[symbol] = path
+    return path, nil
+}
+
+// closeAt returns the close of a simulated day, extending the path as needed
+func (p *pricePath) closeAt(day int) float64 {
+    for len(p.closes) <= day {
+        prev := p.startPrice
+        if n := len(p.closes); n > 0 {
+            prev = p.closes[n-1]
+        }
+
+        // Geometric Brownian motion step
+        step := p.drift - p.volatility*p.volatility/2 + p.volatility*p.closeRNG.NormFloat64()
+        p.closes = append(p.closes, prev*math.Exp(step))
+    }
+    return p.closes[day]
+}
+
+// dayOpen returns the open of a simulated day, which is the previous close
+func (p *pricePath) dayOpen(day int) float64 {
+    if day == 0 {
+        return p.startPrice
+    }
+    return p.closeAt(day - 1)
+}
+
+// minutePath returns the intraday path of a day, keeping it cached
+func (p *pricePath) minutePath(day int) *minutePath {
+    if path, ok := p.minutes[day]; ok {
+        return path
+    }
+
+    if len(p.minutes) >= maxCachedMinutePaths {
+        p.minutes = make(map[int]*minutePath)
+    }
+
+    path := p.generateMinutes(day)
+    p.minutes[day] = path
+    return path
+}
+
+// generateMinutes builds the Brownian bridge between a day's open and close
+func (p *pricePath) generateMinutes(day int) *minutePath {
+    rng := rand.New(rand.NewSource(mixSeed(p.seed, int64(day))))
+
+    logOpen := math.Log(p.dayOpen(day))
+    logClose := math.Log(p.closeAt(day))
+    sigma := p.volatility / math.Sqrt(minutesPerDay)
+
+    // Random walk that the bridge is pinned to
+    walk := make([]float64, minutesPerDay+1)
+    for k := 1; k <= minutesPerDay; k++ {
+        walk[k] = walk[k-1] + sigma*rng.NormFloat64()
+    }
+
+    path := &minutePath{
+        prices:  make([]float64, minutesPerDay+1),
+        volumes: make([]int64, minutesPerDay),
+    }
+
+    for k := 0; k <= minutesPerDay; k++ {
+        t := float64(k) / minutesPerDay
+        path.prices[k] = math.Exp(logOpen + t*(logClose-logOpen) + walk[k] - t*walk[minutesPerDay])
+    }
+    path.prices[minutesPerDay] = p.closeAt(day)
+
+    for k := range path.volumes {
+        path.volumes[k] = int64(p.dailyVolume / minutesPerDay * (0.5 + rng.Float64()))
+    }
+
+    return path
+}
+
+// daySummary returns the summary of a completed day
+func (p *pricePath) daySummary(day int) simulatedDay {
+    if summary, ok := p.days[day]; ok {
+        return summary
+    }
+
+    // Use the cached path if there is one, otherwise generate a throwaway one
+    path, ok := p.minutes[day]
+    if !ok {
+        path = p.generateMinutes(day)
+    }
+
+    summary := summarizeMinutes(path, 0, minutesPerDay)
+    p.days[day] = summary
+    return summary
+}
+
+// intradayCandles builds candles shorter than a day ending at absolute minute last
+func (p *pricePath) intradayCandles(timeframe string, count, last int) []models.CandleStick {
+    step := int(TimeframeInterval(timeframe) / time.Minute)
+
+    first := (last/step - (count - 1)) * step
+    if first < 0 {
+        first = 0
+    }
+
+    var candles []models.CandleStick
+    for start := first; start <= last; start += step {
+        end := start + step
+        if end > last+1 {
+            end = last + 1
+        }
+
+        // A candle never crosses midnight since every step divides a day
+        day := start / minutesPerDay
+        summary := summarizeMinutes(p.minutePath(day), start%minutesPerDay, end-day*minutesPerDay)
+
+        candles = append(candles, summary.candle(simulatorEpoch.Add(time.Duration(start)*time.Minute)))
+    }
+
+    return candles
+}
+
+// dailyCandles builds candles of a day or longer ending on the current day
+func (p *pricePath) dailyCandles(timeframe string, count, today, minute int) []models.CandleStick {
+    var candles []models.CandleStick
+
+    start, end := dayBucket(timeframe, today)
+    for len(candles) < count {
+        if start < 0 {
+            start = 0
+        }
+
+        var summary simulatedDay
+        for day := start; day < end && day <= today; day++ {
+            var current simulatedDay
+            if day == today {
+                current = summarizeMinutes(p.minutePath(day), 0, minute+1)
+            } else {
+                current = p.daySummary(day)
+            }
+
+            if day == start {
+                summary = current
+                continue
+            }
+            summary.high = math.Max(summary.high, current.high)
+            summary.low = math.Min(summary.low, current.low)
+            summary.close = current.close
+            summary.volume += current.volume
+        }
+
+        candles = append(candles, summary.candle(simulatorEpoch.AddDate(0, 0, start)))
+
+        if start == 0 {
+            break
+        }
+        start, end = dayBucket(timeframe, start-1)
+    }
+
+    // Candles were built newest first
+    for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+        candles[i], candles[j] = candles[j], candles[i]
+    }
+
+    return candles
+}
+
+// dayBucket returns the range of days, end exclusive, of the candle containing day
+func dayBucket(timeframe string, day int) (int, int) {
+    switch timeframe {
+    case "1w":
+        // Weeks start on Monday
+        date := simulatorEpoch.AddDate(0, 0, day)
+        start := day - (int(date.Weekday())+6)%7
+        return start, start + 7
+    case "1mo":
+        // Months start on the first
+        date := simulatorEpoch.AddDate(0, 0, day)
+        first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+        start := int(first.Sub(simulatorEpoch).Hours() / 24)
+        end := int(first.AddDate(0, 1, 0).Sub(simulatorEpoch).Hours() / 24)
+        return start, end
+    default:
+        return day, day + 1
+    }
+}
+
+// summarizeMinutes aggregates minutes [from, to) of a path
+func summarizeMinutes(path *minutePath, from, to int) simulatedDay {
+    summary := simulatedDay{
+        open: path.prices[from],
+        high: path.prices[from],
+        low:  path.prices[from],
+    }
+
+    for k := from; k < to; k++ {
+        price := path.prices[k+1]
+        summary.high = math.Max(summary.high, price)
+        summary.low = math.Min(summary.low, price)
+        summary.volume += path.volumes[k]
+    }
+    summary.close = path.prices[to]
+
+    return summary
+}
+
+// candle converts a summary into a candlestick starting at t
+func (d simulatedDay) candle(t time.Time) models.CandleStick {
+    return models.CandleStick{
+        Time:   t,
+        Open:   d.open,
+        High:   d.high,
+        Low:    d.low,
+        Close:  d.close,
+        Volume: d.volume,
+    }
+}
+
+// simulatorPosition returns the simulated day and minute of day for a time
+func simulatorPosition(t time.Time) (int, int) {
+    elapsed := t.Sub(simulatorEpoch)
+    if elapsed < 0 {
+        return -1, 0
+    }
+    minutes := int(elapsed / time.Minute)
+    return minutes / minutesPerDay, minutes % minutesPerDay
+}
+
+// mixSeed derives an independent seed from a base seed and a salt
+func mixSeed(seed, salt int64) int64 {
+    // SplitMix64 finalizer
+    z := uint64(seed) + uint64(salt)*0x9E3779B97F4A7C15
+    z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+    z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+    return int64(z ^ (z >> 31))
+}
@@ -1,47 +1,167 @@
+// File: internal/data/storage.go
 package data
 
 import (
-    "encoding/json"
     "errors"
     "fmt"
+    "log"
     "os"
     "path/filepath"
+    "sort"
     "sync"
     "time"
 
     "github.com/frederikblais/Moose-Market/internal/models"
 )
 
-// Storage paths
+// Storage backend names accepted in configuration
 const (
-    dataDirectory = "moosemarket_data"
-    profilesDir   = "profiles"
-    stocksFile    = "stocks.json"
-    candlesDir    = "candles"
-    drawingsDir   = "drawings"
+    StorageJSON = "json"
+    StorageBolt = "bolt"
 )
 
+// Storage is a persistence backend for profiles, drawings and cached market data
+type Storage interface {
+    // Name returns the configuration name of the backend
+    Name() string
+
+    // Close releases the backend's files
+    Close() error
+
+    GetProfiles() ([]models.Profile, error)
+    GetProfileByID(id string) (*models.Profile, error)
+    SaveProfile(profile *models.Profile) error
+
+    // DeleteProfile moves a profile to the trash, RestoreProfile brings it
+    // back and PurgeProfile removes it from the trash for good
+    DeleteProfile(id string) error
+    TrashedProfiles() ([]models.Profile, error)
+    RestoreProfile(id string) error
+    PurgeProfile(id string) error
+
+    // LoadRawProfile returns a profile as stored, still encrypted if it is
+    LoadRawProfile(id string) ([]byte, error)
+
+    // rekeyProfile saves a profile sealed with next, or in the clear when
+    // next is nil, and makes next its session key. The stored version is
+    // checked, and the backups sealed again, with the key it replaces.
+    rekeyProfile(profile *models.Profile, next *profileKey) error
+
+    // LoadTransactions returns the transactions of a profile in a symbol made
+    // in [from, to), oldest first. A zero time leaves that end open.
+    LoadTransactions(profileID, symbol string, from, to time.Time) ([]models.TransactionRecord, error)
+
+    SaveStocks(stocks []models.Stock) error
+    LoadStocks() ([]models.Stock, error)
+
+    SaveCandleData(candles models.CandleData) error
+    LoadCandleData(symbol, timeframe string) (*models.CandleData, error)
+
+    // LoadCandleRange returns the candles of a series starting in [from, to).
+    // A zero time leaves that end open.
+    LoadCandleRange(symbol, timeframe string, from, to time.Time) (*models.CandleData, error)
+
+    SaveDrawings(symbol string, drawings []models.DrawingObject) error
+    LoadDrawings(symbol string) ([]models.DrawingObject, error)
+}
+
 var (
     // Ensure thread safety for storage operations
     storageMutex sync.RWMutex
-    
+
     // Store active profile in memory
     activeProfile *models.Profile
+
+    // backend is the storage in use, set up by Initialize
+    backend      Storage = jsonStorage{}
+    backendMutex sync.RWMutex
 )
 
-// Initialize makes sure all necessary directories exist
+// currentStorage returns the storage backend in use
+func currentStorage() Storage {
+    backendMutex.RLock()
+    defer backendMutex.RUnlock()
+    return backend
+}
+
+// CurrentStorage returns the storage backend in use
+func CurrentStorage() Storage {
+    return currentStorage()
+}
+
+// Initialize makes sure all necessary directories exist, brings over data
+// left by older versions in the working directory and opens the configured
+// storage backend
 func Initialize() error {
+    if err := initializeDirectories(); err != nil {
+        return err
+    }
+
+    storageMutex.RLock()
+    name := storageBackend
+    storageMutex.RUnlock()
+
+    switch name {
+    case "", StorageJSON:
+        setStorage(jsonStorage{})
+    case StorageBolt:
+        store, err := OpenBoltStorage(DataDirectory(), CacheDirectory())
+        if err != nil {
+            return err
+        }
+        if err := store.importJSONOnce(); err != nil {
+            store.Close()
+            return fmt.Errorf("failed to move JSON data into the database: %w", err)
+        }
+        setStorage(store)
+    default:
+        return fmt.Errorf("unknown storage backend %q", name)
+    }
+
+    return nil
+}
+
+// Shutdown closes the storage backend
+func Shutdown() error {
+    return currentStorage().Close()
+}
+
+// setStorage replaces the storage backend, closing the previous one
+func setStorage(store Storage) {
+    backendMutex.Lock()
+    previous := backend
+    backend = store
+    backendMutex.Unlock()
+
+    if previous != nil && previous != store {
+        previous.Close()
+    }
+}
+
+// initializeDirectories creates the storage directories and migrates legacy data
+func initializeDirectories() error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
     dirs := []string{
         dataDirectory,
         filepath.Join(dataDirectory, profilesDir),
-        filepath.Join(dataDirectory, candlesDir),
         filepath.Join(dataDirectory, drawingsDir),
+        cacheDirectory,
+        filepath.Join(cacheDirectory, candlesDir),
     }
 
     for _, dir := range dirs {
         if err := os.MkdirAll(dir, 0755); err != nil {
             return fmt.Errorf("failed to create directory %s: %w", dir, err)
         }
+
+        // Clean up after writes interrupted by a crash
+        removeTempFiles(dir)
+    }
+
+    if err := migrateLegacyData(); err != nil {
+        return fmt.Errorf("failed to migrate data from %s: %w", legacyDataDirName, err)
     }
 
     return nil
@@ -51,79 +171,105 @@ func Initialize() error {
 
 // GetProfiles returns a list of all available profiles
 func GetProfiles() ([]models.Profile, error) {
-    storageMutex.RLock()
-    defer storageMutex.RUnlock()
-
-    var profiles []models.Profile
+    return currentStorage().GetProfiles()
+}
 
-    // Read the profiles directory
-    profilesPath := filepath.Join(dataDirectory, profilesDir)
-    files, err := os.ReadDir(profilesPath)
-    if err != nil {
-        return nil, err
-    }
+// GetProfileByID loads a profile by its ID
+func GetProfileByID(id string) (*models.Profile, error) {
+    return currentStorage().GetProfileByID(id)
+}
 
-    for _, file := range files {
-        if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
-            continue
-        }
+// SaveProfile saves a profile and records what changed in its audit log
+func SaveProfile(profile *models.Profile) error {
+    store := currentStorage()
 
-        profilePath := filepath.Join(profilesPath, file.Name())
-        profileData, err := os.ReadFile(profilePath)
-        if err != nil {
-            continue // Skip files we can't read
-        }
+    // Keep the stored version to compare with
+    previous, err := store.GetProfileByID(profile.ID)
+    if err != nil || previous.Locked {
+        previous = nil
+    }
 
-        var profile models.Profile
-        if err := json.Unmarshal(profileData, &profile); err != nil {
-            continue // Skip invalid JSON
-        }
+    if err := store.SaveProfile(profile); err != nil {
+        return err
+    }
 
-        profiles = append(profiles, profile)
+    // The profile is saved even when its changes can't be logged
+    if err := recordProfileChange(previous, profile); err != nil {
+        log.Printf("Failed to record the changes of profile %s in its audit log: %v", profile.ID, err)
     }
+    notifySync(profile.ID)
+    return nil
+}
 
-    return profiles, nil
+// DeleteProfile moves a profile to the trash. It fails with ErrProfileExists
+// when the trash already holds a profile with the same ID.
+func DeleteProfile(id string) error {
+    return currentStorage().DeleteProfile(id)
 }
 
-// GetProfileByID loads a profile by its ID
-func GetProfileByID(id string) (*models.Profile, error) {
-    storageMutex.RLock()
-    defer storageMutex.RUnlock()
+// TrashedProfiles returns the profiles in the trash
+func TrashedProfiles() ([]models.Profile, error) {
+    return currentStorage().TrashedProfiles()
+}
 
-    profilePath := filepath.Join(dataDirectory, profilesDir, id+".json")
-    profileData, err := os.ReadFile(profilePath)
-    if err != nil {
-        return nil, err
-    }
+// RestoreProfile moves a profile out of the trash. It fails with
+// ErrProfileExists when a profile with the same ID was created since.
+func RestoreProfile(id string) error {
+    return currentStorage().RestoreProfile(id)
+}
 
-    var profile models.Profile
-    if err := json.Unmarshal(profileData, &profile); err != nil {
-        return nil, err
+// PurgeProfile removes a profile and its audit log from the trash for good
+func PurgeProfile(id string) error {
+    if err := currentStorage().PurgeProfile(id); err != nil {
+        return err
     }
-
-    return &profile, nil
+    return removeAuditLog(id)
 }
 
-// SaveProfile saves a profile to disk
-func SaveProfile(profile *models.Profile) error {
-    storageMutex.Lock()
-    defer storageMutex.Unlock()
+// LoadTransactions returns the transactions of a profile in a symbol made in [from, to)
+func LoadTransactions(profileID, symbol string, from, to time.Time) ([]models.TransactionRecord, error) {
+    return currentStorage().LoadTransactions(profileID, symbol, from, to)
+}
 
-    profileData, err := json.MarshalIndent(profile, "", "  ")
-    if err != nil {
-        return err
+// profileTransactions collects the transactions of a profile in a symbol made
+// in [from, to), oldest first. An empty symbol matches every symbol.
+func profileTransactions(profile *models.Profile, symbol string, from, to time.Time) []models.TransactionRecord {
+    var records []models.TransactionRecord
+    for _, account := range profile.Accounts {
+        for _, position := range account.Positions {
+            if symbol != "" && position.StockSymbol != symbol {
+                continue
+            }
+            for _, transaction := range position.Transactions {
+                if inTimeRange(transaction.Date, from, to) {
+                    records = append(records, models.TransactionRecord{
+                        AccountID:   account.ID,
+                        StockSymbol: position.StockSymbol,
+                        Transaction: transaction,
+                    })
+                }
+            }
+        }
     }
 
-    profilePath := filepath.Join(dataDirectory, profilesDir, profile.ID+".json")
-    return os.WriteFile(profilePath, profileData, 0644)
+    sort.SliceStable(records, func(i, k int) bool {
+        return records[i].Transaction.Date.Before(records[k].Transaction.Date)
+    })
+    return records
+}
+
+// inTimeRange reports whether t is in [from, to), zero bounds being open
+func inTimeRange(t, from, to time.Time) bool {
+    return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
 }
 
 // CreateProfile creates a new profile
 func CreateProfile(name string) (*models.Profile, error) {
-    id := fmt.Sprintf("profile_%d", time.Now().Unix())
+    id := newProfileID()
     now := time.Now()
 
     profile := &models.Profile{
+        SchemaVersion: ProfileSchemaVersion,
         ID:           id,
         Name:         name,
         CreatedAt:    now,
@@ -151,13 +297,22 @@ func CreateProfile(name string) (*models.Profile, error) {
     return profile, nil
 }
 
-// DeleteProfile removes a profile
-func DeleteProfile(id string) error {
-    storageMutex.Lock()
-    defer storageMutex.Unlock()
+// newProfileID returns an ID for a new profile, skipping IDs already in use,
+// in the trash included
+func newProfileID() string {
+    trashed := make(map[string]bool)
+    if profiles, err := TrashedProfiles(); err == nil {
+        for _, profile := range profiles {
+            trashed[profile.ID] = true
+        }
+    }
 
-    profilePath := filepath.Join(dataDirectory, profilesDir, id+".json")
-    return os.Remove(profilePath)
+    for n := time.Now().Unix(); ; n++ {
+        id := fmt.Sprintf("profile_%d", n)
+        if _, err := GetProfileByID(id); errors.Is(err, os.ErrNotExist) && !trashed[id] {
+            return id
+        }
+    }
 }
 
 // SetActiveProfile sets the currently active profile
@@ -174,127 +329,53 @@ func GetActiveProfile() *models.Profile {
     return activeProfile
 }
 
+// GetActiveSettings returns a copy of the active profile's settings
+func GetActiveSettings() (models.Settings, bool) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+    if activeProfile == nil {
+        return models.Settings{}, false
+    }
+    return activeProfile.Settings, true
+}
+
 // Stock Operations
 
-// SaveStocks saves stock data to a JSON file
+// SaveStocks saves stock data
 func SaveStocks(stocks []models.Stock) error {
-    storageMutex.Lock()
-    defer storageMutex.Unlock()
-
-    bytes, err := json.MarshalIndent(stocks, "", "  ")
-    if err != nil {
-        return err
-    }
-
-    stocksPath := filepath.Join(dataDirectory, stocksFile)
-    return os.WriteFile(stocksPath, bytes, 0644)
+    return currentStorage().SaveStocks(stocks)
 }
 
-// LoadStocks loads stock data from a JSON file
+// LoadStocks loads stock data
 func LoadStocks() ([]models.Stock, error) {
-    storageMutex.RLock()
-    defer storageMutex.RUnlock()
-
-    var stocks []models.Stock
-
-    stocksPath := filepath.Join(dataDirectory, stocksFile)
-    if _, err := os.Stat(stocksPath); os.IsNotExist(err) {
-        // If no file yet, return an empty slice
-        return stocks, nil
-    }
-
-    bytes, err := os.ReadFile(stocksPath)
-    if err != nil {
-        return stocks, err
-    }
-
-    err = json.Unmarshal(bytes, &stocks)
-    return stocks, err
+    return currentStorage().LoadStocks()
 }
 
 // Candle Data Operations
 
 // SaveCandleData saves candle data for a specific symbol and timeframe
 func SaveCandleData(candles models.CandleData) error {
-    storageMutex.Lock()
-    defer storageMutex.Unlock()
-
-    fileName := fmt.Sprintf("%s_%s.json", candles.Symbol, candles.Timeframe)
-    filePath := filepath.Join(dataDirectory, candlesDir, fileName)
-
-    data, err := json.MarshalIndent(candles, "", "  ")
-    if err != nil {
-        return err
-    }
-
-    return os.WriteFile(filePath, data, 0644)
+    return currentStorage().SaveCandleData(candles)
 }
 
 // LoadCandleData loads candle data for a specific symbol and timeframe
 func LoadCandleData(symbol, timeframe string) (*models.CandleData, error) {
-    storageMutex.RLock()
-    defer storageMutex.RUnlock()
-
-    fileName := fmt.Sprintf("%s_%s.json", symbol, timeframe)
-    filePath := filepath.Join(dataDirectory, candlesDir, fileName)
-
-    if _, err := os.Stat(filePath); os.IsNotExist(err) {
-        return nil, errors.New("candle data not found")
-    }
-
-    data, err := os.ReadFile(filePath)
-    if err != nil {
-        return nil, err
-    }
-
-    var candleData models.CandleData
-    if err := json.Unmarshal(data, &candleData); err != nil {
-        return nil, err
-    }
+    return currentStorage().LoadCandleData(symbol, timeframe)
+}
 
-    return &candleData, nil
+// LoadCandleRange loads the candles of a symbol and timeframe starting in [from, to)
+func LoadCandleRange(symbol, timeframe string, from, to time.Time) (*models.CandleData, error) {
+    return currentStorage().LoadCandleRange(symbol, timeframe, from, to)
 }
 
 // Drawing Operations
 
 // SaveDrawings saves all drawings for a symbol
 func SaveDrawings(symbol string, drawings []models.DrawingObject) error {
-    storageMutex.Lock()
-    defer storageMutex.Unlock()
-
-    fileName := fmt.Sprintf("%s_drawings.json", symbol)
-    filePath := filepath.Join(dataDirectory, drawingsDir, fileName)
-
-    data, err := json.MarshalIndent(drawings, "", "  ")
-    if err != nil {
-        return err
-    }
-
-    return os.WriteFile(filePath, data, 0644)
+    return currentStorage().SaveDrawings(symbol, drawings)
 }
 
 // LoadDrawings loads all drawings for a symbol
 func LoadDrawings(symbol string) ([]models.DrawingObject, error) {
-    storageMutex.RLock()
-    defer storageMutex.RUnlock()
-
-    fileName := fmt.Sprintf("%s_drawings.json", symbol)
-    filePath := filepath.Join(dataDirectory, drawingsDir, fileName)
-
-    var drawings []models.DrawingObject
-
-    if _, err := os.Stat(filePath); os.IsNotExist(err) {
-        return drawings, nil // Return empty slice if no drawings yet
-    }
-
-    data, err := os.ReadFile(filePath)
-    if err != nil {
-        return nil, err
-    }
-
-    if err := json.Unmarshal(data, &drawings); err != nil {
-        return nil, err
-    }
-
-    return drawings, nil
-}
\ No newline at end of file
+    return currentStorage().LoadDrawings(symbol)
+}
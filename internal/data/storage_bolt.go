@@ -0,0 +1,935 @@
+// File: internal/data/storage_bolt.go
+package data
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Database files and buckets of the bolt backend
+const (
+    boltDataFile  = "moosemarket.db"
+    boltCacheFile = "cache.db"
+)
+
+var (
+    bucketProfiles     = []byte("profiles")     // profile ID -> profile without transactions
+    bucketTrash        = []byte("trash")        // profile ID -> deleted profile, its transactions are kept
+    bucketTransactions = []byte("transactions") // profile ID -> bucket of transactions
+    bucketDrawings     = []byte("drawings")     // symbol -> drawings
+    bucketMeta         = []byte("meta")
+    bucketBackups      = []byte("backups")      // profile ID -> bucket of the versions stored before a migration
+    bucketCandles      = []byte("candles")       // series key -> bucket of candles by time
+    bucketCandleSeries = []byte("candle_series") // series key -> series without candles
+    bucketStocks       = []byte("stocks")
+
+    metaJSONImported = []byte("json_imported")
+    stocksKey        = []byte("all")
+)
+
+// BoltStorage stores data in two embedded bolt databases, one for the user's
+// data and one for cached market data. Transactions are kept apart from their
+// profile, keyed by symbol and time so they can be queried by range and only
+// the changed ones are written. Candles are keyed by time in one bucket per
+// symbol and timeframe.
+type BoltStorage struct {
+    db    *bolt.DB
+    cache *bolt.DB
+}
+
+// storedTransaction is a transaction as kept in the transactions bucket
+type storedTransaction struct {
+    models.TransactionRecord
+    Index int `json:"index"` // order of the transaction within the profile
+}
+
+// profileBackup is a profile as stored before a migration, with the values of
+// its transactions bucket, so the migration can be undone. The values are kept
+// byte for byte rather than as JSON, which marshalling would compact
+type profileBackup struct {
+    Profile      []byte               `json:"profile"`
+    Transactions []storedBackupRecord `json:"transactions,omitempty"`
+}
+
+// storedBackupRecord is a key and value of a transactions bucket
+type storedBackupRecord struct {
+    Key   []byte `json:"key"`
+    Value []byte `json:"value"`
+}
+
+// storedCandleSeries is a candle series without its candles
+type storedCandleSeries struct {
+    Symbol    string    `json:"symbol"`
+    Timeframe string    `json:"timeframe"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OpenBoltStorage opens or creates the databases in the data and cache directories
+func OpenBoltStorage(dataDir, cacheDir string) (*BoltStorage, error) {
+    options := &bolt.Options{Timeout: time.Second}
+
+    // Bolt locks its files, a second instance times out opening them
+    db, err := bolt.Open(filepath.Join(dataDir, boltDataFile), 0600, options)
+    if errors.Is(err, bolt.ErrTimeout) {
+        return nil, fmt.Errorf("the database in %s is in use by another instance of Moose Market", dataDir)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to open database in %s: %w", dataDir, err)
+    }
+    cache, err := bolt.Open(filepath.Join(cacheDir, boltCacheFile), 0600, options)
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to open cache database in %s: %w", cacheDir, err)
+    }
+
+    s := &BoltStorage{db: db, cache: cache}
+    if err := s.createBuckets(); err != nil {
+        s.Close()
+        return nil, err
+    }
+    return s, nil
+}
+
+// createBuckets makes sure the top level buckets exist
+func (s *BoltStorage) createBuckets() error {
+    err := s.db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{bucketProfiles, bucketTrash, bucketTransactions, bucketDrawings, bucketMeta, bucketBackups} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    return s.cache.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{bucketCandles, bucketCandleSeries, bucketStocks} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// Name returns the configuration name of the backend
+func (s *BoltStorage) Name() string {
+    return StorageBolt
+}
+
+// Close closes both databases
+func (s *BoltStorage) Close() error {
+    return errors.Join(s.db.Close(), s.cache.Close())
+}
+
+// Profile Operations
+
+// GetProfiles returns every profile with its transactions
+func (s *BoltStorage) GetProfiles() ([]models.Profile, error) {
+    var ids []string
+    err := s.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketProfiles).ForEach(func(k, v []byte) error {
+            ids = append(ids, string(k))
+            return nil
+        })
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    var profiles []models.Profile
+    for _, id := range ids {
+        profile, err := s.GetProfileByID(id)
+        if err != nil {
+            log.Printf("Skipping profile %s: %v", id, err)
+            continue
+        }
+        profiles = append(profiles, *profile)
+    }
+    return profiles, nil
+}
+
+// GetProfileByID loads a profile and puts its transactions back in their positions
+func (s *BoltStorage) GetProfileByID(id string) (*models.Profile, error) {
+    var stored []byte
+    var transactions []storedTransaction
+    var records []storedBackupRecord
+    err := s.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(bucketProfiles).Get([]byte(id))
+        if value == nil {
+            return fmt.Errorf("profile %s: %w", id, os.ErrNotExist)
+        }
+        stored = append([]byte(nil), value...)
+
+        bucket := tx.Bucket(bucketTransactions).Bucket([]byte(id))
+        if bucket == nil {
+            return nil
+        }
+        return bucket.ForEach(func(k, v []byte) error {
+            var t storedTransaction
+            if err := json.Unmarshal(v, &t); err != nil {
+                return fmt.Errorf("transaction %x of profile %s: %w", k, id, err)
+            }
+            transactions = append(transactions, t)
+            records = append(records, storedBackupRecord{append([]byte(nil), k...), append([]byte(nil), v...)})
+            return nil
+        })
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    sort.SliceStable(transactions, func(i, k int) bool {
+        return transactions[i].Index < transactions[k].Index
+    })
+
+    // Bring older profiles to the current schema. Migrations need the
+    // positions whole, so their transactions are put back in first.
+    content := stored
+    version, err := profileSchemaVersion(stored)
+    if err != nil {
+        return nil, err
+    }
+    reassembled := !isEncryptedProfile(stored) && version < ProfileSchemaVersion
+    if reassembled {
+        if content, err = withTransactions(stored, transactions); err != nil {
+            return nil, err
+        }
+    }
+    profile, migrated, err := decodeStoredProfile(id, content)
+    if err != nil {
+        return nil, err
+    }
+    if profile.Locked || reassembled {
+        transactions = nil
+    }
+
+    // Return the transactions to their positions, in their original order
+    for _, t := range transactions {
+        position := findPosition(profile, t.AccountID, t.StockSymbol)
+        if position == nil {
+            log.Printf("Profile %s has a transaction for a missing position %s/%s", id, t.AccountID, t.StockSymbol)
+            continue
+        }
+        position.Transactions = append(position.Transactions, t.Transaction)
+    }
+
+    if migrated != nil {
+        if err := s.backupProfile(id, stored, records); err != nil {
+            return nil, err
+        }
+        if err := s.SaveProfile(profile); err != nil {
+            return nil, err
+        }
+    }
+    return profile, nil
+}
+
+// withTransactions returns a stored profile with its transactions back in
+// their positions, in the layout of the JSON backend
+func withTransactions(stored []byte, transactions []storedTransaction) ([]byte, error) {
+    var doc profileDocument
+    if err := json.Unmarshal(stored, &doc); err != nil {
+        return nil, err
+    }
+    accounts, _ := doc["accounts"].([]any)
+    for _, t := range transactions {
+        position := documentPosition(accounts, t.AccountID, t.StockSymbol)
+        if position == nil {
+            log.Printf("Profile %v has a transaction for a missing position %s/%s", doc["id"], t.AccountID, t.StockSymbol)
+            continue
+        }
+        list, _ := position["transactions"].([]any)
+        position["transactions"] = append(list, t.Transaction)
+    }
+    return json.Marshal(doc)
+}
+
+// documentPosition returns the position of an account in a symbol of a profile document
+func documentPosition(accounts []any, accountID, symbol string) map[string]any {
+    for _, a := range accounts {
+        account, _ := a.(map[string]any)
+        if id, _ := account["id"].(string); id != accountID {
+            continue
+        }
+        positions, _ := account["positions"].([]any)
+        for _, p := range positions {
+            position, _ := p.(map[string]any)
+            if s, _ := position["stock_symbol"].(string); s == symbol {
+                return position
+            }
+        }
+    }
+    return nil
+}
+
+// backupProfile keeps a profile as stored before a migration in the backups
+// bucket, named after its schema version like the .json.vN files of the JSON
+// backend
+func (s *BoltStorage) backupProfile(id string, stored []byte, records []storedBackupRecord) error {
+    plain, _, err := openProfile(id, stored)
+    if err != nil {
+        return err
+    }
+    version, err := profileSchemaVersion(plain)
+    if err != nil {
+        return err
+    }
+    backup, err := json.Marshal(profileBackup{Profile: stored, Transactions: records})
+    if err != nil {
+        return err
+    }
+
+    err = s.db.Update(func(tx *bolt.Tx) error {
+        bucket, err := tx.Bucket(bucketBackups).CreateBucketIfNotExists([]byte(id))
+        if err != nil {
+            return err
+        }
+        return bucket.Put([]byte(fmt.Sprintf("v%d", version)), backup)
+    })
+    if err != nil {
+        return fmt.Errorf("failed to back up profile %s before migrating: %w", id, err)
+    }
+    log.Printf("Migrated profile %s from schema version %d to %d", id, version, ProfileSchemaVersion)
+    return nil
+}
+
+// findPosition returns the position of an account in a symbol
+func findPosition(profile *models.Profile, accountID, symbol string) *models.Position {
+    for i := range profile.Accounts {
+        if profile.Accounts[i].ID != accountID {
+            continue
+        }
+        for k := range profile.Accounts[i].Positions {
+            if profile.Accounts[i].Positions[k].StockSymbol == symbol {
+                return &profile.Accounts[i].Positions[k]
+            }
+        }
+    }
+    return nil
+}
+
+// SaveProfile stores a profile, writing only the transactions that changed.
+// Encrypted profiles are stored whole, transactions included. It fails with
+// ErrProfileConflict when the stored profile changed since it was loaded.
+func (s *BoltStorage) SaveProfile(profile *models.Profile) error {
+    if err := checkWritable(profile); err != nil {
+        return err
+    }
+
+    return stampProfile(profile, func(loaded time.Time) error {
+        return s.saveProfile(profile, loaded)
+    })
+}
+
+// saveProfile stores a profile loaded from the version last modified at loaded
+func (s *BoltStorage) saveProfile(profile *models.Profile, loaded time.Time) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if err := checkProfileVersion(profile.ID, tx.Bucket(bucketProfiles).Get([]byte(profile.ID)), loaded); err != nil {
+            return err
+        }
+        return putProfile(tx, profile)
+    })
+}
+
+// rekeyProfile stores a profile sealed with next, or in the clear when next
+// is nil, then seals its backups again the same way
+func (s *BoltStorage) rekeyProfile(profile *models.Profile, next *profileKey) error {
+    if err := checkWritable(profile); err != nil {
+        return err
+    }
+
+    return stampProfile(profile, func(loaded time.Time) error {
+        previous := unlockedKey(profile.ID)
+        err := s.db.Update(func(tx *bolt.Tx) error {
+            if err := checkProfileVersion(profile.ID, tx.Bucket(bucketProfiles).Get([]byte(profile.ID)), loaded); err != nil {
+                return err
+            }
+            setUnlockedKey(profile.ID, next)
+            if err := putProfile(tx, profile); err != nil {
+                return err
+            }
+            return resealBoltBackups(tx, profile.ID, previous, next)
+        })
+        if err != nil {
+            setUnlockedKey(profile.ID, previous)
+        }
+        return err
+    })
+}
+
+// putProfile writes a profile in a transaction, sealed with its session key
+// when it has one
+func putProfile(tx *bolt.Tx, profile *models.Profile) error {
+    profile.SchemaVersion = ProfileSchemaVersion
+
+    if unlockedKey(profile.ID) != nil {
+        return putSealedProfile(tx, profile)
+    }
+
+    // Split the transactions from the rest of the profile
+    stripped := *profile
+    stripped.Accounts = make([]models.Account, len(profile.Accounts))
+    records := make(map[string][]byte)
+    for i, account := range profile.Accounts {
+        stripped.Accounts[i] = account
+        stripped.Accounts[i].Positions = make([]models.Position, len(account.Positions))
+        for k, position := range account.Positions {
+            stripped.Accounts[i].Positions[k] = position
+            stripped.Accounts[i].Positions[k].Transactions = nil
+
+            for n, transaction := range position.Transactions {
+                value, err := json.Marshal(storedTransaction{
+                    TransactionRecord: models.TransactionRecord{
+                        AccountID:   account.ID,
+                        StockSymbol: position.StockSymbol,
+                        Transaction: transaction,
+                    },
+                    Index: len(records),
+                })
+                if err != nil {
+                    return err
+                }
+                records[string(transactionKey(position.StockSymbol, transaction.Date, account.ID, transaction.ID, n))] = value
+            }
+        }
+    }
+
+    profileData, err := json.Marshal(stripped)
+    if err != nil {
+        return err
+    }
+    if err := tx.Bucket(bucketProfiles).Put([]byte(profile.ID), profileData); err != nil {
+        return err
+    }
+
+    bucket, err := tx.Bucket(bucketTransactions).CreateBucketIfNotExists([]byte(profile.ID))
+    if err != nil {
+        return err
+    }
+    return syncBucket(bucket, records)
+}
+
+// putSealedProfile stores an encrypted profile in a single value and drops
+// its transactions bucket, which would otherwise leak them in the clear
+func putSealedProfile(tx *bolt.Tx, profile *models.Profile) error {
+    profileData, err := json.Marshal(profile)
+    if err != nil {
+        return err
+    }
+    if profileData, err = sealProfile(profile, profileData); err != nil {
+        return err
+    }
+
+    if err := tx.Bucket(bucketProfiles).Put([]byte(profile.ID), profileData); err != nil {
+        return err
+    }
+    err = tx.Bucket(bucketTransactions).DeleteBucket([]byte(profile.ID))
+    if errors.Is(err, bolt.ErrBucketNotFound) {
+        return nil
+    }
+    return err
+}
+
+// resealBoltBackups seals the pre-migration backups of a profile, sealed with
+// previous, again with next, or leaves them in the clear when next is nil.
+// Backups that can't be sealed again are removed.
+func resealBoltBackups(tx *bolt.Tx, id string, previous, next *profileKey) error {
+    bucket := tx.Bucket(bucketBackups).Bucket([]byte(id))
+    if bucket == nil {
+        return nil
+    }
+
+    resealed := make(map[string][]byte)
+    err := bucket.ForEach(func(k, v []byte) error {
+        var backup profileBackup
+        err := json.Unmarshal(v, &backup)
+        if err == nil {
+            backup.Profile, err = resealProfile(backup.Profile, previous, next)
+        }
+        var value []byte
+        if err == nil {
+            value, err = json.Marshal(backup)
+        }
+        if err != nil {
+            log.Printf("Removing backup %s of profile %s, it can't be sealed with the new passphrase: %v", k, id, err)
+        }
+        resealed[string(k)] = value
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    for k, value := range resealed {
+        if value == nil {
+            err = bucket.Delete([]byte(k))
+        } else {
+            err = bucket.Put([]byte(k), value)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// LoadRawProfile returns a profile as stored, without its transactions unless it is encrypted
+func (s *BoltStorage) LoadRawProfile(id string) ([]byte, error) {
+    var stored []byte
+    err := s.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(bucketProfiles).Get([]byte(id))
+        if value == nil {
+            return fmt.Errorf("profile %s: %w", id, os.ErrNotExist)
+        }
+        stored = append([]byte(nil), value...)
+        return nil
+    })
+    return stored, err
+}
+
+// DeleteProfile moves a profile to the trash bucket, leaving its transactions in place
+func (s *BoltStorage) DeleteProfile(id string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if tx.Bucket(bucketTrash).Get([]byte(id)) != nil {
+            return fmt.Errorf("the trash already holds profile %s: %w", id, ErrProfileExists)
+        }
+        return moveProfile(tx.Bucket(bucketProfiles), tx.Bucket(bucketTrash), id)
+    })
+}
+
+// TrashedProfiles returns the profiles in the trash bucket, without their transactions
+func (s *BoltStorage) TrashedProfiles() ([]models.Profile, error) {
+    var profiles []models.Profile
+    err := s.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketTrash).ForEach(func(k, v []byte) error {
+            profile, _, err := decodeStoredProfile(string(k), v)
+            if err != nil {
+                log.Printf("Skipping trashed profile %s: %v", k, err)
+                return nil
+            }
+            profiles = append(profiles, *profile)
+            return nil
+        })
+    })
+    return profiles, err
+}
+
+// RestoreProfile moves a profile from the trash bucket back with the others
+func (s *BoltStorage) RestoreProfile(id string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if tx.Bucket(bucketProfiles).Get([]byte(id)) != nil {
+            return fmt.Errorf("profile %s: %w", id, ErrProfileExists)
+        }
+        return moveProfile(tx.Bucket(bucketTrash), tx.Bucket(bucketProfiles), id)
+    })
+}
+
+// PurgeProfile removes a profile from the trash bucket along with its transactions and backups
+func (s *BoltStorage) PurgeProfile(id string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if tx.Bucket(bucketTrash).Get([]byte(id)) == nil {
+            return fmt.Errorf("profile %s: %w", id, os.ErrNotExist)
+        }
+        if err := tx.Bucket(bucketTrash).Delete([]byte(id)); err != nil {
+            return err
+        }
+        for _, name := range [][]byte{bucketTransactions, bucketBackups} {
+            if err := tx.Bucket(name).DeleteBucket([]byte(id)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// moveProfile moves a stored profile from one bucket to another
+func moveProfile(from, to *bolt.Bucket, id string) error {
+    value := from.Get([]byte(id))
+    if value == nil {
+        return fmt.Errorf("profile %s: %w", id, os.ErrNotExist)
+    }
+    if err := to.Put([]byte(id), append([]byte(nil), value...)); err != nil {
+        return err
+    }
+    return from.Delete([]byte(id))
+}
+
+// LoadTransactions returns the transactions of a profile in a symbol made in
+// [from, to), oldest first. With a symbol it only reads that symbol's range.
+func (s *BoltStorage) LoadTransactions(profileID, symbol string, from, to time.Time) ([]models.TransactionRecord, error) {
+    // Encrypted profiles keep their transactions inside the profile
+    stored, err := s.LoadRawProfile(profileID)
+    if err != nil {
+        return nil, err
+    }
+    if isEncryptedProfile(stored) {
+        profile, err := s.GetProfileByID(profileID)
+        if err != nil {
+            return nil, err
+        }
+        if profile.Locked {
+            return nil, fmt.Errorf("profile %s: %w", profileID, ErrProfileLocked)
+        }
+        return profileTransactions(profile, symbol, from, to), nil
+    }
+
+    var records []models.TransactionRecord
+    err = s.db.View(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(bucketTransactions).Bucket([]byte(profileID))
+        if bucket == nil {
+            if tx.Bucket(bucketProfiles).Get([]byte(profileID)) == nil {
+                return fmt.Errorf("profile %s: %w", profileID, os.ErrNotExist)
+            }
+            return nil
+        }
+
+        cursor := bucket.Cursor()
+        var k, v []byte
+        var prefix []byte
+        if symbol != "" {
+            prefix = append([]byte(symbol), 0)
+            seek := prefix
+            if !from.IsZero() {
+                seek = append(append([]byte(nil), prefix...), timeKey(from)...)
+            }
+            k, v = cursor.Seek(seek)
+        } else {
+            k, v = cursor.First()
+        }
+
+        for ; k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+            var t storedTransaction
+            if err := json.Unmarshal(v, &t); err != nil {
+                return err
+            }
+            if symbol != "" && !to.IsZero() && !t.Transaction.Date.Before(to) {
+                break
+            }
+            if inTimeRange(t.Transaction.Date, from, to) {
+                records = append(records, t.TransactionRecord)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    sort.SliceStable(records, func(i, k int) bool {
+        return records[i].Transaction.Date.Before(records[k].Transaction.Date)
+    })
+    return records, nil
+}
+
+// transactionKey orders transactions by symbol then time
+func transactionKey(symbol string, date time.Time, accountID, id string, index int) []byte {
+    key := append([]byte(symbol), 0)
+    key = append(key, timeKey(date)...)
+    key = append(key, accountID...)
+    key = append(key, 0)
+    key = append(key, id...)
+    key = append(key, 0)
+    return binary.BigEndian.AppendUint32(key, uint32(index))
+}
+
+// timeKey encodes a time so that byte order is time order
+func timeKey(t time.Time) []byte {
+    return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())^(1<<63))
+}
+
+// syncBucket makes a bucket hold exactly records, writing only what changed
+func syncBucket(bucket *bolt.Bucket, records map[string][]byte) error {
+    var stale [][]byte
+    err := bucket.ForEach(func(k, v []byte) error {
+        if value, ok := records[string(k)]; !ok {
+            stale = append(stale, append([]byte(nil), k...))
+        } else if bytes.Equal(value, v) {
+            delete(records, string(k))
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    for _, k := range stale {
+        if err := bucket.Delete(k); err != nil {
+            return err
+        }
+    }
+    for k, v := range records {
+        if err := bucket.Put([]byte(k), v); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Stock Operations
+
+// SaveStocks saves stock data
+func (s *BoltStorage) SaveStocks(stocks []models.Stock) error {
+    value, err := json.Marshal(stocks)
+    if err != nil {
+        return err
+    }
+    return s.cache.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketStocks).Put(stocksKey, value)
+    })
+}
+
+// LoadStocks loads stock data, empty when none was saved
+func (s *BoltStorage) LoadStocks() ([]models.Stock, error) {
+    var stocks []models.Stock
+    err := s.cache.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(bucketStocks).Get(stocksKey)
+        if value == nil {
+            return nil
+        }
+        return json.Unmarshal(value, &stocks)
+    })
+    return stocks, err
+}
+
+// Candle Data Operations
+
+// candleSeriesKey names the bucket of a symbol and timeframe
+func candleSeriesKey(symbol, timeframe string) []byte {
+    return []byte(symbol + "\x00" + timeframe)
+}
+
+// SaveCandleData replaces the candles of a series, writing only the changed ones
+func (s *BoltStorage) SaveCandleData(candles models.CandleData) error {
+    records := make(map[string][]byte, len(candles.Candles))
+    for _, candle := range candles.Candles {
+        value, err := json.Marshal(candle)
+        if err != nil {
+            return err
+        }
+        records[string(timeKey(candle.Time))] = value
+    }
+
+    series, err := json.Marshal(storedCandleSeries{
+        Symbol:    candles.Symbol,
+        Timeframe: candles.Timeframe,
+        UpdatedAt: candles.UpdatedAt,
+    })
+    if err != nil {
+        return err
+    }
+
+    key := candleSeriesKey(candles.Symbol, candles.Timeframe)
+    return s.cache.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(bucketCandleSeries).Put(key, series); err != nil {
+            return err
+        }
+        bucket, err := tx.Bucket(bucketCandles).CreateBucketIfNotExists(key)
+        if err != nil {
+            return err
+        }
+        return syncBucket(bucket, records)
+    })
+}
+
+// LoadCandleData loads every candle of a series
+func (s *BoltStorage) LoadCandleData(symbol, timeframe string) (*models.CandleData, error) {
+    return s.LoadCandleRange(symbol, timeframe, time.Time{}, time.Time{})
+}
+
+// LoadCandleRange loads the candles of a series starting in [from, to),
+// reading only that range
+func (s *BoltStorage) LoadCandleRange(symbol, timeframe string, from, to time.Time) (*models.CandleData, error) {
+    key := candleSeriesKey(symbol, timeframe)
+    var candleData *models.CandleData
+    err := s.cache.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(bucketCandleSeries).Get(key)
+        bucket := tx.Bucket(bucketCandles).Bucket(key)
+        if value == nil || bucket == nil {
+            return errors.New("candle data not found")
+        }
+
+        var series storedCandleSeries
+        if err := json.Unmarshal(value, &series); err != nil {
+            return err
+        }
+        candleData = &models.CandleData{
+            Symbol:    series.Symbol,
+            Timeframe: series.Timeframe,
+            UpdatedAt: series.UpdatedAt,
+        }
+
+        cursor := bucket.Cursor()
+        k, v := cursor.First()
+        if !from.IsZero() {
+            k, v = cursor.Seek(timeKey(from))
+        }
+        var end []byte
+        if !to.IsZero() {
+            end = timeKey(to)
+        }
+        for ; k != nil && (end == nil || bytes.Compare(k, end) < 0); k, v = cursor.Next() {
+            var candle models.CandleStick
+            if err := json.Unmarshal(v, &candle); err != nil {
+                return err
+            }
+            candleData.Candles = append(candleData.Candles, candle)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return candleData, nil
+}
+
+// Drawing Operations
+
+// SaveDrawings saves all drawings for a symbol
+func (s *BoltStorage) SaveDrawings(symbol string, drawings []models.DrawingObject) error {
+    value, err := json.Marshal(drawings)
+    if err != nil {
+        return err
+    }
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketDrawings).Put([]byte(symbol), value)
+    })
+}
+
+// LoadDrawings loads all drawings for a symbol, empty when there are none
+func (s *BoltStorage) LoadDrawings(symbol string) ([]models.DrawingObject, error) {
+    var drawings []models.DrawingObject
+    err := s.db.View(func(tx *bolt.Tx) error {
+        value := tx.Bucket(bucketDrawings).Get([]byte(symbol))
+        if value == nil {
+            return nil
+        }
+        return json.Unmarshal(value, &drawings)
+    })
+    return drawings, err
+}
+
+// Migration from JSON
+
+// ImportJSON copies everything stored by the JSON backend into the databases.
+// The JSON files are left in place so the JSON backend can still be used.
+func (s *BoltStorage) ImportJSON() error {
+    var source jsonStorage
+
+    profiles, err := source.GetProfiles()
+    if err != nil {
+        return err
+    }
+    for i := range profiles {
+        // Locked profiles can't be re-encoded, copy them as they are
+        if profiles[i].Locked {
+            if err := s.importRawProfile(source, profiles[i].ID); err != nil {
+                return fmt.Errorf("failed to import profile %s: %w", profiles[i].ID, err)
+            }
+            continue
+        }
+        if err := s.SaveProfile(&profiles[i]); err != nil {
+            return fmt.Errorf("failed to import profile %s: %w", profiles[i].ID, err)
+        }
+    }
+
+    // Drawings are stored as <symbol>_drawings.json
+    entries, err := os.ReadDir(filepath.Join(DataDirectory(), drawingsDir))
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    for _, entry := range entries {
+        symbol, ok := strings.CutSuffix(entry.Name(), "_drawings.json")
+        if !ok || entry.IsDir() {
+            continue
+        }
+        drawings, err := source.LoadDrawings(symbol)
+        if err != nil {
+            return fmt.Errorf("failed to import drawings of %s: %w", symbol, err)
+        }
+        if err := s.SaveDrawings(symbol, drawings); err != nil {
+            return err
+        }
+    }
+
+    // Candle files name their series inside, symbols may contain underscores
+    entries, err = os.ReadDir(filepath.Join(CacheDirectory(), candlesDir))
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    for _, entry := range entries {
+        if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+            continue
+        }
+        content, err := os.ReadFile(filepath.Join(CacheDirectory(), candlesDir, entry.Name()))
+        if err != nil {
+            return err
+        }
+        var candles models.CandleData
+        if err := json.Unmarshal(content, &candles); err != nil {
+            log.Printf("Skipping unreadable candle cache %s: %v", entry.Name(), err)
+            continue
+        }
+        if err := s.SaveCandleData(candles); err != nil {
+            return err
+        }
+    }
+
+    stocks, err := source.LoadStocks()
+    if err != nil {
+        log.Printf("Skipping unreadable stock cache: %v", err)
+    } else if len(stocks) > 0 {
+        if err := s.SaveStocks(stocks); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// importRawProfile copies the stored bytes of a profile from another backend
+func (s *BoltStorage) importRawProfile(source Storage, id string) error {
+    content, err := source.LoadRawProfile(id)
+    if err != nil {
+        return err
+    }
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketProfiles).Put([]byte(id), content)
+    })
+}
+
+// importJSONOnce imports the JSON data the first time the databases are used
+func (s *BoltStorage) importJSONOnce() error {
+    imported := false
+    err := s.db.View(func(tx *bolt.Tx) error {
+        imported = tx.Bucket(bucketMeta).Get(metaJSONImported) != nil
+        return nil
+    })
+    if err != nil || imported {
+        return err
+    }
+
+    log.Printf("Importing JSON data into %s", filepath.Join(DataDirectory(), boltDataFile))
+    if err := s.ImportJSON(); err != nil {
+        return err
+    }
+
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketMeta).Put(metaJSONImported, []byte(time.Now().Format(time.RFC3339)))
+    })
+}
@@ -0,0 +1,80 @@
+// File: internal/data/storage_bolt_test.go
+package data
+
+import (
+    "bytes"
+    "encoding/json"
+    "math"
+    "testing"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+func TestBoltMigrationKeepsPreMigrationVersion(t *testing.T) {
+    useTempStorage(t, StorageBolt)
+    store := currentStorage().(*BoltStorage)
+
+    // A version 1 profile as the bolt backend stores it, its transactions apart
+    original := legacyProfile(`{"id": "cash", "name": "Cash", "type": "Non-registered", "balance": 100,
+        "positions": [{"stock_symbol": "XEQT", "quantity": 2, "average_cost": 10, "transactions": null}]}`)
+    bought := models.Transaction{ID: "t1", Type: models.TransactionBuy, Quantity: 2, Price: 10, Date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)}
+    key := transactionKey("XEQT", bought.Date, "cash", bought.ID, 0)
+    record, err := json.Marshal(storedTransaction{
+        TransactionRecord: models.TransactionRecord{AccountID: "cash", StockSymbol: "XEQT", Transaction: bought},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = store.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(bucketProfiles).Put([]byte("profile"), original); err != nil {
+            return err
+        }
+        bucket, err := tx.Bucket(bucketTransactions).CreateBucketIfNotExists([]byte("profile"))
+        if err != nil {
+            return err
+        }
+        return bucket.Put(key, record)
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    profile, err := GetProfileByID("profile")
+    if err != nil {
+        t.Fatalf("GetProfileByID() error = %v", err)
+    }
+    if profile.SchemaVersion != ProfileSchemaVersion {
+        t.Errorf("loaded schema version %d, want %d", profile.SchemaVersion, ProfileSchemaVersion)
+    }
+
+    // The migration saw the stored transactions, so the position needed no opening buy
+    account := profile.Accounts[0]
+    if transactions := account.Positions[0].Transactions; len(transactions) != 1 || transactions[0].ID != "t1" {
+        t.Errorf("position has transactions %+v, want only t1", transactions)
+    }
+    if cash := portfolio.CashBalances(account, time.Time{})[portfolio.DefaultCurrency]; math.Abs(cash-100) > 1e-9 {
+        t.Errorf("migrated account derives %g of cash, want the stored 100", cash)
+    }
+
+    var backup profileBackup
+    err = store.db.View(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(bucketBackups).Bucket([]byte("profile"))
+        if bucket == nil {
+            t.Fatal("no backups of the migrated profile")
+        }
+        return json.Unmarshal(bucket.Get([]byte("v1")), &backup)
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(backup.Profile, original) {
+        t.Error("backup doesn't hold the profile as stored before the migration")
+    }
+    if len(backup.Transactions) != 1 || !bytes.Equal(backup.Transactions[0].Key, key) || !bytes.Equal(backup.Transactions[0].Value, record) {
+        t.Errorf("backup holds transactions %+v, want the stored one", backup.Transactions)
+    }
+}
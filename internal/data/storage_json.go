@@ -0,0 +1,426 @@
+// File: internal/data/storage_json.go
+package data
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Storage paths, relative to dataDirectory or cacheDirectory
+const (
+    profilesDir   = "profiles"
+    stocksFile    = "stocks.json"
+    candlesDir    = "candles"
+    drawingsDir   = "drawings"
+)
+
+// jsonStorage keeps every profile, drawing set and candle series in its own
+// JSON file. Profiles are written atomically with rotating backups.
+type jsonStorage struct{}
+
+// Name returns the configuration name of the backend
+func (jsonStorage) Name() string {
+    return StorageJSON
+}
+
+// Close does nothing, files are closed after every operation
+func (jsonStorage) Close() error {
+    return nil
+}
+
+// Profile Operations
+
+// GetProfiles returns a list of all available profiles, recovering corrupt
+// ones from their backups
+func (jsonStorage) GetProfiles() ([]models.Profile, error) {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    var profiles []models.Profile
+
+    // Read the profiles directory
+    profilesPath := filepath.Join(dataDirectory, profilesDir)
+    files, err := os.ReadDir(profilesPath)
+    if err != nil {
+        return nil, err
+    }
+
+    err = withFileLock(dataDirectory, func() error {
+        for _, file := range files {
+            if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+                continue
+            }
+
+            id := strings.TrimSuffix(file.Name(), ".json")
+            profile, err := loadProfile(id)
+            if err != nil {
+                log.Printf("Skipping profile %s: %v", id, err)
+                continue
+            }
+
+            profiles = append(profiles, *profile)
+        }
+        return nil
+    })
+
+    return profiles, err
+}
+
+// GetProfileByID loads a profile by its ID, recovering it from its backups if it is corrupt
+func (jsonStorage) GetProfileByID(id string) (*models.Profile, error) {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    // Loading may migrate or recover the profile, which writes it
+    var profile *models.Profile
+    err := withFileLock(dataDirectory, func() error {
+        var err error
+        profile, err = loadProfile(id)
+        return err
+    })
+    return profile, err
+}
+
+// SaveProfile saves a profile to disk, keeping the previous version as a
+// backup. It fails with ErrProfileConflict when the file was changed since
+// the profile was loaded.
+func (jsonStorage) SaveProfile(profile *models.Profile) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    if err := checkWritable(profile); err != nil {
+        return err
+    }
+
+    return withFileLock(dataDirectory, func() error {
+        return stampProfile(profile, func(loaded time.Time) error {
+            return saveProfileFile(profile, loaded)
+        })
+    })
+}
+
+// saveProfileFile writes a profile after checking the file still holds the
+// version last modified at loaded. The caller must hold storageMutex for
+// writing and the data directory lock.
+func saveProfileFile(profile *models.Profile, loaded time.Time) error {
+    stored, err := os.ReadFile(profilePath(profile.ID))
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    if err := checkProfileVersion(profile.ID, stored, loaded); err != nil {
+        return err
+    }
+    return writeProfileFile(profile)
+}
+
+// writeProfileFile seals a profile with its session key and writes it, backing
+// up the previous version. The caller must hold storageMutex for writing and
+// the data directory lock.
+func writeProfileFile(profile *models.Profile) error {
+    profile.SchemaVersion = ProfileSchemaVersion
+    profileData, err := json.MarshalIndent(profile, "", "  ")
+    if err != nil {
+        return err
+    }
+    if profileData, err = sealProfile(profile, profileData); err != nil {
+        return err
+    }
+
+    // Encrypting a profile drops its unencrypted backups instead of adding to them
+    dropped := false
+    if unlockedKey(profile.ID) != nil {
+        if dropped, err = dropPlaintextBackups(profile.ID); err != nil {
+            return err
+        }
+    }
+    if !dropped {
+        if err := rotateProfileBackups(profile.ID); err != nil {
+            return fmt.Errorf("failed to back up profile %s: %w", profile.ID, err)
+        }
+    }
+
+    return writeFileAtomic(profilePath(profile.ID), profileData, 0644)
+}
+
+// rekeyProfile saves a profile sealed with next, or in the clear when next is
+// nil, then seals its backups again the same way
+func (jsonStorage) rekeyProfile(profile *models.Profile, next *profileKey) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    if err := checkWritable(profile); err != nil {
+        return err
+    }
+
+    return withFileLock(dataDirectory, func() error {
+        return stampProfile(profile, func(loaded time.Time) error {
+            stored, err := os.ReadFile(profilePath(profile.ID))
+            if err != nil && !errors.Is(err, os.ErrNotExist) {
+                return err
+            }
+            if err := checkProfileVersion(profile.ID, stored, loaded); err != nil {
+                return err
+            }
+
+            previous := unlockedKey(profile.ID)
+            setUnlockedKey(profile.ID, next)
+            if err := writeProfileFile(profile); err != nil {
+                setUnlockedKey(profile.ID, previous)
+                return err
+            }
+            resealProfileBackups(profile.ID, previous, next)
+            return nil
+        })
+    })
+}
+
+// DeleteProfile moves a profile to the trash directory, keeping its backups
+func (jsonStorage) DeleteProfile(id string) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    return withFileLock(dataDirectory, func() error {
+        if _, err := os.Stat(trashedProfilePath(id)); err == nil {
+            return fmt.Errorf("the trash already holds profile %s: %w", id, ErrProfileExists)
+        }
+        if err := os.MkdirAll(filepath.Dir(trashedProfilePath(id)), 0755); err != nil {
+            return err
+        }
+        return os.Rename(profilePath(id), trashedProfilePath(id))
+    })
+}
+
+// TrashedProfiles returns the profiles in the trash directory
+func (jsonStorage) TrashedProfiles() ([]models.Profile, error) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+
+    files, err := os.ReadDir(filepath.Join(dataDirectory, profilesDir, trashDir))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var profiles []models.Profile
+    for _, file := range files {
+        id, ok := strings.CutSuffix(file.Name(), ".json")
+        if file.IsDir() || !ok {
+            continue
+        }
+        content, err := os.ReadFile(trashedProfilePath(id))
+        if err != nil {
+            return nil, err
+        }
+        profile, _, err := decodeStoredProfile(id, content)
+        if err != nil {
+            log.Printf("Skipping trashed profile %s: %v", id, err)
+            continue
+        }
+        profiles = append(profiles, *profile)
+    }
+    return profiles, nil
+}
+
+// RestoreProfile moves a profile from the trash directory back with the others
+func (jsonStorage) RestoreProfile(id string) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    return withFileLock(dataDirectory, func() error {
+        if _, err := os.Stat(profilePath(id)); err == nil {
+            return fmt.Errorf("profile %s: %w", id, ErrProfileExists)
+        }
+        return os.Rename(trashedProfilePath(id), profilePath(id))
+    })
+}
+
+// PurgeProfile removes a profile from the trash directory along with its backups
+func (jsonStorage) PurgeProfile(id string) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    return withFileLock(dataDirectory, func() error {
+        if err := os.Remove(trashedProfilePath(id)); err != nil {
+            return err
+        }
+        return os.RemoveAll(filepath.Join(dataDirectory, profilesDir, backupsDir, id))
+    })
+}
+
+// LoadTransactions scans a profile for the transactions in a symbol made in [from, to)
+func (j jsonStorage) LoadTransactions(profileID, symbol string, from, to time.Time) ([]models.TransactionRecord, error) {
+    profile, err := j.GetProfileByID(profileID)
+    if err != nil {
+        return nil, err
+    }
+    if profile.Locked {
+        return nil, fmt.Errorf("profile %s: %w", profileID, ErrProfileLocked)
+    }
+    return profileTransactions(profile, symbol, from, to), nil
+}
+
+// LoadRawProfile returns the profile file as stored, encrypted or not
+func (jsonStorage) LoadRawProfile(id string) ([]byte, error) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+
+    return os.ReadFile(profilePath(id))
+}
+
+// Stock Operations
+
+// SaveStocks saves stock data to a JSON file
+func (jsonStorage) SaveStocks(stocks []models.Stock) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    bytes, err := json.MarshalIndent(stocks, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    stocksPath := filepath.Join(cacheDirectory, stocksFile)
+    return withFileLock(cacheDirectory, func() error {
+        return writeFileAtomic(stocksPath, bytes, 0644)
+    })
+}
+
+// LoadStocks loads stock data from a JSON file
+func (jsonStorage) LoadStocks() ([]models.Stock, error) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+
+    var stocks []models.Stock
+
+    stocksPath := filepath.Join(cacheDirectory, stocksFile)
+    if _, err := os.Stat(stocksPath); os.IsNotExist(err) {
+        // If no file yet, return an empty slice
+        return stocks, nil
+    }
+
+    bytes, err := os.ReadFile(stocksPath)
+    if err != nil {
+        return stocks, err
+    }
+
+    err = json.Unmarshal(bytes, &stocks)
+    return stocks, err
+}
+
+// Candle Data Operations
+
+// SaveCandleData saves candle data for a specific symbol and timeframe
+func (jsonStorage) SaveCandleData(candles models.CandleData) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    fileName := fmt.Sprintf("%s_%s.json", candles.Symbol, candles.Timeframe)
+    filePath := filepath.Join(cacheDirectory, candlesDir, fileName)
+
+    data, err := json.MarshalIndent(candles, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return withFileLock(cacheDirectory, func() error {
+        return writeFileAtomic(filePath, data, 0644)
+    })
+}
+
+// LoadCandleData loads candle data for a specific symbol and timeframe
+func (jsonStorage) LoadCandleData(symbol, timeframe string) (*models.CandleData, error) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+
+    fileName := fmt.Sprintf("%s_%s.json", symbol, timeframe)
+    filePath := filepath.Join(cacheDirectory, candlesDir, fileName)
+
+    if _, err := os.Stat(filePath); os.IsNotExist(err) {
+        return nil, errors.New("candle data not found")
+    }
+
+    data, err := os.ReadFile(filePath)
+    if err != nil {
+        return nil, err
+    }
+
+    var candleData models.CandleData
+    if err := json.Unmarshal(data, &candleData); err != nil {
+        return nil, err
+    }
+
+    return &candleData, nil
+}
+
+// LoadCandleRange loads a candle series and keeps the candles starting in [from, to)
+func (j jsonStorage) LoadCandleRange(symbol, timeframe string, from, to time.Time) (*models.CandleData, error) {
+    candleData, err := j.LoadCandleData(symbol, timeframe)
+    if err != nil {
+        return nil, err
+    }
+
+    var candles []models.CandleStick
+    for _, candle := range candleData.Candles {
+        if inTimeRange(candle.Time, from, to) {
+            candles = append(candles, candle)
+        }
+    }
+    candleData.Candles = candles
+    return candleData, nil
+}
+
+// Drawing Operations
+
+// SaveDrawings saves all drawings for a symbol
+func (jsonStorage) SaveDrawings(symbol string, drawings []models.DrawingObject) error {
+    storageMutex.Lock()
+    defer storageMutex.Unlock()
+
+    fileName := fmt.Sprintf("%s_drawings.json", symbol)
+    filePath := filepath.Join(dataDirectory, drawingsDir, fileName)
+
+    data, err := json.MarshalIndent(drawings, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return withFileLock(dataDirectory, func() error {
+        return writeFileAtomic(filePath, data, 0644)
+    })
+}
+
+// LoadDrawings loads all drawings for a symbol
+func (jsonStorage) LoadDrawings(symbol string) ([]models.DrawingObject, error) {
+    storageMutex.RLock()
+    defer storageMutex.RUnlock()
+
+    fileName := fmt.Sprintf("%s_drawings.json", symbol)
+    filePath := filepath.Join(dataDirectory, drawingsDir, fileName)
+
+    var drawings []models.DrawingObject
+
+    if _, err := os.Stat(filePath); os.IsNotExist(err) {
+        return drawings, nil // Return empty slice if no drawings yet
+    }
+
+    data, err := os.ReadFile(filePath)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(data, &drawings); err != nil {
+        return nil, err
+    }
+
+    return drawings, nil
+}
\ No newline at end of file
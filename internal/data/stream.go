@@ -0,0 +1,279 @@
+// File: internal/data/stream.go
+package data
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+
+    "golang.org/x/net/websocket"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Streaming protocol. The client sends a subscribe message with the full set
+// of symbols it wants, replacing any earlier set. The server answers with a
+// snapshot quote for every symbol and then streams trade and quote ticks.
+const (
+    streamActionSubscribe = "subscribe"
+    streamTypeSnapshot    = "snapshot"
+    streamTypeTrade       = "trade"
+    streamTypeQuote       = "quote"
+
+    streamReconnectDelay    = time.Second
+    streamMaxReconnectDelay = 30 * time.Second
+)
+
+// streamRequest is a message from a streaming client
+type streamRequest struct {
+    Action  string   `json:"action"`
+    Symbols []string `json:"symbols"`
+}
+
+// streamMessage is a message from the streaming server
+type streamMessage struct {
+    models.Tick
+    Quote *models.Stock `json:"quote,omitempty"` // set on snapshots
+}
+
+// QuoteStream is a WebSocket client for streamed quotes. It keeps the latest
+// quote of every subscribed symbol up to date with each tick and reconnects
+// when the connection drops.
+type QuoteStream struct {
+    url string
+
+    mu      sync.Mutex
+    symbols []string
+    quotes  map[string]*models.Stock
+    conn    *websocket.Conn
+    cancel  context.CancelFunc
+}
+
+// NewQuoteStream creates a stream client for the server at url (ws:// or wss://)
+func NewQuoteStream(url string) *QuoteStream {
+    return &QuoteStream{
+        url:    url,
+        quotes: make(map[string]*models.Stock),
+    }
+}
+
+// SetSymbols replaces the streamed symbols
+func (s *QuoteStream) SetSymbols(symbols []string) {
+    s.mu.Lock()
+    s.symbols = append([]string(nil), symbols...)
+    conn := s.conn
+    s.mu.Unlock()
+
+    if conn != nil {
+        if err := websocket.JSON.Send(conn, streamRequest{Action: streamActionSubscribe, Symbols: symbols}); err != nil {
+            log.Printf("Error updating stream subscription: %v", err)
+        }
+    }
+}
+
+// Start connects in the background and calls onTick for every trade or quote
+// tick with the updated quote of its symbol, until Stop is called.
+// onTick is called from the stream goroutine.
+func (s *QuoteStream) Start(onTick func(tick models.Tick, quote *models.Stock)) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.cancel != nil {
+        return
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    s.cancel = cancel
+    go s.run(ctx, onTick)
+}
+
+// Stop closes the connection and ends the stream
+func (s *QuoteStream) Stop() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.cancel != nil {
+        s.cancel()
+        s.cancel = nil
+    }
+    if s.conn != nil {
+        s.conn.Close()
+    }
+}
+
+// run keeps a connection open, waiting longer after each failed attempt
+func (s *QuoteStream) run(ctx context.Context, onTick func(models.Tick, *models.Stock)) {
+    delay := streamReconnectDelay
+    for {
+        err := s.receive(ctx, onTick)
+        if ctx.Err() != nil {
+            return
+        }
+        if err != nil {
+            log.Printf("Quote stream disconnected: %v", err)
+        } else {
+            // The connection worked for a while, start over with short waits
+            delay = streamReconnectDelay
+        }
+
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-timer.C:
+        }
+
+        delay *= 2
+        if delay > streamMaxReconnectDelay {
+            delay = streamMaxReconnectDelay
+        }
+    }
+}
+
+// receive connects, subscribes and handles messages until the connection fails.
+// It returns nil when at least one message was received.
+func (s *QuoteStream) receive(ctx context.Context, onTick func(models.Tick, *models.Stock)) error {
+    config, err := websocket.NewConfig(s.url, "http://localhost/")
+    if err != nil {
+        return err
+    }
+    conn, err := config.DialContext(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    s.mu.Lock()
+    s.conn = conn
+    symbols := s.symbols
+    s.mu.Unlock()
+
+    defer func() {
+        s.mu.Lock()
+        if s.conn == conn {
+            s.conn = nil
+        }
+        s.mu.Unlock()
+    }()
+
+    // Close the connection to unblock the receive when the stream stops
+    go func() {
+        <-ctx.Done()
+        conn.Close()
+    }()
+
+    if err := websocket.JSON.Send(conn, streamRequest{Action: streamActionSubscribe, Symbols: symbols}); err != nil {
+        return err
+    }
+
+    received := false
+    for {
+        var message streamMessage
+        if err := websocket.JSON.Receive(conn, &message); err != nil {
+            if received {
+                return nil
+            }
+            return err
+        }
+        received = true
+
+        if quote := s.apply(message); quote != nil && message.Type != streamTypeSnapshot {
+            onTick(message.Tick, quote)
+        }
+    }
+}
+
+// apply records a message and returns the updated quote of its symbol, or nil
+// when no quote is known for it yet
+func (s *QuoteStream) apply(message streamMessage) *models.Stock {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if message.Type == streamTypeSnapshot {
+        if message.Quote == nil {
+            return nil
+        }
+        quote := *message.Quote
+        s.quotes[message.Symbol] = &quote
+        return &quote
+    }
+
+    current, ok := s.quotes[message.Symbol]
+    if !ok {
+        return nil
+    }
+
+    // Replace rather than modify, earlier quotes may still be displayed
+    quote := ApplyTick(*current, message.Tick)
+    s.quotes[message.Symbol] = &quote
+    return &quote
+}
+
+// ApplyTick returns the quote updated with a trade tick. Quote ticks leave it
+// unchanged, the bid and ask are not part of models.Stock.
+func ApplyTick(quote models.Stock, tick models.Tick) models.Stock {
+    if tick.Type != streamTypeTrade || tick.Price <= 0 {
+        return quote
+    }
+
+    prevClose := quote.Price - quote.Change
+    if quote.Price > 0 {
+        // Keep the market cap in line with the price
+        quote.MarketCap *= tick.Price / quote.Price
+    }
+    quote.Price = tick.Price
+    quote.Change = tick.Price - prevClose
+    if prevClose != 0 {
+        quote.ChangePercent = quote.Change / prevClose * 100
+    }
+    if tick.Price > quote.High {
+        quote.High = tick.Price
+    }
+    if quote.Low == 0 || tick.Price < quote.Low {
+        quote.Low = tick.Price
+    }
+    quote.Volume += tick.Size
+    quote.Timestamp = tick.Time.Unix()
+    return quote
+}
+
+// ApplyTickToCandles folds a trade tick into the last candle, or starts a new
+// candle when the tick falls after it. New candles are only started for
+// intraday and daily timeframes, weekly and monthly buckets need a reload.
+// It reports whether the candles changed.
+func ApplyTickToCandles(data *models.CandleData, tick models.Tick) bool {
+    if tick.Type != streamTypeTrade || tick.Price <= 0 || len(data.Candles) == 0 {
+        return false
+    }
+
+    last := &data.Candles[len(data.Candles)-1]
+    if tick.Time.Before(last.Time) {
+        return false
+    }
+
+    interval := TimeframeInterval(data.Timeframe)
+    if tick.Time.Before(last.Time.Add(interval)) || interval > 24*time.Hour {
+        // The tick belongs to the last candle
+        last.Close = tick.Price
+        if tick.Price > last.High {
+            last.High = tick.Price
+        }
+        if tick.Price < last.Low {
+            last.Low = tick.Price
+        }
+        last.Volume += tick.Size
+        return true
+    }
+
+    data.Candles = append(data.Candles, models.CandleStick{
+        Time:   tick.Time.Truncate(interval),
+        Open:   tick.Price,
+        High:   tick.Price,
+        Low:    tick.Price,
+        Close:  tick.Price,
+        Volume: tick.Size,
+    })
+    return true
+}
@@ -0,0 +1,151 @@
+// File: internal/data/stream_server.go
+package data
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "sync"
+    "time"
+
+    "golang.org/x/net/websocket"
+)
+
+// DefaultStreamTickInterval is the time between ticks of the stand-in server
+const DefaultStreamTickInterval = time.Second
+
+// StreamServer is a local WebSocket server replaying the market simulator
+// with the streaming protocol, so streaming works without a real feed
+type StreamServer struct {
+    simulator    *MarketSimulator
+    tickInterval time.Duration
+
+    mu       sync.Mutex
+    listener net.Listener
+    server   *http.Server
+}
+
+// NewStreamServer creates a server streaming ticks from simulator every tickInterval
+func NewStreamServer(simulator *MarketSimulator, tickInterval time.Duration) *StreamServer {
+    if tickInterval <= 0 {
+        tickInterval = DefaultStreamTickInterval
+    }
+
+    return &StreamServer{
+        simulator:    simulator,
+        tickInterval: tickInterval,
+    }
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0" for any free port, and serves in
+// the background. It returns the WebSocket URL clients should connect to.
+func (s *StreamServer) Start(addr string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.server != nil {
+        return "", fmt.Errorf("stream server already started")
+    }
+
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return "", err
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/stream", websocket.Handler(s.serve))
+    s.listener = listener
+    s.server = &http.Server{Handler: mux}
+
+    go func(server *http.Server) {
+        if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+            log.Printf("Stream server stopped: %v", err)
+        }
+    }(s.server)
+
+    return "ws://" + listener.Addr().String() + "/stream", nil
+}
+
+// Stop closes the server and every open connection
+func (s *StreamServer) Stop() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.server != nil {
+        s.server.Close()
+        s.server = nil
+    }
+}
+
+// serve streams ticks to one client until it disconnects
+func (s *StreamServer) serve(conn *websocket.Conn) {
+    defer conn.Close()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    var mu sync.Mutex
+    var symbols []string
+
+    // Read subscriptions in the background, the connection ends with the reader
+    go func() {
+        defer cancel()
+        for {
+            var request streamRequest
+            if err := websocket.JSON.Receive(conn, &request); err != nil {
+                return
+            }
+            if request.Action != streamActionSubscribe {
+                continue
+            }
+
+            mu.Lock()
+            symbols = append([]string(nil), request.Symbols...)
+            mu.Unlock()
+
+            // Start every symbol from a full quote
+            for _, symbol := range request.Symbols {
+                quote, err := s.simulator.Quote(symbol)
+                if err != nil {
+                    continue
+                }
+                message := streamMessage{Quote: quote}
+                message.Type = streamTypeSnapshot
+                message.Symbol = symbol
+                message.Time = time.Unix(quote.Timestamp, 0)
+                if err := websocket.JSON.Send(conn, message); err != nil {
+                    return
+                }
+            }
+        }
+    }()
+
+    ticker := time.NewTicker(s.tickInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        mu.Lock()
+        current := symbols
+        mu.Unlock()
+
+        for _, symbol := range current {
+            trade, quote, err := s.simulator.Trade(symbol)
+            if err != nil {
+                continue
+            }
+            for _, tick := range []streamMessage{{Tick: *trade}, {Tick: *quote}} {
+                if err := websocket.JSON.Send(conn, tick); err != nil {
+                    return
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,841 @@
+// File: internal/data/sync.go
+package data
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "maps"
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// Sync settings
+const (
+    // SyncFormat is the version of the operation files written to the sync
+    // folder. Version 2 carries the cash movements and contribution settings
+    // of profile schema version 2, which older versions would drop.
+    SyncFormat = 2
+
+    // syncStateDir holds this machine's sync settings and what it merged of
+    // each profile, under the data directory
+    syncStateDir   = "sync"
+    syncConfigFile = "config.json"
+
+    // syncRecordKind names the operation files sealed for encrypted profiles
+    syncRecordKind = "sync"
+
+    // syncWatchDelay groups the files written by one save or copied in by a
+    // file sync tool into a single merge
+    syncWatchDelay = 500 * time.Millisecond
+
+    // syncPollInterval merges the sync folder even without file events,
+    // which network shares don't always deliver
+    syncPollInterval = time.Minute
+)
+
+// SyncOperation is a set of changes made to a profile on one machine, written
+// as a file in the profile's directory of the sync folder
+type SyncOperation struct {
+    Format   int            `json:"format"`
+    Device   string         `json:"device"`
+    Seq      int            `json:"seq"`     // numbers the operations of a device from 1
+    Clock    map[string]int `json:"clock"`   // operations of each device merged before this one
+    Lamport  int            `json:"lamport"` // orders operations the same way on every machine
+    Actor    string         `json:"actor"`
+    Time     time.Time      `json:"time"`
+    Changes  []AuditChange  `json:"changes"`
+    Resolves []string       `json:"resolves,omitempty"` // conflicts settled by this operation
+}
+
+// ID identifies an operation, it is also its file name
+func (op *SyncOperation) ID() string {
+    return fmt.Sprintf("%s-%08d", op.Device, op.Seq)
+}
+
+// SyncResult reports what syncing a profile did
+type SyncResult struct {
+    ProfileID    string
+    Published    bool           // local changes were written to the sync folder
+    Merged       bool           // changes from other machines were saved to the profile
+    Conflicts    []SyncConflict // conflicts waiting to be resolved
+    NewConflicts int            // conflicts found by this sync
+    Err          error          // why the profile can't be synced, reported once until it changes
+}
+
+// syncConfig holds the sync settings of this machine
+type syncConfig struct {
+    DeviceID string `json:"device_id"`
+    Folder   string `json:"folder"`
+}
+
+// syncState is what this machine merged of a profile
+type syncState struct {
+    Seen     map[string]int `json:"seen"`               // each device's operations merged, up to this number
+    Resolved []string       `json:"resolved,omitempty"` // conflicts resolved here and not published yet
+}
+
+// SyncEngine merges the profiles of this machine with the operations other
+// machines write to a shared folder
+type SyncEngine struct {
+    folder   string
+    device   string
+    onSynced func(SyncResult)
+
+    syncMutex sync.Mutex // one sync at a time
+    conflicts map[string][]SyncConflict
+
+    pendingMutex sync.Mutex
+    pending      map[string]bool
+    wake         chan struct{}
+
+    failuresMutex sync.Mutex
+    failures      map[string]string // last error reported for each profile
+
+    watcher *fsnotify.Watcher
+    done    chan struct{}
+}
+
+var (
+    // activeSync is the running sync engine, told about every saved profile
+    activeSync      *SyncEngine
+    activeSyncMutex sync.Mutex
+)
+
+// syncStateDirectory returns the directory of this machine's sync state
+func syncStateDirectory() string {
+    return filepath.Join(DataDirectory(), syncStateDir)
+}
+
+// loadSyncConfig reads the sync settings, giving this machine an ID the first time
+func loadSyncConfig() (syncConfig, error) {
+    var cfg syncConfig
+    content, err := os.ReadFile(filepath.Join(syncStateDirectory(), syncConfigFile))
+    if err == nil {
+        err = json.Unmarshal(content, &cfg)
+    }
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return cfg, err
+    }
+    if cfg.DeviceID != "" {
+        return cfg, nil
+    }
+
+    id := make([]byte, 8)
+    if _, err := rand.Read(id); err != nil {
+        return cfg, err
+    }
+    cfg.DeviceID = hex.EncodeToString(id)
+    return cfg, saveSyncConfig(cfg)
+}
+
+// saveSyncConfig writes the sync settings
+func saveSyncConfig(cfg syncConfig) error {
+    content, err := json.MarshalIndent(cfg, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(syncStateDirectory(), 0755); err != nil {
+        return err
+    }
+    return writeFileAtomic(filepath.Join(syncStateDirectory(), syncConfigFile), content, 0644)
+}
+
+// SyncFolder returns the shared folder profiles are synced through on this
+// machine, empty when sync is off
+func SyncFolder() string {
+    cfg, err := loadSyncConfig()
+    if err != nil {
+        log.Printf("Failed to read the sync settings: %v", err)
+    }
+    return cfg.Folder
+}
+
+// SetSyncFolder changes the shared folder profiles are synced through, empty
+// turning sync off. It applies to the sync engines started afterwards.
+func SetSyncFolder(folder string) error {
+    cfg, err := loadSyncConfig()
+    if err != nil {
+        return err
+    }
+    cfg.Folder = folder
+    return saveSyncConfig(cfg)
+}
+
+// loadSyncState reads what was merged of a profile. It reports whether the
+// profile was synced before.
+func loadSyncState(id string) (*syncState, bool, error) {
+    state := &syncState{Seen: make(map[string]int)}
+    content, err := os.ReadFile(filepath.Join(syncStateDirectory(), id+".json"))
+    if errors.Is(err, os.ErrNotExist) {
+        return state, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    if err := json.Unmarshal(content, state); err != nil {
+        return nil, false, fmt.Errorf("invalid sync state of profile %s: %w", id, err)
+    }
+    if state.Seen == nil {
+        state.Seen = make(map[string]int)
+    }
+    return state, true, nil
+}
+
+// saveSyncState writes what was merged of a profile
+func saveSyncState(id string, state *syncState) error {
+    content, err := json.MarshalIndent(state, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(filepath.Join(syncStateDirectory(), id+".json"), content, 0644)
+}
+
+// writeSyncOperation writes an operation to the sync folder, sealed when the
+// profile is encrypted
+func writeSyncOperation(folder, profileID string, op *SyncOperation) error {
+    plain, err := json.Marshal(op)
+    if err != nil {
+        return err
+    }
+    content, err := sealRecord(profileID, syncRecordKind, plain)
+    if err != nil {
+        return err
+    }
+
+    dir := filepath.Join(folder, profileID)
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    return writeFileAtomic(filepath.Join(dir, op.ID()+".json"), content, 0644)
+}
+
+// readSyncOperations reads the operations of a profile from the sync folder.
+// Operations sealed with another passphrase or written by a newer version
+// fail the read, since the operations after them would wait for them forever.
+// Files that aren't operations, or not whole yet, are skipped.
+func readSyncOperations(folder, profileID string) ([]*SyncOperation, error) {
+    entries, err := os.ReadDir(filepath.Join(folder, profileID))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var ops []*SyncOperation
+    seen := make(map[string]bool)
+    for _, entry := range entries {
+        name := entry.Name()
+        if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+            continue
+        }
+
+        content, err := os.ReadFile(filepath.Join(folder, profileID, name))
+        if err != nil {
+            return nil, err
+        }
+        plain, err := openRecord(profileID, syncRecordKind, content)
+        if errors.Is(err, ErrProfileLocked) {
+            // Locked profiles aren't synced, so this one isn't encrypted here
+            return nil, fmt.Errorf("sync operation %s of profile %s is encrypted, give the profile the passphrase it has on the other machines", name, profileID)
+        }
+        if errors.Is(err, ErrWrongPassphrase) {
+            return nil, fmt.Errorf("sync operation %s of profile %s is sealed with another passphrase than the profile's here: %w", name, profileID, err)
+        }
+        var op SyncOperation
+        if err == nil {
+            err = json.Unmarshal(plain, &op)
+        }
+        if err == nil && op.Format > SyncFormat {
+            return nil, fmt.Errorf("sync operation %s of profile %s has format %d, this version reads up to %d, update Moose Market to sync it",
+                name, profileID, op.Format, SyncFormat)
+        }
+        if err == nil && (op.Device == "" || op.Seq < 1) {
+            err = errors.New("not a sync operation")
+        }
+        if err != nil {
+            log.Printf("Skipping sync operation %s of profile %s: %v", name, profileID, err)
+            continue
+        }
+
+        if !seen[op.ID()] {
+            seen[op.ID()] = true
+            ops = append(ops, &op)
+        }
+    }
+    return ops, nil
+}
+
+// pauseSync waits for the running sync engine to finish its current sync and
+// holds it until resume is called. It returns the folder profiles are synced
+// through, the configured one when no engine is running.
+func pauseSync() (folder string, resume func()) {
+    activeSyncMutex.Lock()
+    e := activeSync
+    activeSyncMutex.Unlock()
+
+    if e == nil {
+        return SyncFolder(), func() {}
+    }
+    e.syncMutex.Lock()
+    return e.folder, e.syncMutex.Unlock
+}
+
+// openSyncRecords returns the content of the operation files of a profile,
+// opened with its current key, by path. Files that can't be opened are left
+// out, they fail the next sync the same way they would have.
+func openSyncRecords(folder, profileID string) (map[string][]byte, error) {
+    if folder == "" {
+        return nil, nil
+    }
+    dir := filepath.Join(folder, profileID)
+    entries, err := os.ReadDir(dir)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    records := make(map[string][]byte)
+    for _, entry := range entries {
+        name := entry.Name()
+        if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+            continue
+        }
+        path := filepath.Join(dir, name)
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return nil, err
+        }
+        plain, err := openRecord(profileID, syncRecordKind, content)
+        if err != nil {
+            log.Printf("Not sealing sync operation %s of profile %s again: %v", name, profileID, err)
+            continue
+        }
+        records[path] = plain
+    }
+    return records, nil
+}
+
+// sealSyncRecords writes operation files read by openSyncRecords back,
+// sealed with the current key of the profile
+func sealSyncRecords(profileID string, records map[string][]byte) error {
+    for path, plain := range records {
+        content, err := sealRecord(profileID, syncRecordKind, plain)
+        if err != nil {
+            return err
+        }
+        if err := writeFileAtomic(path, content, 0644); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// advanceSeen marks as merged the operations following those already merged
+// from each device. A device's operations are only merged in sequence, those
+// after a missing one wait for it to arrive.
+func advanceSeen(ops []*SyncOperation, seen map[string]int) {
+    available := make(map[string]bool)
+    for _, op := range ops {
+        available[op.ID()] = true
+    }
+    for _, op := range ops {
+        for available[(&SyncOperation{Device: op.Device, Seq: seen[op.Device] + 1}).ID()] {
+            seen[op.Device]++
+        }
+    }
+}
+
+// mergedOperations returns the operations marked as merged, in the order
+// every machine applies them
+func mergedOperations(ops []*SyncOperation, seen map[string]int) []*SyncOperation {
+    var merged []*SyncOperation
+    for _, op := range ops {
+        if op.Seq <= seen[op.Device] {
+            merged = append(merged, op)
+        }
+    }
+    slices.SortFunc(merged, func(a, b *SyncOperation) int {
+        if a.Lamport != b.Lamport {
+            return a.Lamport - b.Lamport
+        }
+        if c := strings.Compare(a.Device, b.Device); c != 0 {
+            return c
+        }
+        return a.Seq - b.Seq
+    })
+    return merged
+}
+
+// StartSync starts syncing profiles through a shared folder. It merges every
+// profile right away, then whenever files change in the folder or profiles
+// are saved. onSynced is called, from another goroutine, after a sync that
+// changed something.
+func StartSync(folder string, onSynced func(SyncResult)) (*SyncEngine, error) {
+    cfg, err := loadSyncConfig()
+    if err != nil {
+        return nil, err
+    }
+    if err := os.MkdirAll(folder, 0755); err != nil {
+        return nil, fmt.Errorf("can't use %s as sync folder: %w", folder, err)
+    }
+
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        return nil, err
+    }
+    if err := watcher.Add(folder); err != nil {
+        watcher.Close()
+        return nil, err
+    }
+    entries, err := os.ReadDir(folder)
+    if err != nil {
+        watcher.Close()
+        return nil, err
+    }
+    for _, entry := range entries {
+        if entry.IsDir() {
+            watcher.Add(filepath.Join(folder, entry.Name()))
+        }
+    }
+
+    e := &SyncEngine{
+        folder:    folder,
+        device:    cfg.DeviceID,
+        onSynced:  onSynced,
+        conflicts: make(map[string][]SyncConflict),
+        pending:   make(map[string]bool),
+        failures:  make(map[string]string),
+        wake:      make(chan struct{}, 1),
+        watcher:   watcher,
+        done:      make(chan struct{}),
+    }
+
+    activeSyncMutex.Lock()
+    activeSync = e
+    activeSyncMutex.Unlock()
+
+    go e.run()
+    go e.syncAll()
+    return e, nil
+}
+
+// Stop ends syncing. Changes saved afterwards are published by the next engine.
+func (e *SyncEngine) Stop() {
+    activeSyncMutex.Lock()
+    if activeSync == e {
+        activeSync = nil
+    }
+    activeSyncMutex.Unlock()
+
+    e.watcher.Close()
+    <-e.done
+}
+
+// Folder returns the shared folder the engine syncs through
+func (e *SyncEngine) Folder() string {
+    return e.folder
+}
+
+// notifySync tells the running sync engine a profile was saved
+func notifySync(id string) {
+    activeSyncMutex.Lock()
+    e := activeSync
+    activeSyncMutex.Unlock()
+
+    if e != nil {
+        e.schedule(id)
+    }
+}
+
+// schedule queues a profile to be synced after syncWatchDelay
+func (e *SyncEngine) schedule(id string) {
+    e.pendingMutex.Lock()
+    e.pending[id] = true
+    e.pendingMutex.Unlock()
+
+    select {
+    case e.wake <- struct{}{}:
+    default:
+    }
+}
+
+// run syncs the profiles queued by saves and by changes in the sync folder
+func (e *SyncEngine) run() {
+    defer close(e.done)
+
+    var flush <-chan time.Time
+    poll := time.NewTicker(syncPollInterval)
+    defer poll.Stop()
+
+    for {
+        select {
+        case event, ok := <-e.watcher.Events:
+            if !ok {
+                return
+            }
+            rel, err := filepath.Rel(e.folder, event.Name)
+            if err != nil || strings.HasPrefix(filepath.Base(event.Name), ".") {
+                continue
+            }
+            id, _, nested := strings.Cut(rel, string(filepath.Separator))
+            if !nested && event.Has(fsnotify.Create) {
+                // A new profile directory
+                if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+                    e.watcher.Add(event.Name)
+                }
+            }
+            e.schedule(id)
+
+        case <-e.wake:
+            flush = time.After(syncWatchDelay)
+
+        case <-flush:
+            e.pendingMutex.Lock()
+            ids := slices.Collect(maps.Keys(e.pending))
+            clear(e.pending)
+            e.pendingMutex.Unlock()
+
+            for _, id := range ids {
+                e.syncAndReport(id)
+            }
+            flush = nil
+
+        case <-poll.C:
+            go e.syncAll()
+
+        case err, ok := <-e.watcher.Errors:
+            if !ok {
+                return
+            }
+            log.Printf("Sync folder watcher: %v", err)
+        }
+    }
+}
+
+// syncAll syncs the profiles of this machine and those found in the sync folder
+func (e *SyncEngine) syncAll() {
+    ids := make(map[string]bool)
+    if profiles, err := GetProfiles(); err == nil {
+        for _, profile := range profiles {
+            ids[profile.ID] = true
+        }
+    } else {
+        log.Printf("Sync: failed to list profiles: %v", err)
+    }
+    if entries, err := os.ReadDir(e.folder); err == nil {
+        for _, entry := range entries {
+            if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+                ids[entry.Name()] = true
+            }
+        }
+    } else {
+        log.Printf("Sync: failed to read %s: %v", e.folder, err)
+    }
+
+    for _, id := range slices.Sorted(maps.Keys(ids)) {
+        e.syncAndReport(id)
+    }
+}
+
+// syncAndReport syncs a profile and tells onSynced when something changed or
+// when it fails differently than the last time. Locked profiles wait until
+// they are unlocked.
+func (e *SyncEngine) syncAndReport(id string) {
+    result, err := e.SyncProfile(id)
+    if errors.Is(err, ErrProfileLocked) {
+        return
+    }
+    reported := e.newFailure(id, err)
+    if err != nil {
+        log.Printf("Failed to sync profile %s: %v", id, err)
+        if reported && e.onSynced != nil {
+            e.onSynced(SyncResult{ProfileID: id, Err: err})
+        }
+        return
+    }
+    if e.onSynced != nil && (result.Published || result.Merged || result.NewConflicts > 0) {
+        e.onSynced(result)
+    }
+}
+
+// newFailure records how syncing a profile last failed, nil when it
+// succeeded. It reports whether err is a failure not reported yet.
+func (e *SyncEngine) newFailure(id string, err error) bool {
+    e.failuresMutex.Lock()
+    defer e.failuresMutex.Unlock()
+
+    if err == nil {
+        delete(e.failures, id)
+        return false
+    }
+    if e.failures[id] == err.Error() {
+        return false
+    }
+    e.failures[id] = err.Error()
+    return true
+}
+
+// SyncProfile publishes the changes made to a profile on this machine since
+// it was last synced, then merges the operations of the other machines into it
+func (e *SyncEngine) SyncProfile(id string) (SyncResult, error) {
+    if err := checkBundleSymbol(id); err != nil {
+        return SyncResult{}, fmt.Errorf("invalid profile directory in the sync folder: %w", err)
+    }
+
+    e.syncMutex.Lock()
+    defer e.syncMutex.Unlock()
+
+    if err := os.MkdirAll(syncStateDirectory(), 0755); err != nil {
+        return SyncResult{}, err
+    }
+    result := SyncResult{ProfileID: id}
+    err := withFileLock(syncStateDirectory(), func() error {
+        return e.syncProfile(id, &result)
+    })
+    return result, err
+}
+
+// syncProfile does the work of SyncProfile. The caller must hold syncMutex
+// and the lock of the sync state directory.
+func (e *SyncEngine) syncProfile(id string, result *SyncResult) error {
+    state, synced, err := loadSyncState(id)
+    if err != nil {
+        return err
+    }
+    local, err := GetProfileByID(id)
+    exists := err == nil
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    if exists && local.Locked {
+        return fmt.Errorf("profile %s: %w", id, ErrProfileLocked)
+    }
+    // A profile deleted here isn't brought back by the other machines
+    if !exists && synced {
+        return nil
+    }
+
+    ops, err := readSyncOperations(e.folder, id)
+    if err != nil {
+        return err
+    }
+
+    // A profile synced for the first time starts from what the other
+    // machines have, what differs here is published as a change
+    if !synced {
+        advanceSeen(ops, state.Seen)
+    }
+
+    // Publish what changed here since the profile was last merged
+    if exists {
+        merged := mergedOperations(ops, state.Seen)
+        base, _ := replaySyncOperations(merged, nil)
+        var before []byte
+        if base != nil {
+            if before, err = json.Marshal(base); err != nil {
+                return err
+            }
+        }
+        current, err := json.Marshal(local)
+        if err != nil {
+            return err
+        }
+        changes, err := diffProfiles(before, current)
+        if err != nil {
+            return err
+        }
+
+        if len(changes) > 0 || len(state.Resolved) > 0 {
+            op := &SyncOperation{
+                Format:   SyncFormat,
+                Device:   e.device,
+                Seq:      state.Seen[e.device] + 1,
+                Clock:    maps.Clone(state.Seen),
+                Lamport:  1,
+                Actor:    auditActor(),
+                Time:     time.Now(),
+                Changes:  changes,
+                Resolves: state.Resolved,
+            }
+            for _, m := range merged {
+                op.Lamport = max(op.Lamport, m.Lamport+1)
+            }
+            if err := writeSyncOperation(e.folder, id, op); err != nil {
+                return err
+            }
+            ops = append(ops, op)
+            state.Seen[e.device] = op.Seq
+            state.Resolved = nil
+            result.Published = true
+        }
+    }
+
+    // Merge every operation that arrived in sequence
+    advanceSeen(ops, state.Seen)
+    merged := mergedOperations(ops, state.Seen)
+    final, conflicts := replaySyncOperations(merged, resolvedConflicts(merged))
+
+    if final != nil {
+        content, err := json.Marshal(final)
+        if err != nil {
+            return err
+        }
+        var current []byte
+        if exists {
+            if current, err = json.Marshal(local); err != nil {
+                return err
+            }
+        }
+        changes, err := diffProfiles(current, content)
+        if err != nil {
+            return err
+        }
+
+        if len(changes) > 0 {
+            profile, err := decodeProfile(content)
+            if err != nil {
+                return err
+            }
+            profile.ID = id
+            profile.SchemaVersion = ProfileSchemaVersion
+            if exists {
+                profile.LastModified = local.LastModified
+                profile.Encrypted = local.Encrypted
+            }
+            if err := SaveProfile(profile); err != nil {
+                return err
+            }
+            result.Merged = true
+        }
+    }
+
+    if err := saveSyncState(id, state); err != nil {
+        return err
+    }
+
+    for _, conflict := range conflicts {
+        if !slices.ContainsFunc(e.conflicts[id], func(c SyncConflict) bool { return c.ID == conflict.ID }) {
+            result.NewConflicts++
+        }
+    }
+    e.conflicts[id] = conflicts
+    result.Conflicts = conflicts
+    return nil
+}
+
+// resolvedConflicts returns the IDs of the conflicts settled by operations
+func resolvedConflicts(ops []*SyncOperation) map[string]bool {
+    resolved := make(map[string]bool)
+    for _, op := range ops {
+        for _, id := range op.Resolves {
+            resolved[id] = true
+        }
+    }
+    return resolved
+}
+
+// Conflicts returns the conflicts of a profile found by its last sync
+func (e *SyncEngine) Conflicts(profileID string) []SyncConflict {
+    e.syncMutex.Lock()
+    defer e.syncMutex.Unlock()
+    return slices.Clone(e.conflicts[profileID])
+}
+
+// ResolveConflict settles a conflict by keeping one of its versions, then
+// publishes the choice so the other machines settle it the same way
+func (e *SyncEngine) ResolveConflict(profileID, conflictID string, keep int) error {
+    e.syncMutex.Lock()
+    defer e.syncMutex.Unlock()
+
+    i := slices.IndexFunc(e.conflicts[profileID], func(c SyncConflict) bool { return c.ID == conflictID })
+    if i < 0 {
+        return errors.New("the conflict was already resolved")
+    }
+    conflict := e.conflicts[profileID][i]
+    if keep < 0 || keep >= len(conflict.Versions) {
+        return fmt.Errorf("the conflict has no version %d", keep)
+    }
+
+    return withFileLock(syncStateDirectory(), func() error {
+        local, err := GetProfileByID(profileID)
+        if err != nil {
+            return err
+        }
+        if local.Locked {
+            return fmt.Errorf("profile %s: %w", profileID, ErrProfileLocked)
+        }
+
+        profile, err := keepConflictVersion(local, conflict, conflict.Versions[keep])
+        if err != nil {
+            return err
+        }
+        if err := SaveProfile(profile); err != nil {
+            return err
+        }
+
+        state, _, err := loadSyncState(profileID)
+        if err != nil {
+            return err
+        }
+        state.Resolved = append(state.Resolved, conflictID)
+        if err := saveSyncState(profileID, state); err != nil {
+            return err
+        }
+
+        result := SyncResult{ProfileID: profileID}
+        return e.syncProfile(profileID, &result)
+    })
+}
+
+// keepConflictVersion returns a copy of a profile where the transaction or
+// watchlist of a conflict is replaced by one of its versions
+func keepConflictVersion(local *models.Profile, conflict SyncConflict, version SyncVersion) (*models.Profile, error) {
+    content, err := json.Marshal(local)
+    if err != nil {
+        return nil, err
+    }
+    var state any
+    if err := json.Unmarshal(content, &state); err != nil {
+        return nil, err
+    }
+
+    scope := syncScope{kind: conflict.Kind, key: conflict.Key}
+    state = removeScope(state, nil, scope)
+    if version.Path != nil {
+        parent := version.Path[:len(version.Path)-1]
+        list, ok := nodeAt(state, parent)
+        if _, isList := list.([]any); !ok || !isList {
+            return nil, fmt.Errorf("can't keep this version, %s no longer exists", strings.Join(parent, "."))
+        }
+        change := AuditChange{Op: AuditAdd, Path: version.Path, Index: len(list.([]any)), New: version.Value}
+        if state, err = applyAuditChange(state, version.Path, change); err != nil {
+            return nil, err
+        }
+    }
+
+    if content, err = json.Marshal(state); err != nil {
+        return nil, err
+    }
+    profile, err := decodeProfile(content)
+    if err != nil {
+        return nil, err
+    }
+    profile.LastModified = local.LastModified
+    profile.Encrypted = local.Encrypted
+    return profile, nil
+}
@@ -0,0 +1,256 @@
+// File: internal/data/sync_merge.go
+package data
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "log"
+    "time"
+)
+
+// SyncConflict is a transaction or watchlist changed on two machines without
+// either having seen the other's change. Every machine keeps the version that
+// comes last in the order of operations until someone picks one.
+type SyncConflict struct {
+    ID       string
+    Kind     string // "transaction" or "watchlist"
+    Key      string // ID of the transaction or watchlist
+    Versions []SyncVersion
+}
+
+// SyncVersion is a transaction or watchlist as one machine left it
+type SyncVersion struct {
+    Actor  string
+    Device string
+    Time   time.Time
+    Path   []string        // where the element is in the profile, nil when it was removed
+    Value  json.RawMessage // the element, null when it was removed
+}
+
+// Kinds of elements whose concurrent changes are conflicts
+const (
+    scopeTransaction = "transaction"
+    scopeWatchlist   = "watchlist"
+)
+
+// syncScope identifies a transaction or watchlist
+type syncScope struct {
+    kind, key string
+}
+
+// scopedVersion is the version an operation left of an element
+type scopedVersion struct {
+    op      *SyncOperation
+    version SyncVersion
+}
+
+// replaySyncOperations applies operations in order to an empty profile. It
+// returns the decoded profile, nil when there were no operations, and the
+// conflicts between them that weren't resolved. Changes that no longer apply
+// are skipped.
+func replaySyncOperations(ops []*SyncOperation, resolved map[string]bool) (any, []SyncConflict) {
+    var state any
+    var conflicts []SyncConflict
+    versions := make(map[syncScope][]scopedVersion)
+
+    for _, op := range ops {
+        for _, change := range op.Changes {
+            next, err := applyAuditChange(state, change.Path, change)
+            if err != nil {
+                log.Printf("Skipping a change of sync operation %s: %v", op.ID(), err)
+                continue
+            }
+            state = next
+        }
+
+        for _, scope := range touchedScopes(op.Changes) {
+            path, value := findScope(state, nil, scope)
+            current := SyncVersion{Actor: op.Actor, Device: op.Device, Time: op.Time, Path: path, Value: value}
+
+            for _, previous := range versions[scope] {
+                if !concurrentOperations(previous.op, op) || bytes.Equal(previous.version.Value, current.Value) {
+                    continue
+                }
+                id := syncConflictID(previous.op, op, scope)
+                if !resolved[id] {
+                    conflicts = append(conflicts, SyncConflict{
+                        ID:       id,
+                        Kind:     scope.kind,
+                        Key:      scope.key,
+                        Versions: []SyncVersion{previous.version, current},
+                    })
+                }
+            }
+            versions[scope] = append(versions[scope], scopedVersion{op: op, version: current})
+        }
+    }
+    return state, conflicts
+}
+
+// concurrentOperations reports whether two operations were made on different
+// machines without either having merged the other
+func concurrentOperations(a, b *SyncOperation) bool {
+    return a.Device != b.Device && b.Clock[a.Device] < a.Seq && a.Clock[b.Device] < b.Seq
+}
+
+// syncConflictID identifies a conflict the same way on every machine
+func syncConflictID(a, b *SyncOperation, scope syncScope) string {
+    sum := sha256.Sum256([]byte(a.ID() + "/" + b.ID() + "/" + scope.kind + "/" + scope.key))
+    return hex.EncodeToString(sum[:8])
+}
+
+// scopeOfPath returns the transaction or watchlist a path leads into
+func scopeOfPath(path []string) (syncScope, bool) {
+    if len(path) >= 2 && path[0] == "watchlists" {
+        return syncScope{scopeWatchlist, path[1]}, true
+    }
+    for i := 1; i+1 < len(path); i++ {
+        if path[i] == "transactions" {
+            return syncScope{scopeTransaction, path[i+1]}, true
+        }
+    }
+    return syncScope{}, false
+}
+
+// touchedScopes returns the transactions and watchlists changes touch,
+// including those inside accounts, positions or lists added or removed whole
+func touchedScopes(changes []AuditChange) []syncScope {
+    var scopes []syncScope
+    seen := make(map[syncScope]bool)
+    add := func(scope syncScope) {
+        if !seen[scope] {
+            seen[scope] = true
+            scopes = append(scopes, scope)
+        }
+    }
+
+    for _, change := range changes {
+        if scope, ok := scopeOfPath(change.Path); ok {
+            add(scope)
+            continue
+        }
+        for _, raw := range []json.RawMessage{change.Old, change.New} {
+            var value any
+            if len(raw) > 0 && json.Unmarshal(raw, &value) == nil {
+                walkScopes(change.Path, value, add)
+            }
+        }
+    }
+    return scopes
+}
+
+// walkScopes calls fn with the transactions and watchlists found in a value
+// at path
+func walkScopes(path []string, value any, fn func(syncScope)) {
+    if scope, ok := scopeOfPath(path); ok {
+        fn(scope)
+        return
+    }
+    switch v := value.(type) {
+    case map[string]any:
+        for key, child := range v {
+            walkScopes(appendPath(path, key), child, fn)
+        }
+    case []any:
+        if !isKeyedList(v) {
+            return
+        }
+        for _, element := range v {
+            key, _ := elementKey(element)
+            walkScopes(appendPath(path, key), element, fn)
+        }
+    }
+}
+
+// findScope returns the path and encoding of the first element of a scope
+// found under path, and a nil path with null when there is none
+func findScope(node any, path []string, scope syncScope) ([]string, json.RawMessage) {
+    if s, ok := scopeOfPath(path); ok {
+        if s != scope {
+            return nil, json.RawMessage("null")
+        }
+        value, err := json.Marshal(node)
+        if err != nil {
+            return nil, json.RawMessage("null")
+        }
+        return path, value
+    }
+
+    switch n := node.(type) {
+    case map[string]any:
+        for key, child := range n {
+            if found, value := findScope(child, appendPath(path, key), scope); found != nil {
+                return found, value
+            }
+        }
+    case []any:
+        if !isKeyedList(n) {
+            break
+        }
+        for _, element := range n {
+            key, _ := elementKey(element)
+            if found, value := findScope(element, appendPath(path, key), scope); found != nil {
+                return found, value
+            }
+        }
+    }
+    return nil, json.RawMessage("null")
+}
+
+// removeScope removes every element of a scope found under path
+func removeScope(node any, path []string, scope syncScope) any {
+    switch n := node.(type) {
+    case map[string]any:
+        for key, child := range n {
+            n[key] = removeScope(child, appendPath(path, key), scope)
+        }
+    case []any:
+        if !isKeyedList(n) {
+            return n
+        }
+        kept := n[:0]
+        for _, element := range n {
+            key, _ := elementKey(element)
+            elementPath := appendPath(path, key)
+            if s, ok := scopeOfPath(elementPath); ok {
+                if s != scope {
+                    kept = append(kept, element)
+                }
+                continue
+            }
+            kept = append(kept, removeScope(element, elementPath, scope))
+        }
+        return kept
+    }
+    return node
+}
+
+// nodeAt returns the value a path leads to
+func nodeAt(node any, path []string) (any, bool) {
+    for _, key := range path {
+        switch n := node.(type) {
+        case map[string]any:
+            child, ok := n[key]
+            if !ok {
+                return nil, false
+            }
+            node = child
+        case []any:
+            found := false
+            for _, element := range n {
+                if k, ok := elementKey(element); ok && k == key {
+                    node, found = element, true
+                    break
+                }
+            }
+            if !found {
+                return nil, false
+            }
+        default:
+            return nil, false
+        }
+    }
+    return node, true
+}
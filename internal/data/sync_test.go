@@ -0,0 +1,291 @@
+// File: internal/data/sync_test.go
+package data
+
+import (
+    "errors"
+    "path/filepath"
+    "slices"
+    "strings"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// syncMachine is a machine syncing through a shared folder, with its own data directory
+type syncMachine struct {
+    dataDir string
+    engine  *SyncEngine
+}
+
+// newSyncMachines returns machines named after devices, syncing through the
+// same folder on the local filesystem. The first one is in use.
+func newSyncMachines(t *testing.T, devices ...string) []*syncMachine {
+    t.Helper()
+    useTempStorage(t, StorageJSON)
+    folder := t.TempDir()
+
+    var machines []*syncMachine
+    for _, device := range devices {
+        m := &syncMachine{
+            dataDir: t.TempDir(),
+            engine: &SyncEngine{
+                folder:    folder,
+                device:    device,
+                conflicts: make(map[string][]SyncConflict),
+                failures:  make(map[string]string),
+            },
+        }
+        m.use()
+        if err := initializeDirectories(); err != nil {
+            t.Fatal(err)
+        }
+        if err := SetSyncFolder(folder); err != nil {
+            t.Fatal(err)
+        }
+        machines = append(machines, m)
+    }
+    machines[0].use()
+    return machines
+}
+
+// use makes the machine's data directory the current one
+func (m *syncMachine) use() {
+    storageMutex.Lock()
+    dataDirectory = m.dataDir
+    storageMutex.Unlock()
+}
+
+// sync syncs a profile on the machine, which is left in use
+func (m *syncMachine) sync(t *testing.T, id string) SyncResult {
+    t.Helper()
+    m.use()
+    result, err := m.engine.SyncProfile(id)
+    if err != nil {
+        t.Fatalf("SyncProfile() on %s error = %v", m.engine.device, err)
+    }
+    return result
+}
+
+// edit changes a profile on the machine and saves it
+func (m *syncMachine) edit(t *testing.T, id string, change func(*models.Profile)) {
+    t.Helper()
+    m.use()
+    profile, err := GetProfileByID(id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    change(profile)
+    if err := SaveProfile(profile); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// profile returns a profile as the machine has it
+func (m *syncMachine) profile(t *testing.T, id string) *models.Profile {
+    t.Helper()
+    m.use()
+    profile, err := GetProfileByID(id)
+    if err != nil {
+        t.Fatalf("profile %s on %s: %v", id, m.engine.device, err)
+    }
+    return profile
+}
+
+func TestConcurrentOperations(t *testing.T) {
+    tests := []struct {
+        name string
+        a, b SyncOperation
+        want bool
+    }{
+        {"same device", SyncOperation{Device: "a", Seq: 1}, SyncOperation{Device: "a", Seq: 2, Clock: map[string]int{}}, false},
+        {"neither merged the other", SyncOperation{Device: "a", Seq: 1}, SyncOperation{Device: "b", Seq: 1}, true},
+        {"b merged a", SyncOperation{Device: "a", Seq: 1}, SyncOperation{Device: "b", Seq: 1, Clock: map[string]int{"a": 1}}, false},
+        {"a merged b", SyncOperation{Device: "a", Seq: 2, Clock: map[string]int{"b": 1}}, SyncOperation{Device: "b", Seq: 1}, false},
+        {"b merged an earlier a", SyncOperation{Device: "a", Seq: 2}, SyncOperation{Device: "b", Seq: 1, Clock: map[string]int{"a": 1}}, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := concurrentOperations(&tt.a, &tt.b); got != tt.want {
+                t.Errorf("concurrentOperations() = %v, want %v", got, tt.want)
+            }
+            if got := concurrentOperations(&tt.b, &tt.a); got != tt.want {
+                t.Errorf("concurrentOperations() reversed = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSyncMerge(t *testing.T) {
+    machines := newSyncMachines(t, "a", "b")
+    a, b := machines[0], machines[1]
+
+    profile, err := CreateProfile("Shared")
+    if err != nil {
+        t.Fatal(err)
+    }
+    a.edit(t, profile.ID, func(p *models.Profile) { p.Watchlists[0].Symbols = []string{"XEQT"} })
+    if result := a.sync(t, profile.ID); !result.Published || result.Merged {
+        t.Errorf("first sync on a = %+v, want the profile published", result)
+    }
+    if result := b.sync(t, profile.ID); !result.Merged {
+        t.Errorf("first sync on b = %+v, want the profile merged", result)
+    }
+    if got, want := summarize(b.profile(t, profile.ID)), summarize(a.profile(t, profile.ID)); !got.equal(want) {
+        t.Fatalf("profile on b = %+v, want %+v", got, want)
+    }
+
+    // Changes to different parts of the profile on both machines are merged
+    b.edit(t, profile.ID, func(p *models.Profile) { p.Name = "Renamed" })
+    b.sync(t, profile.ID)
+    a.edit(t, profile.ID, func(p *models.Profile) {
+        if err := portfolio.AddAccount(p, "cash", "Cash", models.AccountNonRegistered); err != nil {
+            t.Fatal(err)
+        }
+    })
+    if result := a.sync(t, profile.ID); !result.Published || !result.Merged || len(result.Conflicts) != 0 {
+        t.Errorf("sync on a = %+v, want its change published and b's merged", result)
+    }
+    b.sync(t, profile.ID)
+
+    want := profileSummary{name: "Renamed", accounts: []string{"cash"}, symbols: []string{"XEQT"}}
+    for _, m := range machines {
+        if got := summarize(m.profile(t, profile.ID)); !got.equal(want) {
+            t.Errorf("profile on %s = %+v, want %+v", m.engine.device, got, want)
+        }
+    }
+}
+
+func TestSyncConflicts(t *testing.T) {
+    machines := newSyncMachines(t, "a", "b")
+    a, b := machines[0], machines[1]
+
+    profile, err := CreateProfile("Shared")
+    if err != nil {
+        t.Fatal(err)
+    }
+    a.edit(t, profile.ID, func(p *models.Profile) {
+        if err := portfolio.AddAccount(p, "cash", "Cash", models.AccountNonRegistered); err != nil {
+            t.Fatal(err)
+        }
+        deposit := models.Transaction{ID: "t1", Type: models.TransactionDeposit, Amount: 100}
+        if err := portfolio.AddCashTransaction(p, "cash", deposit); err != nil {
+            t.Fatal(err)
+        }
+    })
+    a.sync(t, profile.ID)
+    b.sync(t, profile.ID)
+    watchlistID := profile.Watchlists[0].ID
+
+    // Both machines change the same transaction and watchlist before syncing
+    a.edit(t, profile.ID, func(p *models.Profile) {
+        p.Accounts[0].Transactions[0].Notes = "from a"
+        p.Watchlists[0].Symbols = []string{"XEQT"}
+    })
+    b.edit(t, profile.ID, func(p *models.Profile) {
+        p.Accounts[0].Transactions[0].Notes = "from b"
+        p.Watchlists[0].Symbols = []string{"VFV"}
+    })
+    a.sync(t, profile.ID)
+    result := b.sync(t, profile.ID)
+    if result.NewConflicts != 2 {
+        t.Fatalf("sync on b found %d new conflicts, want 2: %+v", result.NewConflicts, result.Conflicts)
+    }
+    a.sync(t, profile.ID)
+
+    for _, m := range machines {
+        conflicts := m.engine.Conflicts(profile.ID)
+        var scopes []string
+        for _, c := range conflicts {
+            scopes = append(scopes, c.Kind+" "+c.Key)
+        }
+        slices.Sort(scopes)
+        if want := []string{scopeTransaction + " t1", scopeWatchlist + " " + watchlistID}; !slices.Equal(scopes, want) {
+            t.Errorf("conflicts on %s = %v, want %v", m.engine.device, scopes, want)
+        }
+    }
+    if got, want := summarize(a.profile(t, profile.ID)), summarize(b.profile(t, profile.ID)); !got.equal(want) {
+        t.Errorf("machines disagree until the conflicts are resolved: a = %+v, b = %+v", got, want)
+    }
+
+    // Keeping a's versions on a settles the conflicts on both machines
+    a.use()
+    for _, conflict := range a.engine.Conflicts(profile.ID) {
+        keep := slices.IndexFunc(conflict.Versions, func(v SyncVersion) bool { return v.Device == "a" })
+        if err := a.engine.ResolveConflict(profile.ID, conflict.ID, keep); err != nil {
+            t.Fatalf("ResolveConflict() error = %v", err)
+        }
+    }
+    if err := a.engine.ResolveConflict(profile.ID, "missing", 0); err == nil {
+        t.Error("ResolveConflict() of an unknown conflict succeeded")
+    }
+    b.sync(t, profile.ID)
+
+    for _, m := range machines {
+        if conflicts := m.engine.Conflicts(profile.ID); len(conflicts) != 0 {
+            t.Errorf("conflicts on %s after resolving = %+v, want none", m.engine.device, conflicts)
+        }
+        p := m.profile(t, profile.ID)
+        if notes := p.Accounts[0].Transactions[0].Notes; notes != "from a" {
+            t.Errorf("transaction on %s has notes %q, want the kept version", m.engine.device, notes)
+        }
+        if symbols := p.Watchlists[0].Symbols; !slices.Equal(symbols, []string{"XEQT"}) {
+            t.Errorf("watchlist on %s = %v, want the kept version", m.engine.device, symbols)
+        }
+    }
+}
+
+func TestSyncPassphraseChange(t *testing.T) {
+    machines := newSyncMachines(t, "a")
+    a := machines[0]
+    profile := newEncryptedProfile(t, "first")
+    saveNames(t, profile, "v1")
+    a.sync(t, profile.ID)
+    ops, err := filepath.Glob(filepath.Join(a.engine.folder, profile.ID, "*.json"))
+    if err != nil || len(ops) == 0 {
+        t.Fatalf("operations = %v, %v; want some", ops, err)
+    }
+
+    // The operations are sealed again with the new passphrase
+    if err := ChangeProfilePassphrase(profile.ID, "first", "second"); err != nil {
+        t.Fatalf("ChangeProfilePassphrase() error = %v", err)
+    }
+    read, err := readSyncOperations(a.engine.folder, profile.ID)
+    if err != nil || len(read) != len(ops) {
+        t.Fatalf("readSyncOperations() = %d operations, %v; want %d", len(read), err, len(ops))
+    }
+    if result := a.sync(t, profile.ID); result.Merged || len(result.Conflicts) != 0 {
+        t.Errorf("sync after the change = %+v, want nothing new", result)
+    }
+
+    // Operations sealed with another passphrase fail the sync instead of being skipped
+    other, err := newProfileKey("other")
+    if err != nil {
+        t.Fatal(err)
+    }
+    setUnlockedKey(profile.ID, other)
+    if _, err := readSyncOperations(a.engine.folder, profile.ID); !errors.Is(err, ErrWrongPassphrase) {
+        t.Errorf("readSyncOperations() with another passphrase error = %v, want ErrWrongPassphrase", err)
+    }
+
+    // And so do encrypted operations of a profile that isn't encrypted here
+    LockProfile(profile.ID)
+    _, err = readSyncOperations(a.engine.folder, profile.ID)
+    if err == nil || errors.Is(err, ErrProfileLocked) || !strings.Contains(err.Error(), "passphrase") {
+        t.Errorf("readSyncOperations() without the key error = %v, want a request for the passphrase", err)
+    }
+}
+
+func TestSyncReportsFailuresOnce(t *testing.T) {
+    e := &SyncEngine{failures: make(map[string]string)}
+    failure := errors.New("sealed with another passphrase")
+    if !e.newFailure("p", failure) {
+        t.Error("newFailure() of a first failure = false")
+    }
+    if e.newFailure("p", failure) {
+        t.Error("newFailure() of the same failure again = true")
+    }
+    if e.newFailure("p", nil) || !e.newFailure("p", failure) {
+        t.Error("a failure after a successful sync wasn't reported again")
+    }
+}
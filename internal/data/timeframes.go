@@ -0,0 +1,37 @@
+// File: internal/data/timeframes.go
+package data
+
+import "time"
+
+// Timeframes lists the candle timeframes offered by the chart, shortest first
+var Timeframes = []string{"5min", "15min", "30min", "60min", "1d", "1w", "1mo"}
+
+// TimeframeInterval returns the duration covered by one candle of a timeframe.
+// Both the chart names (5min, 60min) and the short names (5m, 1h) are accepted.
+func TimeframeInterval(timeframe string) time.Duration {
+    switch timeframe {
+    case "1min", "1m":
+        return time.Minute
+    case "5min", "5m":
+        return 5 * time.Minute
+    case "15min", "15m":
+        return 15 * time.Minute
+    case "30min", "30m":
+        return 30 * time.Minute
+    case "60min", "1h":
+        return time.Hour
+    case "1d":
+        return 24 * time.Hour
+    case "1w":
+        return 7 * 24 * time.Hour
+    case "1mo":
+        return 30 * 24 * time.Hour
+    default:
+        return time.Hour
+    }
+}
+
+// IsIntradayTimeframe reports whether candles of a timeframe are shorter than a day
+func IsIntradayTimeframe(timeframe string) bool {
+    return TimeframeInterval(timeframe) < 24*time.Hour
+}
@@ -0,0 +1,83 @@
+// File: internal/data/watcher.go
+package data
+
+import (
+    "log"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// profileWatchDelay groups the events of one save, which writes a temp file
+// and renames it, into a single change
+const profileWatchDelay = 250 * time.Millisecond
+
+// profileWatcher is implemented by storage backends that can report profiles
+// changed by other processes
+type profileWatcher interface {
+    WatchProfiles(onChange func(id string)) (stop func(), err error)
+}
+
+// WatchProfiles calls onChange, from another goroutine, with the ID of every
+// profile changed in storage, including changes made by this process. Backends
+// that can't be shared between processes never call it. stop ends the watch.
+func WatchProfiles(onChange func(id string)) (stop func(), err error) {
+    watcher, ok := currentStorage().(profileWatcher)
+    if !ok {
+        return func() {}, nil
+    }
+    return watcher.WatchProfiles(onChange)
+}
+
+// WatchProfiles watches the profiles directory for files written, replaced,
+// removed or moved away
+func (jsonStorage) WatchProfiles(onChange func(id string)) (func(), error) {
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        return nil, err
+    }
+    if err := watcher.Add(filepath.Join(DataDirectory(), profilesDir)); err != nil {
+        watcher.Close()
+        return nil, err
+    }
+
+    go func() {
+        pending := make(map[string]bool)
+        var flush <-chan time.Time
+
+        for {
+            select {
+            case event, ok := <-watcher.Events:
+                if !ok {
+                    return
+                }
+                // Temp files are hidden, backups live in a subdirectory. A
+                // profile moved to the trash is renamed away.
+                name := filepath.Base(event.Name)
+                id, ok := strings.CutSuffix(name, ".json")
+                if !ok || strings.HasPrefix(name, ".") || !event.Has(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) {
+                    continue
+                }
+                pending[id] = true
+                flush = time.After(profileWatchDelay)
+
+            case <-flush:
+                for id := range pending {
+                    onChange(id)
+                }
+                clear(pending)
+                flush = nil
+
+            case err, ok := <-watcher.Errors:
+                if !ok {
+                    return
+                }
+                log.Printf("Profile watcher: %v", err)
+            }
+        }
+    }()
+
+    return func() { watcher.Close() }, nil
+}
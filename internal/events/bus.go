@@ -0,0 +1,70 @@
+// File: internal/events/bus.go
+package events
+
+import (
+    "reflect"
+    "sync"
+)
+
+// Bus is a typed publish/subscribe bus connecting the UI components.
+// Handlers are called on the publishing goroutine without any lock held, so
+// they may publish or subscribe themselves, and publishing is safe from both
+// data goroutines and Fyne's main thread.
+type Bus struct {
+    mu       sync.RWMutex
+    handlers map[reflect.Type][]handler
+    nextID   int
+}
+
+// handler is a subscription to one event type
+type handler struct {
+    id int
+    fn func(any)
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+    return &Bus{
+        handlers: make(map[reflect.Type][]handler),
+    }
+}
+
+// Subscribe registers fn for every published event of type T and returns a
+// function that removes the subscription
+func Subscribe[T any](b *Bus, fn func(T)) func() {
+    eventType := reflect.TypeFor[T]()
+
+    b.mu.Lock()
+    b.nextID++
+    id := b.nextID
+    b.handlers[eventType] = append(b.handlers[eventType], handler{
+        id: id,
+        fn: func(event any) { fn(event.(T)) },
+    })
+    b.mu.Unlock()
+
+    return func() {
+        b.mu.Lock()
+        defer b.mu.Unlock()
+
+        handlers := b.handlers[eventType]
+        for i, h := range handlers {
+            if h.id == id {
+                b.handlers[eventType] = append(handlers[:i:i], handlers[i+1:]...)
+                break
+            }
+        }
+    }
+}
+
+// Publish delivers an event to the subscribers of its type, in subscription order
+func (b *Bus) Publish(event any) {
+    // Copy the handlers so they run without the lock
+    b.mu.RLock()
+    handlers := append([]handler(nil), b.handlers[reflect.TypeOf(event)]...)
+    b.mu.RUnlock()
+
+    for _, h := range handlers {
+        h.fn(event)
+    }
+}
@@ -0,0 +1,47 @@
+// File: internal/events/events.go
+package events
+
+import "github.com/frederikblais/Moose-Market/internal/models"
+
+// QuoteUpdated is published when fresh quotes arrive
+type QuoteUpdated struct {
+    Quotes map[string]*models.Stock
+}
+
+// SymbolSelected is published when the user picks a stock to chart
+type SymbolSelected struct {
+    Symbol string
+}
+
+// AddToWatchlistRequested is published when the user asks to watch a stock
+type AddToWatchlistRequested struct {
+    Symbol string
+}
+
+// WatchlistChanged is published when the displayed watchlist or its symbols change
+type WatchlistChanged struct {
+    Watchlist models.Watchlist
+}
+
+// ProfileSwitched is published when another profile becomes active
+type ProfileSwitched struct {
+    Profile *models.Profile
+}
+
+// ProfileReloaded is published when the active profile was changed on disk,
+// by another instance of the app, and has been loaded again
+type ProfileReloaded struct {
+    Profile *models.Profile
+}
+
+// SettingsChanged is published when the active profile's settings are saved
+type SettingsChanged struct {
+    Settings models.Settings
+}
+
+// TickReceived is published for every streamed trade or quote tick, with the
+// quote of its symbol after the tick
+type TickReceived struct {
+    Tick  models.Tick
+    Quote *models.Stock
+}
@@ -0,0 +1,130 @@
+// File: internal/history/commands.go
+package history
+
+import (
+    "encoding/json"
+    "errors"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// profileEdit changes a profile and saves it. It keeps the profile as it was
+// before and after the change so it can switch between both.
+type profileEdit struct {
+    name      string
+    profileID string
+    edit      func(profile *models.Profile)
+    before    []byte
+    after     []byte
+}
+
+// EditProfile returns a command applying edit to the active profile and
+// saving it. Undoing it restores the profile as it was before edit.
+func EditProfile(name string, edit func(profile *models.Profile)) Command {
+    return &profileEdit{name: name, edit: edit}
+}
+
+// Name describes the change
+func (c *profileEdit) Name() string {
+    return c.name
+}
+
+// Do applies the edit the first time, then restores the edited profile
+func (c *profileEdit) Do() error {
+    if c.after != nil {
+        return restoreProfile(c.profileID, c.after)
+    }
+
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return errors.New("no active profile")
+    }
+    before, err := json.Marshal(profile)
+    if err != nil {
+        return err
+    }
+
+    c.edit(profile)
+    if err := data.SaveProfile(profile); err != nil {
+        applySnapshot(profile, before)
+        return err
+    }
+
+    c.profileID = profile.ID
+    c.before = before
+    c.after, err = json.Marshal(profile)
+    return err
+}
+
+// Undo restores the profile as it was before the edit
+func (c *profileEdit) Undo() error {
+    return restoreProfile(c.profileID, c.before)
+}
+
+// restoreProfile puts a profile back in the state of a snapshot and saves it.
+// The active profile is changed in place so the components holding it see
+// the change.
+func restoreProfile(id string, snapshot []byte) error {
+    profile := data.GetActiveProfile()
+    if profile == nil || profile.ID != id {
+        var err error
+        if profile, err = data.GetProfileByID(id); err != nil {
+            return err
+        }
+    }
+
+    current, err := json.Marshal(profile)
+    if err != nil {
+        return err
+    }
+    if err := applySnapshot(profile, snapshot); err != nil {
+        return err
+    }
+    if err := data.SaveProfile(profile); err != nil {
+        applySnapshot(profile, current)
+        return err
+    }
+    return nil
+}
+
+// applySnapshot replaces the content of a profile with a snapshot, keeping
+// what identifies the stored version it was loaded from
+func applySnapshot(profile *models.Profile, snapshot []byte) error {
+    var restored models.Profile
+    if err := json.Unmarshal(snapshot, &restored); err != nil {
+        return err
+    }
+
+    restored.LastModified = profile.LastModified
+    restored.Encrypted = profile.Encrypted
+    *profile = restored
+    return nil
+}
+
+// profileDeletion moves a profile to the trash
+type profileDeletion struct {
+    profileID string
+    name      string
+}
+
+// DeleteProfile returns a command moving a profile to the trash. Undoing it
+// restores the profile.
+func DeleteProfile(profile *models.Profile) Command {
+    return &profileDeletion{profileID: profile.ID, name: profile.Name}
+}
+
+// Name describes the change
+func (c *profileDeletion) Name() string {
+    return "Delete " + c.name
+}
+
+// Do moves the profile to the trash
+func (c *profileDeletion) Do() error {
+    return data.DeleteProfile(c.profileID)
+}
+
+// Undo restores the profile from the trash
+func (c *profileDeletion) Undo() error {
+    return data.RestoreProfile(c.profileID)
+}
@@ -0,0 +1,113 @@
+// File: internal/history/history.go
+package history
+
+import "sync"
+
+// DefaultLimit is the number of commands kept for undoing
+const DefaultLimit = 100
+
+// Command is a change that can be undone and done again
+type Command interface {
+    // Name describes the change, like "Remove AAPL"
+    Name() string
+
+    // Do applies the change, for the first time or again after Undo
+    Do() error
+
+    // Undo reverts the change
+    Undo() error
+}
+
+// History runs commands and keeps them so they can be undone and redone
+type History struct {
+    mutex  sync.Mutex
+    done   []Command // most recent last
+    undone []Command // most recently undone last
+    limit  int
+}
+
+// NewHistory creates a history keeping up to limit commands
+func NewHistory(limit int) *History {
+    if limit <= 0 {
+        limit = DefaultLimit
+    }
+    return &History{limit: limit}
+}
+
+// Execute runs a command and records it for undoing. Commands undone before
+// can't be redone anymore.
+func (h *History) Execute(cmd Command) error {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if err := cmd.Do(); err != nil {
+        return err
+    }
+
+    h.done = append(h.done, cmd)
+    if len(h.done) > h.limit {
+        h.done = h.done[len(h.done)-h.limit:]
+    }
+    h.undone = nil
+    return nil
+}
+
+// Undo reverts the most recent command and returns it, or nil when there is
+// nothing to undo. A command that fails to undo stays in the history.
+func (h *History) Undo() (Command, error) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if len(h.done) == 0 {
+        return nil, nil
+    }
+    cmd := h.done[len(h.done)-1]
+    if err := cmd.Undo(); err != nil {
+        return cmd, err
+    }
+
+    h.done = h.done[:len(h.done)-1]
+    h.undone = append(h.undone, cmd)
+    return cmd, nil
+}
+
+// Redo applies the most recently undone command again and returns it, or nil
+// when there is nothing to redo
+func (h *History) Redo() (Command, error) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if len(h.undone) == 0 {
+        return nil, nil
+    }
+    cmd := h.undone[len(h.undone)-1]
+    if err := cmd.Do(); err != nil {
+        return cmd, err
+    }
+
+    h.undone = h.undone[:len(h.undone)-1]
+    h.done = append(h.done, cmd)
+    return cmd, nil
+}
+
+// Clear forgets every command, used when the data they apply to was replaced
+func (h *History) Clear() {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    h.done = nil
+    h.undone = nil
+}
+
+// Replace runs replace between commands, so none changes the data while it
+// runs, and forgets every command when replace reports it replaced the data
+// they apply to
+func (h *History) Replace(replace func() bool) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if replace() {
+        h.done = nil
+        h.undone = nil
+    }
+}
@@ -5,6 +5,7 @@ import "time"
 
 // Profile represents a user profile that can contain multiple accounts
 type Profile struct {
+    SchemaVersion int      `json:"schema_version"` // layout of the stored document
     ID           string    `json:"id"`
     Name         string    `json:"name"`
     CreatedAt    time.Time `json:"created_at"`
@@ -12,15 +13,27 @@ type Profile struct {
     Accounts     []Account `json:"accounts"`
     Watchlists   []Watchlist `json:"watchlists"`
     Settings     Settings  `json:"settings"`
+
+    Encrypted    bool      `json:"-"` // stored encrypted with a passphrase
+    Locked       bool      `json:"-"` // encrypted and not unlocked, only ID and Name are set
 }
 
+// Account types
+const (
+    AccountTFSA          = "TFSA"
+    AccountRRSP          = "RRSP"
+    AccountFHSA          = "FHSA"
+    AccountNonRegistered = "Non-registered"
+)
+
 // Account represents a financial account within a profile (TFSA, RRSP, etc)
 type Account struct {
     ID          string    `json:"id"`
     Name        string    `json:"name"`
     Type        string    `json:"type"` // TFSA, RRSP, FHSA, etc.
-    Balance     float64   `json:"balance"`
+    Balance     float64   `json:"balance"` // cash in CAD, derived from the transactions
     Positions   []Position `json:"positions"`
+    Transactions []Transaction `json:"transactions,omitempty"` // cash movements not tied to a position
     CreatedAt   time.Time `json:"created_at"`
     LastUpdated time.Time `json:"last_updated"`
 }
@@ -33,17 +46,55 @@ type Position struct {
     Transactions []Transaction `json:"transactions"`
 }
 
-// Transaction represents a buy/sell transaction for a position
+// Transaction types
+const (
+    TransactionBuy   = "buy"
+    TransactionSell  = "sell"
+    TransactionSplit = "split" // Quantity is the number of new shares for each share held
+
+    // TransactionReturnOfCapital is a distribution of Price per share on
+    // Quantity shares that lowers their cost instead of being income
+    TransactionReturnOfCapital = "return_of_capital"
+
+    // TransactionReinvest is a distribution reinvested in Quantity new
+    // shares at Price, bought like a buy
+    TransactionReinvest = "reinvest"
+
+    // Cash movements of an account, for Amount
+    TransactionDeposit     = "deposit"
+    TransactionWithdrawal  = "withdrawal"
+    TransactionDividend    = "dividend"
+    TransactionInterest    = "interest"
+    TransactionFee         = "fee"
+    TransactionTransferIn  = "transfer_in"
+    TransactionTransferOut = "transfer_out"
+
+    // TransactionFXConversion converts Amount in Currency to ToAmount in ToCurrency
+    TransactionFXConversion = "fx_conversion"
+)
+
+// Transaction represents a trade of a position or a cash movement of an account
 type Transaction struct {
     ID        string    `json:"id"`
-    Type      string    `json:"type"` // buy, sell
+    Type      string    `json:"type"` // a trade of the position or a cash movement of the account
     Quantity  float64   `json:"quantity"`
     Price     float64   `json:"price"`
+    Amount    float64   `json:"amount,omitempty"` // of a cash movement
+    Currency  string    `json:"currency,omitempty"` // of the price or amount, CAD when empty
+    ToAmount  float64   `json:"to_amount,omitempty"` // received by an FX conversion
+    ToCurrency string   `json:"to_currency,omitempty"`
     Date      time.Time `json:"date"`
     Commission float64  `json:"commission"`
     Notes     string    `json:"notes"`
 }
 
+// TransactionRecord is a transaction together with the position it belongs to
+type TransactionRecord struct {
+    AccountID   string      `json:"account_id"`
+    StockSymbol string      `json:"stock_symbol"`
+    Transaction Transaction `json:"transaction"`
+}
+
 // Watchlist represents a collection of stocks to monitor
 type Watchlist struct {
     ID        string   `json:"id"`
@@ -58,4 +109,14 @@ type Settings struct {
     Currency           string `json:"currency"`
     RefreshInterval    int    `json:"refresh_interval"` // in seconds
     DefaultWatchlistID string `json:"default_watchlist_id"`
+    Contributions      ContributionSettings `json:"contributions"`
+}
+
+// ContributionSettings holds what the contribution room of registered
+// accounts is worked out from
+type ContributionSettings struct {
+    TFSAFirstYear      int     `json:"tfsa_first_year,omitempty"`      // year you turned 18, or 2009
+    RRSPDeductionLimit float64 `json:"rrsp_deduction_limit,omitempty"` // from the notice of assessment
+    RRSPLimitYear      int     `json:"rrsp_limit_year,omitempty"`      // year the deduction limit is for
+    FHSAFirstYear      int     `json:"fhsa_first_year,omitempty"`      // year the first FHSA was opened
 }
\ No newline at end of file
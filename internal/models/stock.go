@@ -20,11 +20,23 @@ type Stock struct {
     Timestamp int64   `json:"timestamp"`
 }
 
+// Tick represents a single streamed market event for a symbol
+type Tick struct {
+    Type   string    `json:"type"` // trade or quote
+    Symbol string    `json:"symbol"`
+    Price  float64   `json:"price,omitempty"` // trade price
+    Size   int64     `json:"size,omitempty"`  // trade size
+    Bid    float64   `json:"bid,omitempty"`
+    Ask    float64   `json:"ask,omitempty"`
+    Time   time.Time `json:"time"`
+}
+
 // CandleData represents OHLC data for charting
 type CandleData struct {
     Symbol    string        `json:"symbol"`
-    Timeframe string        `json:"timeframe"` // 1m, 5m, 15m, 1h, 1d, 1w
+    Timeframe string        `json:"timeframe"` // 5min, 15min, 30min, 60min, 1d, 1w, 1mo
     Candles   []CandleStick `json:"candles"`
+    UpdatedAt time.Time     `json:"updated_at"`  // when the candles were last fetched
 }
 
 // CandleStick represents a single candlestick in a chart
@@ -52,4 +64,27 @@ type DrawingObject struct {
 type Point struct {
     X float64 `json:"x"` // X can be a timestamp or index
     Y float64 `json:"y"` // Y is the price level
-}
\ No newline at end of file
+}
+
+// SymbolMatch represents a single result of a symbol search
+type SymbolMatch struct {
+    Symbol   string `json:"symbol"`
+    Name     string `json:"name"`
+    Exchange string `json:"exchange"`
+    Currency string `json:"currency"`
+}
+
+// CompanyInfo represents descriptive data about a listed company
+type CompanyInfo struct {
+    Symbol        string  `json:"symbol"`
+    Name          string  `json:"name"`
+    Exchange      string  `json:"exchange"`
+    Currency      string  `json:"currency"`
+    Country       string  `json:"country"`
+    Sector        string  `json:"sector"`
+    Industry      string  `json:"industry"`
+    Description   string  `json:"description"`
+    MarketCap     float64 `json:"market_cap"`
+    PE            float64 `json:"pe"`
+    DividendYield float64 `json:"dividend_yield"`
+}
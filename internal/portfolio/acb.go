@@ -0,0 +1,234 @@
+// File: internal/portfolio/acb.go
+package portfolio
+
+import (
+    "cmp"
+    "slices"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// registeredAccountTypes are the account types whose gains aren't taxed
+var registeredAccountTypes = []string{
+    models.AccountTFSA,
+    models.AccountRRSP,
+    models.AccountFHSA,
+}
+
+// IsRegistered reports whether gains made in an account type are sheltered from tax
+func IsRegistered(accountType string) bool {
+    return slices.Contains(registeredAccountTypes, accountType)
+}
+
+// ACBEvent is a transaction of a security with its pooled adjusted cost base
+// (ACB) before and after it
+type ACBEvent struct {
+    AccountID    string
+    Symbol       string
+    Transaction  models.Transaction
+    SharesBefore float64
+    SharesAfter  float64
+    ACBBefore    float64 // total ACB of the shares held before
+    ACBAfter     float64
+    Proceeds     float64 // of a sell, net of its commission
+    Gain         float64 // capital gain or loss of a sell, or return of capital beyond the ACB
+    DeniedLoss   float64 // superficial loss left out of the Gain of a sell
+    AddedLoss    float64 // denied loss added to the ACB by the event
+}
+
+// ACBPerShareBefore returns the ACB per share held before the event
+func (e ACBEvent) ACBPerShareBefore() float64 {
+    return perShare(e.ACBBefore, e.SharesBefore)
+}
+
+// ACBPerShareAfter returns the ACB per share held after the event
+func (e ACBEvent) ACBPerShareAfter() float64 {
+    return perShare(e.ACBAfter, e.SharesAfter)
+}
+
+// IsDisposition reports whether the event realized a capital gain or loss
+func (e ACBEvent) IsDisposition() bool {
+    return e.Transaction.Type == models.TransactionSell || e.Gain != 0
+}
+
+// ACBPool is the identical shares of a security held across the taxable accounts
+type ACBPool struct {
+    Symbol string
+    Shares float64
+    ACB    float64
+    Events []ACBEvent // by date
+}
+
+// ACBPerShare returns the ACB per share held
+func (p *ACBPool) ACBPerShare() float64 {
+    return perShare(p.ACB, p.Shares)
+}
+
+// ACBReport is the adjusted cost base of the securities of a profile and the
+// capital gains and losses realized on them
+type ACBReport struct {
+    Pools        []*ACBPool // by symbol
+    Dispositions []ACBEvent // sells and returns of capital beyond the ACB, by date
+
+    SuperficialLosses []SuperficialLoss // by date
+}
+
+// Years returns the years with dispositions, most recent first
+func (r *ACBReport) Years() []int {
+    var years []int
+    for _, e := range r.Dispositions {
+        if year := e.Transaction.Date.Year(); !slices.Contains(years, year) {
+            years = append(years, year)
+        }
+    }
+    slices.Sort(years)
+    slices.Reverse(years)
+    return years
+}
+
+// DispositionsIn returns the dispositions of a tax year
+func (r *ACBReport) DispositionsIn(year int) []ACBEvent {
+    var events []ACBEvent
+    for _, e := range r.Dispositions {
+        if e.Transaction.Date.Year() == year {
+            events = append(events, e)
+        }
+    }
+    return events
+}
+
+// NetGain returns the capital gains less the capital losses of a tax year
+func (r *ACBReport) NetGain(year int) float64 {
+    var total float64
+    for _, e := range r.DispositionsIn(year) {
+        total += e.Gain
+    }
+    return total
+}
+
+// pooledTransaction is a transaction with the account holding it
+type pooledTransaction struct {
+    accountID   string
+    symbol      string
+    transaction models.Transaction
+}
+
+// ComputeACB pools the shares of each security held in the taxable accounts
+// of a profile, as identical properties share a single ACB, and replays their
+// transactions in date order. Commissions are added to the ACB of buys and
+// taken from the proceeds of sells, reinvested distributions buy shares and
+// returns of capital lower the ACB, any excess being a capital gain. Losses
+// are checked against the purchases of every account, registered ones
+// included, and superficial losses are denied and added to the ACB of the
+// replacement shares. It fails with a TransactionError on the first
+// transaction that can't be applied, returning the report up to it.
+func ComputeACB(profile *models.Profile) (*ACBReport, error) {
+    var pooled []pooledTransaction
+    for _, account := range profile.Accounts {
+        if IsRegistered(account.Type) {
+            continue
+        }
+        for _, position := range account.Positions {
+            for _, t := range SortTransactions(position.Transactions) {
+                pooled = append(pooled, pooledTransaction{account.ID, position.StockSymbol, t})
+            }
+        }
+    }
+    slices.SortStableFunc(pooled, func(a, b pooledTransaction) int {
+        return a.transaction.Date.Compare(b.transaction.Date)
+    })
+
+    report := &ACBReport{}
+    pools := make(map[string]*ACBPool)
+    ledgers := make(map[string]*Ledger)
+    bought := acquisitions(profile)
+    pending := make(map[string]float64) // denied losses waiting for the purchase of their replacement shares
+    for i, p := range pooled {
+        pool, ledger := pools[p.symbol], ledgers[p.symbol]
+        if pool == nil {
+            pool, ledger = &ACBPool{Symbol: p.symbol}, &Ledger{Symbol: p.symbol}
+            pools[p.symbol], ledgers[p.symbol] = pool, ledger
+            report.Pools = append(report.Pools, pool)
+        }
+
+        event := ACBEvent{
+            AccountID:    p.accountID,
+            Symbol:       p.symbol,
+            Transaction:  p.transaction,
+            SharesBefore: ledger.Quantity,
+            ACBBefore:    ledger.CostBasis,
+        }
+        gain, err := ledger.apply(p.transaction)
+        if err != nil {
+            sortPools(report)
+            return report, &TransactionError{AccountID: p.accountID, Symbol: p.symbol, TransactionID: p.transaction.ID, Err: err}
+        }
+        if amount, ok := pending[p.transaction.ID]; ok {
+            ledger.addCost(amount)
+            event.AddedLoss = amount
+            delete(pending, p.transaction.ID)
+        }
+        event.Gain = gain
+        if p.transaction.Type == models.TransactionSell {
+            event.Proceeds = p.transaction.Quantity*p.transaction.Price - p.transaction.Commission
+            if gain < 0 {
+                if loss, ok := superficialLoss(profile, bought, event, -gain); ok {
+                    event.Gain += loss.Denied
+                    event.DeniedLoss = loss.Denied
+                    // The replacement shares still held carry the denied
+                    // loss; if none are, it waits for the next purchase
+                    if ledger.Quantity > 0 {
+                        ledger.addCost(loss.Added)
+                        event.AddedLoss = loss.Added
+                    } else if next := nextPurchase(pooled[i+1:], p.symbol, p.transaction.Date); next != "" {
+                        pending[next] += loss.Added
+                    } else {
+                        loss.Added = 0
+                    }
+                    report.SuperficialLosses = append(report.SuperficialLosses, loss)
+                }
+            }
+        }
+        event.SharesAfter, event.ACBAfter = ledger.Quantity, ledger.CostBasis
+
+        pool.Shares, pool.ACB = ledger.Quantity, ledger.CostBasis
+        pool.Events = append(pool.Events, event)
+        if event.IsDisposition() {
+            report.Dispositions = append(report.Dispositions, event)
+        }
+    }
+    sortPools(report)
+    return report, nil
+}
+
+// nextPurchase returns the ID of the first purchase of a security among the
+// taxable transactions following a sale, within its superficial loss window
+func nextPurchase(following []pooledTransaction, symbol string, sold time.Time) string {
+    _, to := superficialWindow(sold)
+    for _, p := range following {
+        if !p.transaction.Date.Before(to) {
+            break
+        }
+        t := p.transaction
+        if p.symbol == symbol && (t.Type == models.TransactionBuy || t.Type == models.TransactionReinvest) {
+            return t.ID
+        }
+    }
+    return ""
+}
+
+// sortPools orders the pools of a report by symbol
+func sortPools(report *ACBReport) {
+    slices.SortFunc(report.Pools, func(a, b *ACBPool) int {
+        return cmp.Compare(a.Symbol, b.Symbol)
+    })
+}
+
+// perShare divides a cost by a number of shares, zero when there are none
+func perShare(cost, shares float64) float64 {
+    if shares <= 0 {
+        return 0
+    }
+    return cost / shares
+}
@@ -0,0 +1,214 @@
+// File: internal/portfolio/acb_test.go
+package portfolio
+
+import (
+    "errors"
+    "slices"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// holding is a position of a test account with its transactions
+type holding struct {
+    accountID    string
+    accountType  string
+    symbol       string
+    transactions []models.Transaction
+}
+
+// holdingsProfile returns a profile with the given positions, adding their
+// accounts as they first appear
+func holdingsProfile(t *testing.T, holdings []holding) *models.Profile {
+    t.Helper()
+    profile := &models.Profile{}
+    for _, h := range holdings {
+        if findAccount(profile, h.accountID) < 0 {
+            if err := AddAccount(profile, h.accountID, h.accountID, h.accountType); err != nil {
+                t.Fatal(err)
+            }
+        }
+        account := &profile.Accounts[findAccount(profile, h.accountID)]
+        account.Positions = append(account.Positions, models.Position{StockSymbol: h.symbol, Transactions: h.transactions})
+    }
+    return profile
+}
+
+func TestComputeACB(t *testing.T) {
+    tests := []struct {
+        name     string
+        holdings []holding
+        shares   float64
+        acb      float64
+        gains    []float64 // of the dispositions, by date
+        years    []int
+        netGain  map[int]float64
+    }{
+        {
+            name: "identical shares are pooled across taxable accounts only",
+            holdings: []holding{
+                {"cash", models.AccountNonRegistered, "XEQT", []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 20, 0, "2024-01-10"),
+                    trade(t, "s1", models.TransactionSell, 5, 40, 0, "2024-03-10"),
+                }},
+                {"margin", models.AccountNonRegistered, "XEQT", []models.Transaction{
+                    trade(t, "b2", models.TransactionBuy, 10, 30, 10, "2024-02-10"),
+                }},
+                {"tfsa", models.AccountTFSA, "XEQT", []models.Transaction{
+                    trade(t, "b3", models.TransactionBuy, 100, 1, 0, "2024-01-15"),
+                    trade(t, "s2", models.TransactionSell, 100, 50, 0, "2024-04-15"),
+                }},
+            },
+            shares:  15,
+            acb:     382.5,
+            gains:   []float64{72.5},
+            years:   []int{2024},
+            netGain: map[int]float64{2024: 72.5},
+        },
+        {
+            name: "sell commission lowers the gain",
+            holdings: []holding{
+                {"cash", models.AccountNonRegistered, "XEQT", []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 5, "2024-01-10"),
+                    trade(t, "s1", models.TransactionSell, 10, 12, 5, "2024-03-10"),
+                }},
+            },
+            gains:   []float64{10},
+            years:   []int{2024},
+            netGain: map[int]float64{2024: 10},
+        },
+        {
+            name: "return of capital beyond the ACB is a disposition",
+            holdings: []holding{
+                {"cash", models.AccountNonRegistered, "VDY", []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2023-01-10"),
+                    trade(t, "r1", models.TransactionReturnOfCapital, 10, 4, 0, "2023-06-10"),
+                    trade(t, "r2", models.TransactionReturnOfCapital, 10, 8, 0, "2024-06-10"),
+                    trade(t, "d1", models.TransactionReinvest, 2, 10, 0, "2024-07-10"),
+                    trade(t, "s1", models.TransactionSell, 6, 15, 0, "2025-02-10"),
+                }},
+            },
+            shares:  6,
+            acb:     10,
+            gains:   []float64{20, 80},
+            years:   []int{2025, 2024},
+            netGain: map[int]float64{2023: 0, 2024: 20, 2025: 80},
+        },
+        {
+            name: "sells are replayed in date order across accounts",
+            holdings: []holding{
+                {"cash", models.AccountNonRegistered, "XEQT", []models.Transaction{
+                    trade(t, "s1", models.TransactionSell, 10, 30, 0, "2024-05-10"),
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-10"),
+                }},
+                {"margin", models.AccountNonRegistered, "XEQT", []models.Transaction{
+                    trade(t, "b2", models.TransactionBuy, 10, 20, 0, "2024-03-10"),
+                }},
+            },
+            shares:  10,
+            acb:     150,
+            gains:   []float64{150},
+            years:   []int{2024},
+            netGain: map[int]float64{2024: 150},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            report, err := ComputeACB(holdingsProfile(t, tt.holdings))
+            if err != nil {
+                t.Fatalf("ComputeACB() error = %v", err)
+            }
+            if len(report.Pools) != 1 {
+                t.Fatalf("ComputeACB() has %d pools, want 1", len(report.Pools))
+            }
+            pool := report.Pools[0]
+            if !closeTo(pool.Shares, tt.shares) || !closeTo(pool.ACB, tt.acb) {
+                t.Errorf("pool holds %g shares with an ACB of %g, want %g with %g", pool.Shares, pool.ACB, tt.shares, tt.acb)
+            }
+            if len(report.Dispositions) != len(tt.gains) {
+                t.Fatalf("ComputeACB() has %d dispositions, want %d", len(report.Dispositions), len(tt.gains))
+            }
+            for i, e := range report.Dispositions {
+                if !closeTo(e.Gain, tt.gains[i]) {
+                    t.Errorf("disposition %s gain = %g, want %g", e.Transaction.ID, e.Gain, tt.gains[i])
+                }
+            }
+            if years := report.Years(); !slices.Equal(years, tt.years) {
+                t.Errorf("Years() = %v, want %v", years, tt.years)
+            }
+            for year, want := range tt.netGain {
+                if got := report.NetGain(year); !closeTo(got, want) {
+                    t.Errorf("NetGain(%d) = %g, want %g", year, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestComputeACBEvents(t *testing.T) {
+    profile := holdingsProfile(t, []holding{
+        {"cash", models.AccountNonRegistered, "XEQT", []models.Transaction{
+            trade(t, "b1", models.TransactionBuy, 10, 20, 0, "2024-01-10"),
+            trade(t, "s1", models.TransactionSell, 5, 40, 0, "2024-03-10"),
+        }},
+        {"margin", models.AccountNonRegistered, "XEQT", []models.Transaction{
+            trade(t, "b2", models.TransactionBuy, 10, 30, 10, "2024-02-10"),
+        }},
+    })
+    report, err := ComputeACB(profile)
+    if err != nil {
+        t.Fatalf("ComputeACB() error = %v", err)
+    }
+
+    events := report.Pools[0].Events
+    want := []struct {
+        id               string
+        accountID        string
+        before, after    float64 // ACB per share
+        proceeds         float64
+        sharesAfter, acb float64
+    }{
+        {"b1", "cash", 0, 20, 0, 10, 200},
+        {"b2", "margin", 20, 25.5, 0, 20, 510},
+        {"s1", "cash", 25.5, 25.5, 200, 15, 382.5},
+    }
+    if len(events) != len(want) {
+        t.Fatalf("pool has %d events, want %d", len(events), len(want))
+    }
+    for i, w := range want {
+        e := events[i]
+        if e.Transaction.ID != w.id || e.AccountID != w.accountID {
+            t.Fatalf("event %d is %s in %s, want %s in %s", i, e.Transaction.ID, e.AccountID, w.id, w.accountID)
+        }
+        if !closeTo(e.ACBPerShareBefore(), w.before) || !closeTo(e.ACBPerShareAfter(), w.after) || !closeTo(e.Proceeds, w.proceeds) ||
+            !closeTo(e.SharesAfter, w.sharesAfter) || !closeTo(e.ACBAfter, w.acb) {
+            t.Errorf("event %s = %g to %g per share, proceeds %g, %g shares with an ACB of %g; want %g to %g, proceeds %g, %g shares with %g",
+                w.id, e.ACBPerShareBefore(), e.ACBPerShareAfter(), e.Proceeds, e.SharesAfter, e.ACBAfter,
+                w.before, w.after, w.proceeds, w.sharesAfter, w.acb)
+        }
+    }
+}
+
+func TestComputeACBOversell(t *testing.T) {
+    profile := holdingsProfile(t, []holding{
+        {"cash", models.AccountNonRegistered, "XEQT", []models.Transaction{
+            trade(t, "b1", models.TransactionBuy, 10, 20, 0, "2024-01-10"),
+        }},
+        {"margin", models.AccountNonRegistered, "XEQT", []models.Transaction{
+            trade(t, "s1", models.TransactionSell, 11, 30, 0, "2024-02-10"),
+            trade(t, "b2", models.TransactionBuy, 10, 30, 0, "2024-03-10"),
+        }},
+    })
+    report, err := ComputeACB(profile)
+    if !errors.Is(err, ErrNegativeQuantity) {
+        t.Fatalf("ComputeACB() error = %v, want %v", err, ErrNegativeQuantity)
+    }
+    var txErr *TransactionError
+    if !errors.As(err, &txErr) || txErr.AccountID != "margin" || txErr.TransactionID != "s1" {
+        t.Errorf("ComputeACB() error = %#v, want a TransactionError for s1 in margin", err)
+    }
+    if len(report.Pools) != 1 || len(report.Pools[0].Events) != 1 {
+        t.Errorf("ComputeACB() should return the report up to the failing transaction, got %+v", report.Pools)
+    }
+}
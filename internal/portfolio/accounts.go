@@ -0,0 +1,54 @@
+// File: internal/portfolio/accounts.go
+package portfolio
+
+import (
+    "fmt"
+    "slices"
+    "strings"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// AccountTypes are the kinds of accounts a profile can hold
+var AccountTypes = []string{
+    models.AccountTFSA,
+    models.AccountRRSP,
+    models.AccountFHSA,
+    models.AccountNonRegistered,
+}
+
+// NewAccountID returns an ID not used by any account of a profile
+func NewAccountID(profile *models.Profile) string {
+    for n := time.Now().Unix(); ; n++ {
+        id := fmt.Sprintf("account_%d", n)
+        if findAccount(profile, id) < 0 {
+            return id
+        }
+    }
+}
+
+// AddAccount adds an empty account to a profile
+func AddAccount(profile *models.Profile, id, name, accountType string) error {
+    name = strings.TrimSpace(name)
+    if name == "" {
+        return fmt.Errorf("the account needs a name")
+    }
+    if !slices.Contains(AccountTypes, accountType) {
+        return fmt.Errorf("unknown account type %q", accountType)
+    }
+    if findAccount(profile, id) >= 0 {
+        return fmt.Errorf("account %s already exists", id)
+    }
+
+    now := time.Now()
+    profile.Accounts = append(profile.Accounts, models.Account{
+        ID:          id,
+        Name:        name,
+        Type:        accountType,
+        Positions:   []models.Position{},
+        CreatedAt:   now,
+        LastUpdated: now,
+    })
+    return nil
+}
@@ -0,0 +1,188 @@
+// File: internal/portfolio/cash.go
+package portfolio
+
+import (
+    "fmt"
+    "math"
+    "slices"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// DefaultCurrency is the currency of the transactions that don't name one,
+// and of the Balance of an account
+const DefaultCurrency = "CAD"
+
+// balanceEpsilon is how far a cash balance may be from another and still match
+const balanceEpsilon = 0.005
+
+// cashSchemaVersion is the first profile schema version whose account
+// balances are derived from their transactions. Older balances were entered
+// by hand and are kept until the profile is migrated.
+const cashSchemaVersion = 2
+
+// CashTransactionTypes are the kinds of cash movements an account can record
+var CashTransactionTypes = []string{
+    models.TransactionDeposit,
+    models.TransactionWithdrawal,
+    models.TransactionDividend,
+    models.TransactionInterest,
+    models.TransactionFee,
+    models.TransactionTransferIn,
+    models.TransactionTransferOut,
+    models.TransactionFXConversion,
+}
+
+// Reconciliation compares the cash balance of an account derived from its
+// transactions with the balance of a statement
+type Reconciliation struct {
+    AccountID  string
+    Currency   string
+    Date       time.Time
+    Derived    float64
+    Statement  float64
+    Difference float64 // statement less derived
+}
+
+// Balanced reports whether the statement matches the transactions
+func (r Reconciliation) Balanced() bool {
+    return math.Abs(r.Difference) < balanceEpsilon
+}
+
+// TransactionCurrency returns the currency of the price or amount of a transaction
+func TransactionCurrency(t models.Transaction) string {
+    if t.Currency == "" {
+        return DefaultCurrency
+    }
+    return t.Currency
+}
+
+// applyCash adds the cash a transaction moves to balances by currency. Buys
+// are paid for and sells credited, commissions included, returns of capital
+// are paid out and reinvested distributions only cost their commission.
+func applyCash(balances map[string]float64, t models.Transaction) {
+    currency := TransactionCurrency(t)
+    switch t.Type {
+    case models.TransactionDeposit, models.TransactionDividend, models.TransactionInterest, models.TransactionTransferIn:
+        balances[currency] += t.Amount
+    case models.TransactionWithdrawal, models.TransactionFee, models.TransactionTransferOut:
+        balances[currency] -= t.Amount
+    case models.TransactionFXConversion:
+        balances[currency] -= t.Amount
+        balances[t.ToCurrency] += t.ToAmount
+    case models.TransactionBuy:
+        balances[currency] -= t.Quantity*t.Price + t.Commission
+    case models.TransactionSell:
+        balances[currency] += t.Quantity*t.Price - t.Commission
+    case models.TransactionReturnOfCapital:
+        balances[currency] += t.Quantity*t.Price - t.Commission
+    default:
+        balances[currency] -= t.Commission
+    }
+}
+
+// CashBalances derives the cash of an account by currency from its cash
+// movements and trades dated before until, or from all of them when until is zero
+func CashBalances(account models.Account, until time.Time) map[string]float64 {
+    balances := make(map[string]float64)
+    apply := func(t models.Transaction) {
+        if until.IsZero() || t.Date.Before(until) {
+            applyCash(balances, t)
+        }
+    }
+    for _, t := range account.Transactions {
+        apply(t)
+    }
+    for _, position := range account.Positions {
+        for _, t := range position.Transactions {
+            apply(t)
+        }
+    }
+    return balances
+}
+
+// deriveBalance sets the Balance of an account from its transactions, once
+// the profile has been migrated to derived balances
+func deriveBalance(profile *models.Profile, account *models.Account) {
+    if profile.SchemaVersion < cashSchemaVersion {
+        return
+    }
+    account.Balance = CashBalances(*account, time.Time{})[DefaultCurrency]
+}
+
+// Reconcile compares the cash of an account in a currency at the end of a day
+// with the balance of a statement
+func Reconcile(profile *models.Profile, accountID, currency string, date time.Time, statement float64) (Reconciliation, error) {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return Reconciliation{}, fmt.Errorf("no account %s", accountID)
+    }
+    if currency == "" {
+        currency = DefaultCurrency
+    }
+    end := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()).AddDate(0, 0, 1)
+    derived := CashBalances(profile.Accounts[a], end)[currency]
+    return Reconciliation{
+        AccountID:  accountID,
+        Currency:   currency,
+        Date:       date,
+        Derived:    derived,
+        Statement:  statement,
+        Difference: statement - derived,
+    }, nil
+}
+
+// AddCashTransaction records a cash movement in an account
+func AddCashTransaction(profile *models.Profile, accountID string, t models.Transaction) error {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return fmt.Errorf("no account %s", accountID)
+    }
+    if !slices.Contains(CashTransactionTypes, t.Type) {
+        return fmt.Errorf("%w: unknown cash movement %q", ErrInvalidTransaction, t.Type)
+    }
+    if t.Amount <= 0 {
+        return fmt.Errorf("%w: the amount must be positive", ErrInvalidTransaction)
+    }
+    if t.Type == models.TransactionFXConversion {
+        if t.ToAmount <= 0 || t.ToCurrency == "" {
+            return fmt.Errorf("%w: a conversion needs the amount and currency received", ErrInvalidTransaction)
+        }
+        if t.ToCurrency == TransactionCurrency(t) {
+            return fmt.Errorf("%w: a conversion needs two different currencies", ErrInvalidTransaction)
+        }
+    } else if t.ToAmount != 0 || t.ToCurrency != "" {
+        return fmt.Errorf("%w: only a conversion receives another currency", ErrInvalidTransaction)
+    }
+    if t.ID == "" {
+        t.ID = NewTransactionID(profile)
+    } else if _, _, found := FindTransaction(profile, t.ID); found {
+        return fmt.Errorf("%w: the ID %s is already used", ErrInvalidTransaction, t.ID)
+    }
+
+    account := &profile.Accounts[a]
+    account.Transactions = append(account.Transactions, t)
+    deriveBalance(profile, account)
+    account.LastUpdated = time.Now()
+    return nil
+}
+
+// RemoveCashTransaction deletes a cash movement of an account
+func RemoveCashTransaction(profile *models.Profile, accountID, transactionID string) error {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return fmt.Errorf("no account %s", accountID)
+    }
+    account := &profile.Accounts[a]
+    i := slices.IndexFunc(account.Transactions, func(t models.Transaction) bool {
+        return t.ID == transactionID
+    })
+    if i < 0 {
+        return fmt.Errorf("no transaction %s in account %s", transactionID, accountID)
+    }
+    account.Transactions = slices.Delete(account.Transactions, i, i+1)
+    deriveBalance(profile, account)
+    account.LastUpdated = time.Now()
+    return nil
+}
@@ -0,0 +1,270 @@
+// File: internal/portfolio/cash_test.go
+package portfolio
+
+import (
+    "maps"
+    "testing"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// movement returns a cash movement of an account
+func movement(t *testing.T, kind string, amount float64, currency, date string) models.Transaction {
+    t.Helper()
+    return models.Transaction{Type: kind, Amount: amount, Currency: currency, Date: mustDate(t, date)}
+}
+
+func TestCashBalances(t *testing.T) {
+    usdBuy := trade(t, "b2", models.TransactionBuy, 10, 50, 1, "2024-03-01")
+    usdBuy.Currency = "USD"
+    conversion := movement(t, models.TransactionFXConversion, 1400, "", "2024-02-01")
+    conversion.ToAmount, conversion.ToCurrency = 1000, "USD"
+
+    tests := []struct {
+        name         string
+        movements    []models.Transaction
+        transactions []models.Transaction // of a position
+        until        string
+        balances     map[string]float64
+    }{
+        {
+            name: "deposits, income and withdrawals",
+            movements: []models.Transaction{
+                movement(t, models.TransactionDeposit, 1000, "", "2024-01-02"),
+                movement(t, models.TransactionDividend, 25, "", "2024-03-01"),
+                movement(t, models.TransactionInterest, 5, "", "2024-03-31"),
+                movement(t, models.TransactionFee, 10, "", "2024-04-01"),
+                movement(t, models.TransactionWithdrawal, 300, "", "2024-05-01"),
+            },
+            balances: map[string]float64{"CAD": 720},
+        },
+        {
+            name: "transfers",
+            movements: []models.Transaction{
+                movement(t, models.TransactionTransferIn, 5000, "", "2024-01-02"),
+                movement(t, models.TransactionTransferOut, 1200, "", "2024-02-02"),
+            },
+            balances: map[string]float64{"CAD": 3800},
+        },
+        {
+            name:      "buy with commission",
+            movements: []models.Transaction{movement(t, models.TransactionDeposit, 2000, "", "2024-01-02")},
+            transactions: []models.Transaction{
+                trade(t, "b1", models.TransactionBuy, 10, 100, 9.99, "2024-01-03"),
+            },
+            balances: map[string]float64{"CAD": 990.01},
+        },
+        {
+            name:      "sell net of commission",
+            movements: []models.Transaction{movement(t, models.TransactionDeposit, 1000, "", "2024-01-02")},
+            transactions: []models.Transaction{
+                trade(t, "b1", models.TransactionBuy, 10, 100, 0, "2024-01-03"),
+                trade(t, "s1", models.TransactionSell, 4, 120, 5, "2024-02-03"),
+            },
+            balances: map[string]float64{"CAD": 475},
+        },
+        {
+            name: "return of capital is paid and reinvestment costs its commission",
+            transactions: []models.Transaction{
+                trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-03"),
+                trade(t, "r1", models.TransactionReturnOfCapital, 10, 0.5, 0, "2024-02-03"),
+                trade(t, "d1", models.TransactionReinvest, 1, 10, 1, "2024-03-03"),
+                trade(t, "x1", models.TransactionSplit, 2, 0, 2, "2024-04-03"),
+            },
+            balances: map[string]float64{"CAD": -98},
+        },
+        {
+            name: "conversion pays for a buy in another currency",
+            movements: []models.Transaction{
+                movement(t, models.TransactionDeposit, 2000, "", "2024-01-02"),
+                conversion,
+                movement(t, models.TransactionDividend, 12, "USD", "2024-04-01"),
+            },
+            transactions: []models.Transaction{usdBuy},
+            balances:     map[string]float64{"CAD": 600, "USD": 511},
+        },
+        {
+            name: "only the transactions before until",
+            movements: []models.Transaction{
+                movement(t, models.TransactionDeposit, 1000, "", "2024-01-02"),
+                movement(t, models.TransactionWithdrawal, 100, "", "2024-03-01"),
+            },
+            transactions: []models.Transaction{
+                trade(t, "b1", models.TransactionBuy, 1, 100, 0, "2024-02-01"),
+                trade(t, "s1", models.TransactionSell, 1, 150, 0, "2024-03-01"),
+            },
+            until:    "2024-03-01",
+            balances: map[string]float64{"CAD": 900},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            account := models.Account{
+                ID:           "cash",
+                Transactions: tt.movements,
+                Positions:    []models.Position{{StockSymbol: "XEQT", Transactions: tt.transactions}},
+            }
+            var until time.Time
+            if tt.until != "" {
+                until = mustDate(t, tt.until)
+            }
+            balances := CashBalances(account, until)
+            if !maps.EqualFunc(balances, tt.balances, closeTo) {
+                t.Errorf("CashBalances() = %v, want %v", balances, tt.balances)
+            }
+        })
+    }
+}
+
+func TestDerivedBalance(t *testing.T) {
+    for _, tt := range []struct {
+        name    string
+        version int
+        balance float64
+    }{
+        {"balance entered by hand is kept until the profile is migrated", 1, 1500},
+        {"balance is derived once the profile is migrated", cashSchemaVersion, 475},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            profile := &models.Profile{SchemaVersion: tt.version}
+            if err := AddAccount(profile, "cash", "Cash", models.AccountNonRegistered); err != nil {
+                t.Fatal(err)
+            }
+            profile.Accounts[0].Balance = 1500
+
+            if err := AddCashTransaction(profile, "cash", movement(t, models.TransactionDeposit, 1000, "", "2024-01-02")); err != nil {
+                t.Fatal(err)
+            }
+            if err := AddTransaction(profile, "cash", "XEQT", trade(t, "", models.TransactionBuy, 5, 100, 25, "2024-01-03")); err != nil {
+                t.Fatal(err)
+            }
+            if err := DeriveProfile(profile); err != nil {
+                t.Fatal(err)
+            }
+            if got := profile.Accounts[0].Balance; !closeTo(got, tt.balance) {
+                t.Errorf("Balance = %g, want %g", got, tt.balance)
+            }
+            if issues := CheckProfile(profile); len(issues) > 0 {
+                t.Errorf("CheckProfile() = %+v, want none", issues)
+            }
+        })
+    }
+}
+
+func TestDeriveProfileFundsOpeningBuys(t *testing.T) {
+    profile := &models.Profile{SchemaVersion: cashSchemaVersion}
+    if err := AddAccount(profile, "cash", "Cash", models.AccountNonRegistered); err != nil {
+        t.Fatal(err)
+    }
+    if err := AddCashTransaction(profile, "cash", movement(t, models.TransactionDeposit, 250, "", "2024-01-02")); err != nil {
+        t.Fatal(err)
+    }
+    account := &profile.Accounts[0]
+    account.Positions = append(account.Positions, models.Position{StockSymbol: "XEQT", Quantity: 10, AverageCost: 30})
+
+    if err := DeriveProfile(profile); err != nil {
+        t.Fatal(err)
+    }
+    if len(account.Positions[0].Transactions) != 1 || len(account.Transactions) != 2 {
+        t.Fatalf("DeriveProfile() gave %d opening buys and %d cash movements, want 1 and 2",
+            len(account.Positions[0].Transactions), len(account.Transactions))
+    }
+    opening, funding := account.Positions[0].Transactions[0], account.Transactions[1]
+    if funding.Type != models.TransactionDeposit || !closeTo(funding.Amount, 300) || !funding.Date.Equal(opening.Date) || funding.Notes != openingBalanceNote {
+        t.Errorf("opening buy %+v is funded by %+v, want a deposit of 300 on its date", opening, funding)
+    }
+    if !closeTo(account.Balance, 250) {
+        t.Errorf("Balance = %g after opening a position, want 250", account.Balance)
+    }
+}
+
+func TestReconcile(t *testing.T) {
+    profile := &models.Profile{SchemaVersion: cashSchemaVersion}
+    if err := AddAccount(profile, "cash", "Cash", models.AccountNonRegistered); err != nil {
+        t.Fatal(err)
+    }
+    for _, m := range []models.Transaction{
+        movement(t, models.TransactionDeposit, 1000, "", "2024-01-02"),
+        movement(t, models.TransactionDeposit, 500, "USD", "2024-01-02"),
+        movement(t, models.TransactionFee, 10, "", "2024-01-31"),
+        movement(t, models.TransactionDeposit, 100, "", "2024-02-01"),
+    } {
+        if err := AddCashTransaction(profile, "cash", m); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name      string
+        currency  string
+        date      string
+        statement float64
+        derived   float64
+        balanced  bool
+    }{
+        {"includes the movements of the statement date", "", "2024-01-31", 990, 990, true},
+        {"difference with the statement", "CAD", "2024-01-31", 1000, 990, false},
+        {"other currency", "USD", "2024-02-01", 500, 500, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r, err := Reconcile(profile, "cash", tt.currency, mustDate(t, tt.date), tt.statement)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !closeTo(r.Derived, tt.derived) || !closeTo(r.Difference, tt.statement-tt.derived) || r.Balanced() != tt.balanced {
+                t.Errorf("Reconcile() = %+v, want %g derived, balanced %v", r, tt.derived, tt.balanced)
+            }
+        })
+    }
+}
+
+func TestAddCashTransactionRejects(t *testing.T) {
+    sameCurrency := movement(t, models.TransactionFXConversion, 100, "USD", "2024-01-02")
+    sameCurrency.ToAmount, sameCurrency.ToCurrency = 100, "USD"
+    noTarget := movement(t, models.TransactionFXConversion, 100, "", "2024-01-02")
+    receivesCurrency := movement(t, models.TransactionDeposit, 100, "", "2024-01-02")
+    receivesCurrency.ToCurrency = "USD"
+
+    for _, tt := range []struct {
+        name string
+        t    models.Transaction
+    }{
+        {"trade", trade(t, "", models.TransactionBuy, 1, 1, 0, "2024-01-02")},
+        {"zero amount", movement(t, models.TransactionDeposit, 0, "", "2024-01-02")},
+        {"conversion to the same currency", sameCurrency},
+        {"conversion without the amount received", noTarget},
+        {"other currency received outside a conversion", receivesCurrency},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            profile := &models.Profile{SchemaVersion: cashSchemaVersion}
+            if err := AddAccount(profile, "cash", "Cash", models.AccountNonRegistered); err != nil {
+                t.Fatal(err)
+            }
+            if err := AddCashTransaction(profile, "cash", tt.t); err == nil {
+                t.Errorf("AddCashTransaction() accepted %+v", tt.t)
+            }
+            if len(profile.Accounts[0].Transactions) != 0 {
+                t.Error("AddCashTransaction() recorded a rejected movement")
+            }
+        })
+    }
+}
+
+func TestOpeningBalanceIsNotAContribution(t *testing.T) {
+    profile := contributionProfile(t, models.ContributionSettings{TFSAFirstYear: 2024}, nil)
+    opening := movement(t, models.TransactionDeposit, 20000, "", "2024-01-02")
+    opening.Notes = openingBalanceNote
+    if err := AddCashTransaction(profile, "tfsa", opening); err != nil {
+        t.Fatal(err)
+    }
+    room, err := ComputeContributionRoom(profile, models.AccountTFSA, 2024)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !closeTo(room.Contributed, 0) || !closeTo(room.Remaining, 7000) {
+        t.Errorf("ComputeContributionRoom() = %+v, want the opening balance left out", room)
+    }
+}
@@ -0,0 +1,214 @@
+// File: internal/portfolio/contributions.go
+package portfolio
+
+import (
+    "fmt"
+    "math"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// TFSAAnnualLimits are the TFSA dollar limits by year. Years after the last
+// one use its limit until the table is updated.
+var TFSAAnnualLimits = map[int]float64{
+    2009: 5000, 2010: 5000, 2011: 5000, 2012: 5000,
+    2013: 5500, 2014: 5500,
+    2015: 10000,
+    2016: 5500, 2017: 5500, 2018: 5500,
+    2019: 6000, 2020: 6000, 2021: 6000, 2022: 6000,
+    2023: 6500,
+    2024: 7000, 2025: 7000, 2026: 7000,
+}
+
+const (
+    // tfsaFirstYear is the year TFSAs were introduced
+    tfsaFirstYear = 2009
+
+    // fhsaFirstYear is the year FHSAs were introduced
+    fhsaFirstYear = 2023
+
+    // FHSA participation room
+    FHSAAnnualLimit       = 8000.0
+    FHSALifetimeLimit     = 40000.0
+    FHSACarryForwardLimit = 8000.0
+
+    // rrspOverContributionAllowance is how far RRSP contributions may go over
+    // the deduction limit before the penalty applies
+    rrspOverContributionAllowance = 2000.0
+
+    // overContributionPenaltyRate is the tax on an excess contribution for
+    // each month it stays in the account
+    overContributionPenaltyRate = 0.01
+)
+
+// ContributionRoom is the contribution room of a registered account type in a
+// year, shared by every account of that type
+type ContributionRoom struct {
+    AccountType string
+    Year        int
+    Known       bool    // false for an RRSP without a deduction limit entered for the year or before
+    Available   float64 // room at the start of the year
+    Contributed float64 // deposits of the year
+    Withdrawn   float64 // withdrawals of the year
+    Remaining   float64 // room left, negative when over-contributed
+}
+
+// OverContribution is the excess a deposit would leave in a registered
+// account type and the penalty it would cost
+type OverContribution struct {
+    AccountType    string
+    Excess         float64 // contributions beyond the room
+    Taxable        float64 // part of the excess the penalty applies to
+    MonthlyPenalty float64
+    Months         int     // months left in the year, the deposit's included
+    Penalty        float64 // if the excess stays until the end of the year
+}
+
+// tfsaLimit returns the TFSA dollar limit of a year
+func tfsaLimit(year int) float64 {
+    if year < tfsaFirstYear {
+        return 0
+    }
+    if limit, ok := TFSAAnnualLimits[year]; ok {
+        return limit
+    }
+    latest := tfsaFirstYear
+    for y := range TFSAAnnualLimits {
+        latest = max(latest, y)
+    }
+    if year > latest {
+        return TFSAAnnualLimits[latest]
+    }
+    return 0
+}
+
+// cashByYear sums the cash movements of a type in the accounts of a type, by
+// year. Opening balances stand in for cash held before the account was
+// tracked, so they aren't contributions.
+func cashByYear(profile *models.Profile, accountType, transactionType string) map[int]float64 {
+    totals := make(map[int]float64)
+    for _, account := range profile.Accounts {
+        if account.Type != accountType {
+            continue
+        }
+        for _, t := range account.Transactions {
+            if t.Type == transactionType && t.Notes != openingBalanceNote {
+                totals[t.Date.Year()] += t.Amount
+            }
+        }
+    }
+    return totals
+}
+
+// ComputeContributionRoom works out the contribution room of a registered
+// account type in a year from the deposits and withdrawals of its accounts.
+// TFSA room adds up the annual limits since the first year, with withdrawals
+// added back the year after. RRSP room is the deduction limit entered for a
+// year, less what was deposited since in the years after. FHSA room is the
+// annual limit plus up to a year of unused room carried forward, within the
+// lifetime limit.
+func ComputeContributionRoom(profile *models.Profile, accountType string, year int) (ContributionRoom, error) {
+    room := ContributionRoom{AccountType: accountType, Year: year, Known: true}
+    deposits := cashByYear(profile, accountType, models.TransactionDeposit)
+    withdrawals := cashByYear(profile, accountType, models.TransactionWithdrawal)
+    settings := profile.Settings.Contributions
+
+    switch accountType {
+    case models.AccountTFSA:
+        first := max(settings.TFSAFirstYear, tfsaFirstYear)
+        for y := first; y <= year; y++ {
+            room.Available += tfsaLimit(y)
+            if y < year {
+                room.Available += withdrawals[y] - deposits[y]
+            }
+        }
+
+    case models.AccountRRSP:
+        // Unused room carries forward. The room earned in later years
+        // isn't known until their limit is entered.
+        room.Known = settings.RRSPLimitYear != 0 && settings.RRSPLimitYear <= year
+        if room.Known {
+            room.Available = settings.RRSPDeductionLimit
+            for y := settings.RRSPLimitYear; y < year; y++ {
+                room.Available -= deposits[y]
+            }
+        }
+
+    case models.AccountFHSA:
+        var carried, lifetime float64
+        for y := fhsaOpeningYear(profile); y <= year; y++ {
+            room.Available = min(FHSAAnnualLimit+carried, FHSALifetimeLimit-lifetime)
+            if y < year {
+                carried = min(FHSACarryForwardLimit, max(room.Available-deposits[y], 0))
+                lifetime += deposits[y]
+            }
+        }
+        room.Available = max(room.Available, 0)
+
+    default:
+        return room, fmt.Errorf("%s accounts have no contribution room", accountType)
+    }
+
+    room.Contributed = deposits[year]
+    room.Withdrawn = withdrawals[year]
+    if room.Known {
+        room.Remaining = room.Available - room.Contributed
+    }
+    return room, nil
+}
+
+// fhsaOpeningYear returns the year the first FHSA was opened, as entered or
+// else as recorded in the profile
+func fhsaOpeningYear(profile *models.Profile) int {
+    if year := profile.Settings.Contributions.FHSAFirstYear; year != 0 {
+        return max(year, fhsaFirstYear)
+    }
+    year := math.MaxInt
+    for _, account := range profile.Accounts {
+        if account.Type != models.AccountFHSA {
+            continue
+        }
+        if !account.CreatedAt.IsZero() {
+            year = min(year, account.CreatedAt.Year())
+        }
+        for _, t := range account.Transactions {
+            year = min(year, t.Date.Year())
+        }
+    }
+    if year == math.MaxInt {
+        year = time.Now().Year()
+    }
+    return max(year, fhsaFirstYear)
+}
+
+// CheckDeposit reports the over-contribution a deposit in an account would
+// make, nil when it fits the room, the account isn't registered or its room
+// isn't known
+func CheckDeposit(profile *models.Profile, accountID string, amount float64, date time.Time) (*OverContribution, error) {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return nil, fmt.Errorf("no account %s", accountID)
+    }
+    accountType := profile.Accounts[a].Type
+    if !IsRegistered(accountType) {
+        return nil, nil
+    }
+    room, err := ComputeContributionRoom(profile, accountType, date.Year())
+    if err != nil || !room.Known {
+        return nil, err
+    }
+
+    excess := amount - room.Remaining
+    if excess <= 0 {
+        return nil, nil
+    }
+    over := &OverContribution{AccountType: accountType, Excess: excess, Taxable: excess}
+    if accountType == models.AccountRRSP {
+        over.Taxable = max(excess-rrspOverContributionAllowance, 0)
+    }
+    over.MonthlyPenalty = over.Taxable * overContributionPenaltyRate
+    over.Months = 12 - int(date.Month()) + 1
+    over.Penalty = over.MonthlyPenalty * float64(over.Months)
+    return over, nil
+}
@@ -0,0 +1,336 @@
+// File: internal/portfolio/contributions_test.go
+package portfolio
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// cashMovement is a cash transaction recorded in a test account
+type cashMovement struct {
+    accountID string
+    kind      string
+    amount    float64
+    date      string
+}
+
+// mustDate parses a YYYY-MM-DD date
+func mustDate(t *testing.T, text string) time.Time {
+    t.Helper()
+    date, err := time.Parse("2006-01-02", text)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return date
+}
+
+// closeTo reports whether two amounts are equal up to rounding
+func closeTo(a, b float64) bool {
+    return math.Abs(a-b) < 1e-6
+}
+
+// contributionProfile returns a profile with one account of each type, two
+// RRSPs, and the given cash movements
+func contributionProfile(t *testing.T, settings models.ContributionSettings, movements []cashMovement) *models.Profile {
+    t.Helper()
+    profile := &models.Profile{}
+    profile.Settings.Contributions = settings
+    for _, account := range []struct{ id, kind string }{
+        {"tfsa", models.AccountTFSA},
+        {"rrsp", models.AccountRRSP},
+        {"spousal", models.AccountRRSP},
+        {"fhsa", models.AccountFHSA},
+        {"cash", models.AccountNonRegistered},
+    } {
+        if err := AddAccount(profile, account.id, account.id, account.kind); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, m := range movements {
+        err := AddCashTransaction(profile, m.accountID, models.Transaction{Type: m.kind, Amount: m.amount, Date: mustDate(t, m.date)})
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+    return profile
+}
+
+func TestComputeContributionRoom(t *testing.T) {
+    tfsaSince2024 := models.ContributionSettings{TFSAFirstYear: 2024}
+    fhsaSince2023 := models.ContributionSettings{FHSAFirstYear: 2023}
+    rrspLimit := models.ContributionSettings{RRSPDeductionLimit: 31560, RRSPLimitYear: 2025}
+    withdrawal := []cashMovement{
+        {"tfsa", models.TransactionDeposit, 7000, "2024-02-01"},
+        {"tfsa", models.TransactionWithdrawal, 3000, "2024-06-01"},
+    }
+    rrspDeposits := []cashMovement{
+        {"rrsp", models.TransactionDeposit, 5000, "2024-03-01"},
+        {"rrsp", models.TransactionDeposit, 12000, "2025-03-01"},
+        {"spousal", models.TransactionDeposit, 8000, "2025-09-01"},
+        {"rrsp", models.TransactionDeposit, 4000, "2026-02-01"},
+    }
+    fhsaEveryYear := []cashMovement{
+        {"fhsa", models.TransactionDeposit, 8000, "2023-05-01"},
+        {"fhsa", models.TransactionDeposit, 8000, "2024-05-01"},
+        {"fhsa", models.TransactionDeposit, 8000, "2025-05-01"},
+        {"fhsa", models.TransactionDeposit, 8000, "2026-05-01"},
+        {"fhsa", models.TransactionDeposit, 5000, "2027-05-01"},
+    }
+
+    tests := []struct {
+        name        string
+        settings    models.ContributionSettings
+        movements   []cashMovement
+        accountType string
+        year        int
+        want        ContributionRoom
+    }{
+        {
+            name:        "tfsa limits add up since the first year",
+            settings:    models.ContributionSettings{TFSAFirstYear: 2009},
+            accountType: models.AccountTFSA,
+            year:        2026,
+            want:        ContributionRoom{Known: true, Available: 109000, Remaining: 109000},
+        },
+        {
+            name:        "tfsa room starts in 2009 at the earliest",
+            settings:    models.ContributionSettings{TFSAFirstYear: 2001},
+            accountType: models.AccountTFSA,
+            year:        2010,
+            want:        ContributionRoom{Known: true, Available: 10000, Remaining: 10000},
+        },
+        {
+            name:        "tfsa years after the table use its last limit",
+            settings:    models.ContributionSettings{TFSAFirstYear: 2026},
+            accountType: models.AccountTFSA,
+            year:        2027,
+            want:        ContributionRoom{Known: true, Available: 14000, Remaining: 14000},
+        },
+        {
+            name:        "tfsa withdrawal isn't re-added the same year",
+            settings:    tfsaSince2024,
+            movements:   withdrawal,
+            accountType: models.AccountTFSA,
+            year:        2024,
+            want:        ContributionRoom{Known: true, Available: 7000, Contributed: 7000, Withdrawn: 3000, Remaining: 0},
+        },
+        {
+            name:        "tfsa withdrawal is re-added the next year",
+            settings:    tfsaSince2024,
+            movements:   withdrawal,
+            accountType: models.AccountTFSA,
+            year:        2025,
+            want:        ContributionRoom{Known: true, Available: 10000, Remaining: 10000},
+        },
+        {
+            name:        "tfsa over-contribution is negative room",
+            settings:    models.ContributionSettings{TFSAFirstYear: 2026},
+            movements:   []cashMovement{{"tfsa", models.TransactionDeposit, 9000, "2026-01-10"}},
+            accountType: models.AccountTFSA,
+            year:        2026,
+            want:        ContributionRoom{Known: true, Available: 7000, Contributed: 9000, Remaining: -2000},
+        },
+        {
+            name:        "tfsa over-contribution carries into the next year",
+            settings:    models.ContributionSettings{TFSAFirstYear: 2025},
+            movements:   []cashMovement{{"tfsa", models.TransactionDeposit, 9000, "2025-01-10"}},
+            accountType: models.AccountTFSA,
+            year:        2026,
+            want:        ContributionRoom{Known: true, Available: 5000, Remaining: 5000},
+        },
+        {
+            name:        "rrsp limit is shared by all rrsps",
+            settings:    rrspLimit,
+            movements:   rrspDeposits,
+            accountType: models.AccountRRSP,
+            year:        2025,
+            want:        ContributionRoom{Known: true, Available: 31560, Contributed: 20000, Remaining: 11560},
+        },
+        {
+            name:        "rrsp unused room is carried forward",
+            settings:    rrspLimit,
+            movements:   rrspDeposits,
+            accountType: models.AccountRRSP,
+            year:        2026,
+            want:        ContributionRoom{Known: true, Available: 11560, Contributed: 4000, Remaining: 7560},
+        },
+        {
+            name:        "rrsp room before the limit year is unknown",
+            settings:    rrspLimit,
+            movements:   rrspDeposits,
+            accountType: models.AccountRRSP,
+            year:        2024,
+            want:        ContributionRoom{Known: false, Contributed: 5000},
+        },
+        {
+            name:        "rrsp room without a limit is unknown",
+            accountType: models.AccountRRSP,
+            year:        2025,
+            want:        ContributionRoom{Known: false},
+        },
+        {
+            name:        "fhsa annual limit",
+            settings:    fhsaSince2023,
+            accountType: models.AccountFHSA,
+            year:        2023,
+            want:        ContributionRoom{Known: true, Available: 8000, Remaining: 8000},
+        },
+        {
+            name:        "fhsa carries forward one year of unused room",
+            settings:    fhsaSince2023,
+            accountType: models.AccountFHSA,
+            year:        2024,
+            want:        ContributionRoom{Known: true, Available: 16000, Remaining: 16000},
+        },
+        {
+            name:        "fhsa carry-forward is capped",
+            settings:    fhsaSince2023,
+            accountType: models.AccountFHSA,
+            year:        2026,
+            want:        ContributionRoom{Known: true, Available: 16000, Remaining: 16000},
+        },
+        {
+            name:        "fhsa partial carry-forward",
+            settings:    fhsaSince2023,
+            movements:   []cashMovement{{"fhsa", models.TransactionDeposit, 5000, "2023-05-01"}},
+            accountType: models.AccountFHSA,
+            year:        2024,
+            want:        ContributionRoom{Known: true, Available: 11000, Remaining: 11000},
+        },
+        {
+            name:        "fhsa withdrawals don't restore room",
+            settings:    fhsaSince2023,
+            movements:   []cashMovement{
+                {"fhsa", models.TransactionDeposit, 8000, "2023-05-01"},
+                {"fhsa", models.TransactionWithdrawal, 8000, "2023-09-01"},
+            },
+            accountType: models.AccountFHSA,
+            year:        2024,
+            want:        ContributionRoom{Known: true, Available: 8000, Remaining: 8000},
+        },
+        {
+            name:        "fhsa lifetime limit",
+            settings:    fhsaSince2023,
+            movements:   fhsaEveryYear,
+            accountType: models.AccountFHSA,
+            year:        2028,
+            want:        ContributionRoom{Known: true, Available: 3000, Remaining: 3000},
+        },
+        {
+            name:        "fhsa lifetime limit reached",
+            settings:    fhsaSince2023,
+            movements:   append(fhsaEveryYear, cashMovement{"fhsa", models.TransactionDeposit, 3000, "2028-05-01"}),
+            accountType: models.AccountFHSA,
+            year:        2029,
+            want:        ContributionRoom{Known: true, Available: 0, Remaining: 0},
+        },
+        {
+            name:        "fhsa over-contribution",
+            settings:    fhsaSince2023,
+            movements:   []cashMovement{{"fhsa", models.TransactionDeposit, 10000, "2023-05-01"}},
+            accountType: models.AccountFHSA,
+            year:        2023,
+            want:        ContributionRoom{Known: true, Available: 8000, Contributed: 10000, Remaining: -2000},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            profile := contributionProfile(t, tt.settings, tt.movements)
+            got, err := ComputeContributionRoom(profile, tt.accountType, tt.year)
+            if err != nil {
+                t.Fatalf("ComputeContributionRoom() error = %v", err)
+            }
+            want := tt.want
+            want.AccountType, want.Year = tt.accountType, tt.year
+            if got.Known != want.Known || !closeTo(got.Available, want.Available) || !closeTo(got.Contributed, want.Contributed) ||
+                !closeTo(got.Withdrawn, want.Withdrawn) || !closeTo(got.Remaining, want.Remaining) {
+                t.Errorf("ComputeContributionRoom() = %+v, want %+v", got, want)
+            }
+        })
+    }
+}
+
+func TestComputeContributionRoomRejectsNonRegistered(t *testing.T) {
+    profile := contributionProfile(t, models.ContributionSettings{}, nil)
+    if _, err := ComputeContributionRoom(profile, models.AccountNonRegistered, 2025); err == nil {
+        t.Error("ComputeContributionRoom() of a non-registered account succeeded")
+    }
+}
+
+func TestCheckDeposit(t *testing.T) {
+    settings := models.ContributionSettings{TFSAFirstYear: 2026, RRSPDeductionLimit: 10000, RRSPLimitYear: 2026, FHSAFirstYear: 2026}
+    movements := []cashMovement{{"tfsa", models.TransactionDeposit, 5000, "2026-01-10"}}
+
+    tests := []struct {
+        name      string
+        accountID string
+        amount    float64
+        date      string
+        want      *OverContribution
+    }{
+        {
+            name:      "within the tfsa room",
+            accountID: "tfsa",
+            amount:    2000,
+            date:      "2026-10-16",
+        },
+        {
+            name:      "over the tfsa room",
+            accountID: "tfsa",
+            amount:    4000,
+            date:      "2026-10-16",
+            want:      &OverContribution{AccountType: models.AccountTFSA, Excess: 2000, Taxable: 2000, MonthlyPenalty: 20, Months: 3, Penalty: 60},
+        },
+        {
+            name:      "rrsp excess within the allowance",
+            accountID: "rrsp",
+            amount:    11500,
+            date:      "2026-10-16",
+            want:      &OverContribution{AccountType: models.AccountRRSP, Excess: 1500, Taxable: 0, Months: 3},
+        },
+        {
+            name:      "rrsp excess beyond the allowance",
+            accountID: "spousal",
+            amount:    13000,
+            date:      "2026-01-15",
+            want:      &OverContribution{AccountType: models.AccountRRSP, Excess: 3000, Taxable: 1000, MonthlyPenalty: 10, Months: 12, Penalty: 120},
+        },
+        {
+            name:      "over the fhsa room",
+            accountID: "fhsa",
+            amount:    9000,
+            date:      "2026-12-01",
+            want:      &OverContribution{AccountType: models.AccountFHSA, Excess: 1000, Taxable: 1000, MonthlyPenalty: 10, Months: 1, Penalty: 10},
+        },
+        {
+            name:      "non-registered account",
+            accountID: "cash",
+            amount:    1000000,
+            date:      "2026-10-16",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            profile := contributionProfile(t, settings, movements)
+            got, err := CheckDeposit(profile, tt.accountID, tt.amount, mustDate(t, tt.date))
+            if err != nil {
+                t.Fatalf("CheckDeposit() error = %v", err)
+            }
+            if tt.want == nil || got == nil {
+                if tt.want != got {
+                    t.Errorf("CheckDeposit() = %+v, want %+v", got, tt.want)
+                }
+                return
+            }
+            if got.AccountType != tt.want.AccountType || !closeTo(got.Excess, tt.want.Excess) || !closeTo(got.Taxable, tt.want.Taxable) ||
+                !closeTo(got.MonthlyPenalty, tt.want.MonthlyPenalty) || got.Months != tt.want.Months || !closeTo(got.Penalty, tt.want.Penalty) {
+                t.Errorf("CheckDeposit() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,142 @@
+// File: internal/portfolio/ledger.go
+package portfolio
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "slices"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// quantityEpsilon absorbs the rounding of fractional share quantities
+const quantityEpsilon = 1e-9
+
+var (
+    // ErrNegativeQuantity is returned for a sell of more shares than are held
+    ErrNegativeQuantity = errors.New("sells more shares than are held")
+
+    // ErrInvalidTransaction is returned for a transaction that can't be replayed
+    ErrInvalidTransaction = errors.New("invalid transaction")
+)
+
+// TransactionError is a transaction of a position that can't be replayed
+type TransactionError struct {
+    AccountID     string
+    Symbol        string
+    TransactionID string
+    Err           error
+}
+
+func (e *TransactionError) Error() string {
+    return fmt.Sprintf("%s in account %s, transaction %s: %v", e.Symbol, e.AccountID, e.TransactionID, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+    return e.Err
+}
+
+// Entry is a transaction of a ledger with the position it leaves
+type Entry struct {
+    Transaction models.Transaction
+    Quantity    float64 // shares held afterwards
+    AverageCost float64 // cost per share held afterwards, commissions included
+    Realized    float64 // gain or loss realized by a sell or a return of capital
+}
+
+// Ledger is a position derived from its transactions
+type Ledger struct {
+    AccountID   string
+    Symbol      string
+    Quantity    float64
+    AverageCost float64 // cost per share, buy commissions included
+    CostBasis   float64 // cost of the shares held
+    Realized    float64 // gains and losses of the sells, net of their commissions, and of returns of capital beyond the cost
+    Commissions float64
+    Entries     []Entry // in the order they were replayed
+}
+
+// SortTransactions returns transactions in the order they are replayed: by
+// date, then in the order they were entered
+func SortTransactions(transactions []models.Transaction) []models.Transaction {
+    sorted := slices.Clone(transactions)
+    slices.SortStableFunc(sorted, func(a, b models.Transaction) int {
+        return a.Date.Compare(b.Date)
+    })
+    return sorted
+}
+
+// ReplayPosition derives a position from its transactions. It fails with a
+// TransactionError on the first transaction that can't be applied, such as a
+// sell leaving a negative quantity.
+func ReplayPosition(accountID string, position models.Position) (*Ledger, error) {
+    ledger := &Ledger{AccountID: accountID, Symbol: position.StockSymbol}
+    for _, t := range SortTransactions(position.Transactions) {
+        realized, err := ledger.apply(t)
+        if err != nil {
+            return ledger, &TransactionError{AccountID: accountID, Symbol: position.StockSymbol, TransactionID: t.ID, Err: err}
+        }
+        ledger.Entries = append(ledger.Entries, Entry{
+            Transaction: t,
+            Quantity:    ledger.Quantity,
+            AverageCost: ledger.AverageCost,
+            Realized:    realized,
+        })
+    }
+    return ledger, nil
+}
+
+// apply updates the ledger with a transaction and returns the gain or loss it realized
+func (l *Ledger) apply(t models.Transaction) (float64, error) {
+    if t.Quantity <= 0 || t.Price < 0 || t.Commission < 0 {
+        return 0, fmt.Errorf("%w: quantity must be positive, price and commission can't be negative", ErrInvalidTransaction)
+    }
+
+    var realized float64
+    switch t.Type {
+    case models.TransactionBuy, models.TransactionReinvest:
+        l.Quantity += t.Quantity
+        l.CostBasis += t.Quantity*t.Price + t.Commission
+        l.Commissions += t.Commission
+
+    case models.TransactionSell:
+        if t.Quantity > l.Quantity+quantityEpsilon {
+            return 0, fmt.Errorf("%w: sells %g of %g", ErrNegativeQuantity, t.Quantity, l.Quantity)
+        }
+        sold := l.AverageCost * t.Quantity
+        realized = t.Quantity*t.Price - t.Commission - sold
+        l.Realized += realized
+        l.Commissions += t.Commission
+        l.Quantity -= t.Quantity
+        l.CostBasis -= sold
+
+    case models.TransactionSplit:
+        // The cost is spread over more or fewer shares
+        l.Quantity *= t.Quantity
+        l.CostBasis += t.Commission
+        l.Commissions += t.Commission
+
+    case models.TransactionReturnOfCapital:
+        // Capital returned beyond the cost of the shares is a gain
+        l.CostBasis -= t.Quantity * t.Price
+        if l.CostBasis < 0 {
+            realized = -l.CostBasis
+            l.Realized += realized
+            l.CostBasis = 0
+        }
+
+    default:
+        return 0, fmt.Errorf("%w: unknown type %q", ErrInvalidTransaction, t.Type)
+    }
+
+    // Selling everything leaves no cost behind, only rounding
+    if math.Abs(l.Quantity) < quantityEpsilon {
+        l.Quantity, l.CostBasis = 0, 0
+    }
+    l.AverageCost = 0
+    if l.Quantity > 0 {
+        l.AverageCost = l.CostBasis / l.Quantity
+    }
+    return realized, nil
+}
@@ -0,0 +1,230 @@
+// File: internal/portfolio/ledger_test.go
+package portfolio
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// trade returns a transaction of a position
+func trade(t *testing.T, id, kind string, quantity, price, commission float64, date string) models.Transaction {
+    t.Helper()
+    return models.Transaction{ID: id, Type: kind, Quantity: quantity, Price: price, Commission: commission, Date: mustDate(t, date)}
+}
+
+func TestReplayPosition(t *testing.T) {
+    tests := []struct {
+        name         string
+        transactions func(t *testing.T) []models.Transaction
+        quantity     float64
+        averageCost  float64
+        costBasis    float64
+        realized     float64
+        commissions  float64
+        entries      []float64 // realized by each entry, in replay order
+    }{
+        {
+            name: "buys with commissions",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 100, 5, "2024-01-02"),
+                    trade(t, "b2", models.TransactionBuy, 10, 110, 5, "2024-02-02"),
+                }
+            },
+            quantity:    20,
+            averageCost: 105.5,
+            costBasis:   2110,
+            commissions: 10,
+            entries:     []float64{0, 0},
+        },
+        {
+            name: "partial sell",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 100, 5, "2024-01-02"),
+                    trade(t, "b2", models.TransactionBuy, 10, 110, 5, "2024-02-02"),
+                    trade(t, "s1", models.TransactionSell, 5, 120, 10, "2024-03-02"),
+                }
+            },
+            quantity:    15,
+            averageCost: 105.5,
+            costBasis:   1582.5,
+            realized:    62.5,
+            commissions: 20,
+            entries:     []float64{0, 0, 62.5},
+        },
+        {
+            name: "selling everything leaves no cost",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 3, 10, 0, "2024-01-02"),
+                    trade(t, "s1", models.TransactionSell, 3, 8, 0, "2024-03-02"),
+                }
+            },
+            realized: -6,
+            entries:  []float64{0, -6},
+        },
+        {
+            name: "split",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 100, 0, "2024-01-02"),
+                    trade(t, "x1", models.TransactionSplit, 2, 0, 0, "2024-02-02"),
+                }
+            },
+            quantity:    20,
+            averageCost: 50,
+            costBasis:   1000,
+            entries:     []float64{0, 0},
+        },
+        {
+            name: "return of capital lowers the cost",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-02"),
+                    trade(t, "r1", models.TransactionReturnOfCapital, 10, 1.5, 0, "2024-02-02"),
+                }
+            },
+            quantity:    10,
+            averageCost: 8.5,
+            costBasis:   85,
+            entries:     []float64{0, 0},
+        },
+        {
+            name: "return of capital beyond the cost is a gain",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-02"),
+                    trade(t, "r1", models.TransactionReturnOfCapital, 10, 12, 0, "2024-02-02"),
+                    trade(t, "b2", models.TransactionBuy, 10, 10, 0, "2024-03-02"),
+                }
+            },
+            quantity:    20,
+            averageCost: 5,
+            costBasis:   100,
+            realized:    20,
+            entries:     []float64{0, 20, 0},
+        },
+        {
+            name: "reinvested distribution buys shares",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-02"),
+                    trade(t, "d1", models.TransactionReinvest, 1, 12, 0, "2024-02-02"),
+                }
+            },
+            quantity:    11,
+            averageCost: 112.0 / 11,
+            costBasis:   112,
+            entries:     []float64{0, 0},
+        },
+        {
+            name: "sells out of date order",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "s1", models.TransactionSell, 5, 20, 0, "2024-03-02"),
+                    trade(t, "b2", models.TransactionBuy, 10, 16, 0, "2024-02-02"),
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-02"),
+                }
+            },
+            quantity:    15,
+            averageCost: 13,
+            costBasis:   195,
+            realized:    35,
+            entries:     []float64{0, 0, 35},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            position := models.Position{StockSymbol: "XEQT", Transactions: tt.transactions(t)}
+            ledger, err := ReplayPosition("account", position)
+            if err != nil {
+                t.Fatalf("ReplayPosition() error = %v", err)
+            }
+            if !closeTo(ledger.Quantity, tt.quantity) || !closeTo(ledger.AverageCost, tt.averageCost) || !closeTo(ledger.CostBasis, tt.costBasis) ||
+                !closeTo(ledger.Realized, tt.realized) || !closeTo(ledger.Commissions, tt.commissions) {
+                t.Errorf("ReplayPosition() = %g shares at %g (cost %g), realized %g, commissions %g; want %g shares at %g (cost %g), realized %g, commissions %g",
+                    ledger.Quantity, ledger.AverageCost, ledger.CostBasis, ledger.Realized, ledger.Commissions,
+                    tt.quantity, tt.averageCost, tt.costBasis, tt.realized, tt.commissions)
+            }
+            if len(ledger.Entries) != len(tt.entries) {
+                t.Fatalf("ReplayPosition() has %d entries, want %d", len(ledger.Entries), len(tt.entries))
+            }
+            for i, entry := range ledger.Entries {
+                if !closeTo(entry.Realized, tt.entries[i]) {
+                    t.Errorf("entry %d (%s) realized %g, want %g", i, entry.Transaction.ID, entry.Realized, tt.entries[i])
+                }
+            }
+        })
+    }
+}
+
+func TestReplayPositionErrors(t *testing.T) {
+    tests := []struct {
+        name          string
+        transactions  func(t *testing.T) []models.Transaction
+        transactionID string
+        err           error
+        entries       int // replayed before the error
+    }{
+        {
+            name: "selling more shares than are held",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-01-02"),
+                    trade(t, "s1", models.TransactionSell, 11, 10, 0, "2024-02-02"),
+                }
+            },
+            transactionID: "s1",
+            err:           ErrNegativeQuantity,
+            entries:       1,
+        },
+        {
+            name: "selling before the buy",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{
+                    trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-03-02"),
+                    trade(t, "s1", models.TransactionSell, 10, 10, 0, "2024-02-02"),
+                }
+            },
+            transactionID: "s1",
+            err:           ErrNegativeQuantity,
+        },
+        {
+            name: "negative price",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{trade(t, "b1", models.TransactionBuy, 10, -1, 0, "2024-01-02")}
+            },
+            transactionID: "b1",
+            err:           ErrInvalidTransaction,
+        },
+        {
+            name: "cash movement in a position",
+            transactions: func(t *testing.T) []models.Transaction {
+                return []models.Transaction{trade(t, "d1", models.TransactionDeposit, 1, 1, 0, "2024-01-02")}
+            },
+            transactionID: "d1",
+            err:           ErrInvalidTransaction,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            position := models.Position{StockSymbol: "XEQT", Transactions: tt.transactions(t)}
+            ledger, err := ReplayPosition("account", position)
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("ReplayPosition() error = %v, want %v", err, tt.err)
+            }
+            var txErr *TransactionError
+            if !errors.As(err, &txErr) || txErr.TransactionID != tt.transactionID || txErr.AccountID != "account" || txErr.Symbol != "XEQT" {
+                t.Errorf("ReplayPosition() error = %#v, want a TransactionError for %s", err, tt.transactionID)
+            }
+            if len(ledger.Entries) != tt.entries {
+                t.Errorf("ReplayPosition() replayed %d entries before failing, want %d", len(ledger.Entries), tt.entries)
+            }
+        })
+    }
+}
@@ -0,0 +1,289 @@
+// File: internal/portfolio/positions.go
+package portfolio
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "slices"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// costEpsilon is how far a stored average cost may be from the derived one
+const costEpsilon = 0.005
+
+// openingBalanceNote marks the transactions standing in for positions and
+// cash entered before they had transactions
+const openingBalanceNote = "Opening balance"
+
+// Issue is a disagreement between a stored position or cash balance and its transactions
+type Issue struct {
+    AccountID     string
+    Symbol        string // empty when the issue is with the cash of the account
+    TransactionID string // empty when the issue is with the position itself
+    Problem       string
+}
+
+// CheckProfile replays the transactions of every position of a profile and
+// reports the positions whose stored quantity or average cost don't match,
+// the accounts whose stored cash balance doesn't, as well as the transactions
+// that can't be replayed. Cash balances are only checked once the profile has
+// been migrated to derived balances.
+func CheckProfile(profile *models.Profile) []Issue {
+    var issues []Issue
+    for _, account := range profile.Accounts {
+        derived := CashBalances(account, time.Time{})[DefaultCurrency]
+        if profile.SchemaVersion >= cashSchemaVersion && math.Abs(derived-account.Balance) >= balanceEpsilon {
+            issues = append(issues, Issue{AccountID: account.ID, Problem: fmt.Sprintf("stores a cash balance of %.2f, its transactions give %.2f", account.Balance, derived)})
+        }
+        for _, position := range account.Positions {
+            issue := Issue{AccountID: account.ID, Symbol: position.StockSymbol}
+
+            seen := make(map[string]bool)
+            for _, t := range position.Transactions {
+                if seen[t.ID] {
+                    issues = append(issues, Issue{account.ID, position.StockSymbol, t.ID, "the transaction ID is used more than once"})
+                }
+                seen[t.ID] = true
+            }
+
+            if len(position.Transactions) == 0 {
+                if position.Quantity != 0 {
+                    issue.Problem = fmt.Sprintf("holds %g shares without any transaction", position.Quantity)
+                    issues = append(issues, issue)
+                }
+                continue
+            }
+
+            ledger, err := ReplayPosition(account.ID, position)
+            var txErr *TransactionError
+            if errors.As(err, &txErr) {
+                issue.TransactionID = txErr.TransactionID
+                issue.Problem = txErr.Err.Error()
+                issues = append(issues, issue)
+                continue
+            }
+
+            if math.Abs(ledger.Quantity-position.Quantity) > quantityEpsilon {
+                issue.Problem = fmt.Sprintf("stores %g shares, its transactions add up to %g", position.Quantity, ledger.Quantity)
+                issues = append(issues, issue)
+            } else if math.Abs(ledger.AverageCost-position.AverageCost) > costEpsilon {
+                issue.Problem = fmt.Sprintf("stores an average cost of %.4f, its transactions give %.4f", position.AverageCost, ledger.AverageCost)
+                issues = append(issues, issue)
+            }
+        }
+    }
+    return issues
+}
+
+// DeriveProfile sets the quantity and average cost of every position of a
+// profile, and the cash balance of every account, from their transactions.
+// Positions entered without transactions get an opening buy, and a deposit
+// paying for it, so their holdings and cash are kept. The profile is left
+// unchanged when a position has transactions that can't be replayed.
+func DeriveProfile(profile *models.Profile) error {
+    type derived struct {
+        account, position int
+        transactions      []models.Transaction
+        ledger            *Ledger
+    }
+    var results []derived
+    opened := make(map[string]bool)
+    funding := make(map[int][]models.Transaction) // deposits paying for the opening buys, by account
+
+    for i, account := range profile.Accounts {
+        for k, position := range account.Positions {
+            if len(position.Transactions) == 0 && position.Quantity > 0 {
+                opening := openingBalance(profile, account, position)
+                for opened[opening.ID] {
+                    opening.ID = NewTransactionID(profile)
+                }
+                opened[opening.ID] = true
+                position.Transactions = []models.Transaction{opening}
+                if deposit := openingFunding(opening); deposit.Amount > 0 {
+                    funding[i] = append(funding[i], deposit)
+                }
+            }
+            ledger, err := ReplayPosition(account.ID, position)
+            if err != nil {
+                return err
+            }
+            results = append(results, derived{i, k, position.Transactions, ledger})
+        }
+    }
+
+    for _, r := range results {
+        position := &profile.Accounts[r.account].Positions[r.position]
+        position.Transactions = r.transactions
+        position.Quantity = r.ledger.Quantity
+        position.AverageCost = r.ledger.AverageCost
+    }
+    for i := range profile.Accounts {
+        account := &profile.Accounts[i]
+        account.Transactions = append(account.Transactions, funding[i]...)
+        deriveBalance(profile, account)
+    }
+    return nil
+}
+
+// openingBalance returns a buy of the shares a position held before it had transactions
+func openingBalance(profile *models.Profile, account models.Account, position models.Position) models.Transaction {
+    date := account.CreatedAt
+    if date.IsZero() {
+        date = profile.CreatedAt
+    }
+    return models.Transaction{
+        ID:       NewTransactionID(profile),
+        Type:     models.TransactionBuy,
+        Quantity: position.Quantity,
+        Price:    position.AverageCost,
+        Date:     date,
+        Notes:    openingBalanceNote,
+    }
+}
+
+// openingFunding returns a deposit of the cash an opening buy costs, so
+// opening a position leaves the cash of its account unchanged
+func openingFunding(opening models.Transaction) models.Transaction {
+    return models.Transaction{
+        ID:       opening.ID + "_funding",
+        Type:     models.TransactionDeposit,
+        Amount:   opening.Quantity*opening.Price + opening.Commission,
+        Currency: opening.Currency,
+        Date:     opening.Date,
+        Notes:    openingBalanceNote,
+    }
+}
+
+// NewTransactionID returns an ID not used by any transaction of a profile
+func NewTransactionID(profile *models.Profile) string {
+    used := make(map[string]bool)
+    for _, account := range profile.Accounts {
+        for _, t := range account.Transactions {
+            used[t.ID] = true
+        }
+        for _, position := range account.Positions {
+            for _, t := range position.Transactions {
+                used[t.ID] = true
+            }
+        }
+    }
+    for n := time.Now().UnixNano(); ; n++ {
+        if id := fmt.Sprintf("transaction_%d", n); !used[id] {
+            return id
+        }
+    }
+}
+
+// AddTransaction records a transaction in a position of an account, creating
+// the position if needed, and derives the position again. The profile is left
+// unchanged when the transaction can't be replayed, such as a sell of more
+// shares than are held at its date.
+func AddTransaction(profile *models.Profile, accountID, symbol string, t models.Transaction) error {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return fmt.Errorf("no account %s", accountID)
+    }
+    account := &profile.Accounts[a]
+    if t.ID == "" {
+        t.ID = NewTransactionID(profile)
+    } else if _, _, found := FindTransaction(profile, t.ID); found {
+        return fmt.Errorf("%w: the ID %s is already used", ErrInvalidTransaction, t.ID)
+    }
+
+    p := -1
+    for i := range account.Positions {
+        if account.Positions[i].StockSymbol == symbol {
+            p = i
+            break
+        }
+    }
+    position := models.Position{StockSymbol: symbol}
+    if p >= 0 {
+        position = account.Positions[p]
+    }
+    position.Transactions = append(slices.Clip(position.Transactions), t)
+
+    ledger, err := ReplayPosition(accountID, position)
+    if err != nil {
+        return err
+    }
+    position.Quantity = ledger.Quantity
+    position.AverageCost = ledger.AverageCost
+
+    if p >= 0 {
+        account.Positions[p] = position
+    } else {
+        account.Positions = append(account.Positions, position)
+    }
+    deriveBalance(profile, account)
+    account.LastUpdated = time.Now()
+    return nil
+}
+
+// RemoveTransaction deletes a transaction and derives its position again. It
+// fails, leaving the profile unchanged, when later sells would then sell more
+// shares than are held.
+func RemoveTransaction(profile *models.Profile, accountID, symbol, transactionID string) error {
+    a := findAccount(profile, accountID)
+    if a < 0 {
+        return fmt.Errorf("no account %s", accountID)
+    }
+    account := &profile.Accounts[a]
+    for i, position := range account.Positions {
+        if position.StockSymbol != symbol {
+            continue
+        }
+
+        var kept []models.Transaction
+        for _, t := range position.Transactions {
+            if t.ID != transactionID {
+                kept = append(kept, t)
+            }
+        }
+        position.Transactions = kept
+        ledger, err := ReplayPosition(accountID, position)
+        if err != nil {
+            return err
+        }
+        position.Quantity = ledger.Quantity
+        position.AverageCost = ledger.AverageCost
+        account.Positions[i] = position
+        deriveBalance(profile, account)
+        account.LastUpdated = time.Now()
+        return nil
+    }
+    return fmt.Errorf("no position in %s in account %s", symbol, accountID)
+}
+
+// FindTransaction returns the account and position holding a transaction, the
+// symbol being empty for a cash movement
+func FindTransaction(profile *models.Profile, transactionID string) (accountID, symbol string, found bool) {
+    for _, account := range profile.Accounts {
+        for _, t := range account.Transactions {
+            if t.ID == transactionID {
+                return account.ID, "", true
+            }
+        }
+        for _, position := range account.Positions {
+            for _, t := range position.Transactions {
+                if t.ID == transactionID {
+                    return account.ID, position.StockSymbol, true
+                }
+            }
+        }
+    }
+    return "", "", false
+}
+
+// findAccount returns the index of an account of a profile, -1 if there is none
+func findAccount(profile *models.Profile, accountID string) int {
+    for i := range profile.Accounts {
+        if profile.Accounts[i].ID == accountID {
+            return i
+        }
+    }
+    return -1
+}
@@ -0,0 +1,131 @@
+// File: internal/portfolio/superficial.go
+package portfolio
+
+import (
+    "slices"
+    "time"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// superficialWindowDays is how many days before and after a sale at a loss
+// the purchase of identical shares makes the loss superficial
+const superficialWindowDays = 30
+
+// SuperficialLoss is a capital loss denied because identical shares were
+// bought within 30 days of its sale, in any account, and still held 30 days
+// after it
+type SuperficialLoss struct {
+    AccountID     string
+    Symbol        string
+    TransactionID string   // the sell
+    Loss          float64  // loss of the sell before the denial
+    Denied        float64  // part of the loss that can't be claimed
+    Added         float64  // part of the denied loss added to the ACB of replacement shares in taxable accounts
+    Replacements  []string // IDs of the purchases within the window
+}
+
+// acquisition is a purchase of shares, in any account
+type acquisition struct {
+    accountID   string
+    symbol      string
+    registered  bool
+    transaction models.Transaction
+}
+
+// acquisitions returns the purchases of every account of a profile
+func acquisitions(profile *models.Profile) []acquisition {
+    var bought []acquisition
+    for _, account := range profile.Accounts {
+        for _, position := range account.Positions {
+            for _, t := range position.Transactions {
+                if t.Type == models.TransactionBuy || t.Type == models.TransactionReinvest {
+                    bought = append(bought, acquisition{account.ID, position.StockSymbol, IsRegistered(account.Type), t})
+                }
+            }
+        }
+    }
+    return bought
+}
+
+// superficialWindow returns the first and last day of the period around a sale
+func superficialWindow(sold time.Time) (from, to time.Time) {
+    day := time.Date(sold.Year(), sold.Month(), sold.Day(), 0, 0, 0, 0, sold.Location())
+    return day.AddDate(0, 0, -superficialWindowDays), day.AddDate(0, 0, superficialWindowDays+1)
+}
+
+// sharesHeldAt returns the shares of a security held across every account of
+// a profile at the end of a day
+func sharesHeldAt(profile *models.Profile, symbol string, end time.Time) float64 {
+    var held float64
+    for _, account := range profile.Accounts {
+        for _, position := range account.Positions {
+            if position.StockSymbol != symbol {
+                continue
+            }
+            ledger := &Ledger{}
+            for _, t := range SortTransactions(position.Transactions) {
+                if !t.Date.Before(end) {
+                    break
+                }
+                if _, err := ledger.apply(t); err != nil {
+                    break
+                }
+            }
+            held += ledger.Quantity
+        }
+    }
+    return held
+}
+
+// superficialLoss works out how much of the loss of a sell is denied. The
+// denied part is the loss times the fewest of the shares sold, the shares
+// bought within 30 days of the sale and the shares held 30 days after it,
+// over the shares sold. Of the denied part, the share bought in taxable
+// accounts is added to the ACB of the replacement shares; the share bought in
+// registered accounts is lost for good.
+func superficialLoss(profile *models.Profile, bought []acquisition, sale ACBEvent, loss float64) (SuperficialLoss, bool) {
+    from, to := superficialWindow(sale.Transaction.Date)
+    result := SuperficialLoss{AccountID: sale.AccountID, Symbol: sale.Symbol, TransactionID: sale.Transaction.ID, Loss: loss}
+
+    var purchased, taxable float64
+    for _, a := range bought {
+        date := a.transaction.Date
+        if a.symbol != sale.Symbol || date.Before(from) || !date.Before(to) {
+            continue
+        }
+        purchased += a.transaction.Quantity
+        if !a.registered {
+            taxable += a.transaction.Quantity
+        }
+        result.Replacements = append(result.Replacements, a.transaction.ID)
+    }
+    held := sharesHeldAt(profile, sale.Symbol, to)
+    replaced := min(sale.Transaction.Quantity, purchased, held)
+    if replaced <= quantityEpsilon {
+        return SuperficialLoss{}, false
+    }
+
+    result.Denied = loss * replaced / sale.Transaction.Quantity
+    result.Added = result.Denied * taxable / purchased
+    return result, true
+}
+
+// SuperficialLossFor returns the superficial loss of a sell, or the one a
+// purchase replaced shares for
+func (r *ACBReport) SuperficialLossFor(transactionID string) (SuperficialLoss, bool) {
+    for _, loss := range r.SuperficialLosses {
+        if loss.TransactionID == transactionID || slices.Contains(loss.Replacements, transactionID) {
+            return loss, true
+        }
+    }
+    return SuperficialLoss{}, false
+}
+
+// addCost adds to the cost of the shares held, such as a denied loss
+func (l *Ledger) addCost(amount float64) {
+    l.CostBasis += amount
+    if l.Quantity > 0 {
+        l.AverageCost = l.CostBasis / l.Quantity
+    }
+}
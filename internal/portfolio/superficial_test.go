@@ -0,0 +1,208 @@
+// File: internal/portfolio/superficial_test.go
+package portfolio
+
+import (
+    "slices"
+    "testing"
+
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+func TestSuperficialLoss(t *testing.T) {
+    // Every case sells s1 on 2024-06-15: its window runs from 2024-05-16 to
+    // 2024-07-15, both included
+    bought := trade(t, "b0", models.TransactionBuy, 100, 10, 0, "2024-01-10")
+    cash := func(transactions ...models.Transaction) holding {
+        return holding{"cash", models.AccountNonRegistered, "XEQT", transactions}
+    }
+    tfsa := func(transactions ...models.Transaction) holding {
+        return holding{"tfsa", models.AccountTFSA, "XEQT", transactions}
+    }
+
+    tests := []struct {
+        name         string
+        holdings     []holding
+        gain         float64 // of s1, the denied loss left out
+        denied       float64
+        added        float64
+        replacements []string // none when the loss isn't superficial
+        acb          float64  // of the pool at the end
+    }{
+        {
+            name:     "no replacement purchase",
+            holdings: []holding{cash(bought, trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"))},
+            gain:     -200,
+        },
+        {
+            name: "replacement in a registered account is denied but not added",
+            holdings: []holding{
+                cash(bought, trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15")),
+                tfsa(trade(t, "b1", models.TransactionBuy, 50, 8, 0, "2024-06-20")),
+            },
+            gain:         -100,
+            denied:       100,
+            replacements: []string{"b1"},
+        },
+        {
+            name: "partial replacement",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "b1", models.TransactionBuy, 20, 9, 0, "2024-06-03"),
+                trade(t, "s1", models.TransactionSell, 50, 8, 0, "2024-06-15"),
+            )},
+            gain:         -55,
+            denied:       110.0 / 3,
+            added:        110.0 / 3,
+            replacements: []string{"b1"},
+            acb:          725,
+        },
+        {
+            name: "replacement shares are prorated between accounts",
+            holdings: []holding{
+                cash(
+                    bought,
+                    trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+                    trade(t, "b2", models.TransactionBuy, 20, 8, 0, "2024-06-25"),
+                ),
+                tfsa(trade(t, "b1", models.TransactionBuy, 60, 8, 0, "2024-06-20")),
+            },
+            gain:         -40,
+            denied:       160,
+            added:        40,
+            replacements: []string{"b2", "b1"},
+            acb:          200,
+        },
+        {
+            name: "repurchase after selling everything carries the loss",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+                trade(t, "b1", models.TransactionBuy, 100, 8, 0, "2024-07-01"),
+            )},
+            denied:       200,
+            added:        200,
+            replacements: []string{"b1"},
+            acb:          1000,
+        },
+        {
+            name: "purchase 30 days before",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-05-16"),
+                trade(t, "s1", models.TransactionSell, 10, 8, 0, "2024-06-15"),
+            )},
+            denied:       20,
+            added:        20,
+            replacements: []string{"b1"},
+            acb:          1020,
+        },
+        {
+            name: "purchase 31 days before",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "b1", models.TransactionBuy, 10, 10, 0, "2024-05-15"),
+                trade(t, "s1", models.TransactionSell, 10, 8, 0, "2024-06-15"),
+            )},
+            gain: -20,
+            acb:  1000,
+        },
+        {
+            name: "purchase 30 days after",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+                trade(t, "b1", models.TransactionBuy, 100, 10, 0, "2024-07-15"),
+            )},
+            denied:       200,
+            added:        200,
+            replacements: []string{"b1"},
+            acb:          1200,
+        },
+        {
+            name: "purchase 31 days after",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+                trade(t, "b1", models.TransactionBuy, 100, 10, 0, "2024-07-16"),
+            )},
+            gain: -200,
+            acb:  1000,
+        },
+        {
+            name: "replacement sold before the window ends",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+                trade(t, "b1", models.TransactionBuy, 100, 8, 0, "2024-06-20"),
+                trade(t, "s2", models.TransactionSell, 100, 9, 0, "2024-07-01"),
+            )},
+            gain: -200,
+        },
+        {
+            name: "shares of the sold lot bought within the window and still held",
+            holdings: []holding{cash(
+                trade(t, "b0", models.TransactionBuy, 100, 10, 0, "2024-06-05"),
+                trade(t, "s1", models.TransactionSell, 50, 8, 0, "2024-06-15"),
+            )},
+            denied:       100,
+            added:        100,
+            replacements: []string{"b0"},
+            acb:          600,
+        },
+        {
+            name: "shares of the sold lot bought within the window and all sold",
+            holdings: []holding{cash(
+                trade(t, "b0", models.TransactionBuy, 100, 10, 0, "2024-06-05"),
+                trade(t, "s1", models.TransactionSell, 100, 8, 0, "2024-06-15"),
+            )},
+            gain: -200,
+        },
+        {
+            // denied = loss × min(S, P, B) / S = 120 × min(60, 30, 70) / 60
+            name: "fewest of the shares sold, bought and held",
+            holdings: []holding{cash(
+                bought,
+                trade(t, "b1", models.TransactionBuy, 30, 10, 0, "2024-06-05"),
+                trade(t, "s1", models.TransactionSell, 60, 8, 0, "2024-06-15"),
+            )},
+            gain:         -60,
+            denied:       60,
+            added:        60,
+            replacements: []string{"b1"},
+            acb:          760,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            report, err := ComputeACB(holdingsProfile(t, tt.holdings))
+            if err != nil {
+                t.Fatalf("ComputeACB() error = %v", err)
+            }
+            i := slices.IndexFunc(report.Dispositions, func(e ACBEvent) bool { return e.Transaction.ID == "s1" })
+            if i < 0 {
+                t.Fatal("s1 isn't a disposition")
+            }
+            if sale := report.Dispositions[i]; !closeTo(sale.Gain, tt.gain) || !closeTo(sale.DeniedLoss, tt.denied) {
+                t.Errorf("s1 gain = %g with %g denied, want %g with %g denied", sale.Gain, sale.DeniedLoss, tt.gain, tt.denied)
+            }
+            if pool := report.Pools[0]; !closeTo(pool.ACB, tt.acb) {
+                t.Errorf("pool ACB = %g, want %g", pool.ACB, tt.acb)
+            }
+
+            loss, found := report.SuperficialLossFor("s1")
+            if found != (tt.replacements != nil) {
+                t.Fatalf("SuperficialLossFor(s1) found = %v, want %v", found, tt.replacements != nil)
+            }
+            if !found {
+                return
+            }
+            if !closeTo(loss.Denied, tt.denied) || !closeTo(loss.Added, tt.added) {
+                t.Errorf("SuperficialLossFor(s1) = %g denied, %g added; want %g, %g", loss.Denied, loss.Added, tt.denied, tt.added)
+            }
+            if !slices.Equal(loss.Replacements, tt.replacements) {
+                t.Errorf("SuperficialLossFor(s1) replacements = %v, want %v", loss.Replacements, tt.replacements)
+            }
+        })
+    }
+}
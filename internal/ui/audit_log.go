@@ -0,0 +1,192 @@
+// File: internal/ui/audit_log.go
+package ui
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/history"
+    "github.com/frederikblais/Moose-Market/internal/models"
+)
+
+// auditLogLine is a change shown in the audit log viewer
+type auditLogLine struct {
+    time   time.Time
+    actor  string
+    change data.AuditChange
+}
+
+// auditValueWidth is how much of a changed value the viewer shows
+const auditValueWidth = 40
+
+// showAuditLog lists the recorded changes of the active profile, filtered by
+// account and date, and can restore the profile as it was at a change
+func (d *Dashboard) showAuditLog() {
+    profile := d.currentProfile()
+    if profile == nil {
+        return
+    }
+
+    // Filter inputs
+    allAccounts := "All accounts"
+    accountIDs := map[string]string{allAccounts: ""}
+    options := []string{allAccounts}
+    for _, account := range profile.Accounts {
+        accountIDs[account.Name] = account.ID
+        options = append(options, account.Name)
+    }
+    accountSelect := widget.NewSelect(options, nil)
+    accountSelect.SetSelected(allAccounts)
+    fromEntry := widget.NewEntry()
+    fromEntry.SetPlaceHolder("From (YYYY-MM-DD)")
+    toEntry := widget.NewEntry()
+    toEntry.SetPlaceHolder("To (YYYY-MM-DD)")
+
+    var lines []auditLogLine
+    selected := -1
+    list := widget.NewList(
+        func() int {
+            return len(lines)
+        },
+        func() fyne.CanvasObject {
+            label := widget.NewLabel("Template")
+            label.Truncation = fyne.TextTruncateEllipsis
+            return label
+        },
+        func(i widget.ListItemID, o fyne.CanvasObject) {
+            o.(*widget.Label).SetText(formatAuditLine(lines[i]))
+        },
+    )
+    list.OnSelected = func(i widget.ListItemID) {
+        selected = i
+    }
+
+    load := func() {
+        filter := data.AuditFilter{AccountID: accountIDs[accountSelect.Selected]}
+        var err error
+        if filter.From, err = parseDate(fromEntry.Text); err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        if filter.To, err = parseDate(toEntry.Text); err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        // The To day is included
+        if !filter.To.IsZero() {
+            filter.To = filter.To.AddDate(0, 0, 1)
+        }
+
+        entries, err := data.ReadAuditLog(profile.ID, filter)
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+
+        // Most recent first
+        lines = lines[:0]
+        for i := len(entries) - 1; i >= 0; i-- {
+            for _, change := range entries[i].Changes {
+                lines = append(lines, auditLogLine{time: entries[i].Time, actor: entries[i].Actor, change: change})
+            }
+        }
+        selected = -1
+        list.UnselectAll()
+        list.Refresh()
+    }
+    accountSelect.OnChanged = func(string) { load() }
+    filterButton := widget.NewButtonWithIcon("Filter", theme.SearchIcon(), load)
+
+    restoreButton := widget.NewButtonWithIcon("Restore to Selected Change", theme.HistoryIcon(), func() {
+        if selected < 0 || selected >= len(lines) {
+            dialog.ShowInformation("Change Log", "Select the change to go back to.", d.window)
+            return
+        }
+        at := lines[selected].time
+        dialog.ShowConfirm("Restore Profile",
+            fmt.Sprintf("Put %s back as it was saved on %s? This can be undone.", profile.Name, at.Format("2006-01-02 15:04:05")),
+            func(confirm bool) {
+                if confirm {
+                    d.restoreProfileAsOf(at)
+                    load()
+                }
+            },
+            d.window)
+    })
+
+    filters := container.NewGridWithColumns(4, accountSelect, fromEntry, toEntry, filterButton)
+    content := container.NewBorder(filters, restoreButton, nil, nil, list)
+    viewer := dialog.NewCustom("Change Log: "+profile.Name, "Close", content, d.window)
+    viewer.Resize(fyne.NewSize(900, 500))
+    load()
+    viewer.Show()
+}
+
+// restoreProfileAsOf replaces the content of the active profile with the
+// version saved at a point in time, as an undoable change
+func (d *Dashboard) restoreProfileAsOf(at time.Time) {
+    past, err := data.ProfileAsOf(d.currentProfile().ID, at)
+    if err != nil {
+        dialog.ShowError(err, d.window)
+        return
+    }
+
+    cmd := history.EditProfile("Restore as of "+at.Format("2006-01-02 15:04"), func(profile *models.Profile) {
+        profile.Name = past.Name
+        profile.Accounts = past.Accounts
+        profile.Watchlists = past.Watchlists
+        profile.Settings = past.Settings
+    })
+    d.afterHistoryChange(cmd, d.history.Execute(cmd))
+}
+
+// parseDate parses a YYYY-MM-DD date, empty giving the zero time
+func parseDate(text string) (time.Time, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return time.Time{}, nil
+    }
+    date, err := time.ParseInLocation("2006-01-02", text, time.Local)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", text)
+    }
+    return date, nil
+}
+
+// formatAuditLine describes a change on one line
+func formatAuditLine(line auditLogLine) string {
+    path := strings.Join(line.change.Path, ".")
+    if path == "" {
+        path = "profile"
+    }
+
+    var value string
+    switch line.change.Op {
+    case data.AuditAdd:
+        value = shortenAuditValue(line.change.New)
+    case data.AuditRemove:
+        value = shortenAuditValue(line.change.Old)
+    default:
+        value = shortenAuditValue(line.change.Old) + " → " + shortenAuditValue(line.change.New)
+    }
+
+    return fmt.Sprintf("%s  %s  %s %s: %s",
+        line.time.Format("2006-01-02 15:04:05"), line.actor, line.change.Op, path, value)
+}
+
+// shortenAuditValue cuts a JSON value to fit on a line
+func shortenAuditValue(value []byte) string {
+    text := []rune(string(value))
+    if len(text) > auditValueWidth {
+        return string(text[:auditValueWidth-1]) + "…"
+    }
+    return string(text)
+}
@@ -0,0 +1,296 @@
+// File: internal/ui/cash.go
+package ui
+
+import (
+    "errors"
+    "fmt"
+    "maps"
+    "math"
+    "slices"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// showCashTransactions lists the cash movements of an account with the cash
+// balance they and the trades of the account leave
+func (d *Dashboard) showCashTransactions(accountID string) {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+    var account models.Account
+    for _, a := range profile.Accounts {
+        if a.ID == accountID {
+            account = a
+        }
+    }
+
+    var cashDialog dialog.Dialog
+    content := container.NewVBox()
+    balance := widget.NewLabel("Balance, buys and sells included: " + formatBalances(portfolio.CashBalances(account, time.Time{})))
+    balance.TextStyle = fyne.TextStyle{Bold: true}
+    content.Add(balance)
+    if len(account.Transactions) == 0 {
+        content.Add(widget.NewLabel("No cash movements yet."))
+    }
+    for _, t := range portfolio.SortTransactions(account.Transactions) {
+        line := fmt.Sprintf("%s  %s %.2f %s", t.Date.Format("2006-01-02"), t.Type, t.Amount, portfolio.TransactionCurrency(t))
+        if t.Type == models.TransactionFXConversion {
+            line += fmt.Sprintf(" → %.2f %s", t.ToAmount, t.ToCurrency)
+        }
+        if t.Notes != "" {
+            line += "  (" + t.Notes + ")"
+        }
+
+        transactionID := t.ID
+        remove := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+            err := d.editPortfolio("Remove cash transaction", func(profile *models.Profile) error {
+                return portfolio.RemoveCashTransaction(profile, accountID, transactionID)
+            })
+            if err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            cashDialog.Hide()
+            d.showCashTransactions(accountID)
+        })
+        content.Add(container.NewBorder(nil, nil, nil, remove, widget.NewLabel(line)))
+    }
+
+    buttons := container.NewHBox(
+        widget.NewButtonWithIcon("Add Cash Movement", theme.ContentAddIcon(), func() {
+            cashDialog.Hide()
+            d.showAddCashTransaction(accountID)
+        }),
+        widget.NewButtonWithIcon("Reconcile", theme.ConfirmIcon(), func() {
+            cashDialog.Hide()
+            d.showReconcile(accountID)
+        }),
+        widget.NewButtonWithIcon("Portfolio", theme.NavigateBackIcon(), func() {
+            cashDialog.Hide()
+            d.showPortfolio()
+        }),
+    )
+
+    cashDialog = dialog.NewCustom("Cash in "+account.Name, "Close",
+        container.NewBorder(nil, buttons, nil, nil, container.NewVScroll(content)), d.window)
+    cashDialog.Resize(fyne.NewSize(600, 400))
+    cashDialog.Show()
+}
+
+// showAddCashTransaction asks for a cash movement to record in an account,
+// warning before a deposit goes over the contribution room of a registered
+// account
+func (d *Dashboard) showAddCashTransaction(accountID string) {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+
+    typeSelect := widget.NewSelect(portfolio.CashTransactionTypes, nil)
+    typeSelect.SetSelected(models.TransactionDeposit)
+    amountEntry := widget.NewEntry()
+    currencyEntry := widget.NewEntry()
+    currencyEntry.SetText(portfolio.DefaultCurrency)
+    toAmountEntry := widget.NewEntry()
+    toAmountEntry.SetPlaceHolder("Received by a conversion")
+    toCurrencyEntry := widget.NewEntry()
+    toCurrencyEntry.SetPlaceHolder("Received by a conversion")
+    dateEntry := widget.NewEntry()
+    dateEntry.SetText(time.Now().Format("2006-01-02"))
+    notesEntry := widget.NewEntry()
+
+    form := []*widget.FormItem{
+        widget.NewFormItem("Type", typeSelect),
+        widget.NewFormItem("Amount", amountEntry),
+        widget.NewFormItem("Currency", currencyEntry),
+        widget.NewFormItem("Converted to", toAmountEntry),
+        widget.NewFormItem("Currency received", toCurrencyEntry),
+        widget.NewFormItem("Date", dateEntry),
+        widget.NewFormItem("Notes", notesEntry),
+    }
+    dialog.ShowForm("Add Cash Movement to "+accountName(profile, accountID), "Add", "Cancel", form, func(confirm bool) {
+        if !confirm {
+            d.showCashTransactions(accountID)
+            return
+        }
+
+        t := models.Transaction{
+            ID:       portfolio.NewTransactionID(profile),
+            Type:     typeSelect.Selected,
+            Currency: parseCurrency(currencyEntry.Text),
+            Notes:    strings.TrimSpace(notesEntry.Text),
+        }
+        var err error
+        t.Amount, err = parseAmount("amount", amountEntry.Text)
+        if err == nil && t.Type == models.TransactionFXConversion {
+            t.ToCurrency = strings.ToUpper(strings.TrimSpace(toCurrencyEntry.Text))
+            t.ToAmount, err = parseAmount("amount received", toAmountEntry.Text)
+        }
+        if err == nil {
+            t.Date, err = parseDate(dateEntry.Text)
+        }
+        if err == nil && t.Date.IsZero() {
+            err = errors.New("the transaction needs a date")
+        }
+        var over *portfolio.OverContribution
+        if err == nil && t.Type == models.TransactionDeposit {
+            over, err = portfolio.CheckDeposit(profile, accountID, t.Amount, t.Date)
+        }
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+
+        add := func() {
+            err := d.editPortfolio(fmt.Sprintf("Add %s of %.2f", t.Type, t.Amount), func(profile *models.Profile) error {
+                return portfolio.AddCashTransaction(profile, accountID, t)
+            })
+            if err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            d.showCashTransactions(accountID)
+        }
+        if over == nil {
+            add()
+            return
+        }
+        dialog.ShowConfirm("Over-contribution",
+            formatOverContribution(over)+"\n\nRecord the deposit anyway?",
+            func(confirm bool) {
+                if confirm {
+                    add()
+                } else {
+                    d.showCashTransactions(accountID)
+                }
+            }, d.window)
+    }, d.window)
+}
+
+// showReconcile compares the cash of an account with the balance of a
+// statement, and can record the difference as a transfer
+func (d *Dashboard) showReconcile(accountID string) {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+
+    dateEntry := widget.NewEntry()
+    dateEntry.SetText(time.Now().Format("2006-01-02"))
+    currencyEntry := widget.NewEntry()
+    currencyEntry.SetText(portfolio.DefaultCurrency)
+    statementEntry := widget.NewEntry()
+    statementEntry.SetPlaceHolder("Cash on the statement")
+
+    form := []*widget.FormItem{
+        widget.NewFormItem("Statement date", dateEntry),
+        widget.NewFormItem("Currency", currencyEntry),
+        widget.NewFormItem("Balance", statementEntry),
+    }
+    dialog.ShowForm("Reconcile "+accountName(profile, accountID), "Compare", "Cancel", form, func(confirm bool) {
+        if !confirm {
+            d.showCashTransactions(accountID)
+            return
+        }
+
+        date, err := parseDate(dateEntry.Text)
+        if err == nil && date.IsZero() {
+            err = errors.New("the statement needs a date")
+        }
+        var statement float64
+        if err == nil {
+            statement, err = parseAmount("balance", statementEntry.Text)
+        }
+        var result portfolio.Reconciliation
+        if err == nil {
+            result, err = portfolio.Reconcile(profile, accountID, strings.ToUpper(strings.TrimSpace(currencyEntry.Text)), date, statement)
+        }
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+
+        summary := fmt.Sprintf("On %s the transactions give %.2f %s and the statement %.2f %s.",
+            date.Format("2006-01-02"), result.Derived, result.Currency, result.Statement, result.Currency)
+        if result.Balanced() {
+            dialog.ShowInformation("Reconciled", summary+" They match.", d.window)
+            d.showCashTransactions(accountID)
+            return
+        }
+        dialog.ShowConfirm("Not Reconciled",
+            summary+fmt.Sprintf(" They are %.2f apart, which may be a missing transaction. Record the difference as a transfer on the statement date?", result.Difference),
+            func(confirm bool) {
+                if confirm {
+                    d.recordReconciliation(result)
+                }
+                d.showCashTransactions(accountID)
+            }, d.window)
+    }, d.window)
+}
+
+// recordReconciliation records the difference between a statement and the
+// transactions of an account as a transfer
+func (d *Dashboard) recordReconciliation(result portfolio.Reconciliation) {
+    t := models.Transaction{
+        ID:       portfolio.NewTransactionID(data.GetActiveProfile()),
+        Type:     models.TransactionTransferIn,
+        Amount:   result.Difference,
+        Currency: parseCurrency(result.Currency),
+        Date:     result.Date,
+        Notes:    "Reconciliation with statement",
+    }
+    if t.Amount < 0 {
+        t.Type, t.Amount = models.TransactionTransferOut, -t.Amount
+    }
+    err := d.editPortfolio("Reconcile cash", func(profile *models.Profile) error {
+        return portfolio.AddCashTransaction(profile, result.AccountID, t)
+    })
+    if err != nil {
+        dialog.ShowError(err, d.window)
+    }
+}
+
+// formatBalances lists cash balances by currency
+func formatBalances(balances map[string]float64) string {
+    var parts []string
+    for _, currency := range slices.Sorted(maps.Keys(balances)) {
+        if math.Abs(balances[currency]) >= 0.005 {
+            parts = append(parts, fmt.Sprintf("%.2f %s", balances[currency], currency))
+        }
+    }
+    if len(parts) == 0 {
+        return "0.00 " + portfolio.DefaultCurrency
+    }
+    return strings.Join(parts, ", ")
+}
+
+// parseCurrency normalizes a currency code typed in a form, the default
+// currency being left empty
+func parseCurrency(text string) string {
+    currency := strings.ToUpper(strings.TrimSpace(text))
+    if currency == portfolio.DefaultCurrency {
+        return ""
+    }
+    return currency
+}
+
+// formatOverContribution describes the excess a deposit would make and its penalty
+func formatOverContribution(over *portfolio.OverContribution) string {
+    text := fmt.Sprintf("This deposit goes %.2f over your %s contribution room.", over.Excess, over.AccountType)
+    if over.Taxable == 0 {
+        return text + " It stays within the 2,000.00 an RRSP may go over without a penalty."
+    }
+    return text + fmt.Sprintf(" The penalty is 1%% of %.2f per month, %.2f a month, or %.2f if the excess stays until the end of the year (%d months).",
+        over.Taxable, over.MonthlyPenalty, over.Penalty, over.Months)
+}
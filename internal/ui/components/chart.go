@@ -2,11 +2,11 @@
 package components
 
 import (
+    "context"
     "fmt"
     "image/color"
-    "strconv"
+    "sync"
     "time"
-    "sort"
     "math"
 
     "fyne.io/fyne/v2"
@@ -15,6 +15,7 @@ import (
     "fyne.io/fyne/v2/widget"
     
     "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/events"
     "github.com/frederikblais/Moose-Market/internal/models"
 )
 
@@ -26,16 +27,19 @@ type ChartContainer struct {
     candleData         *models.CandleData
     chartCanvas        *canvas.Rectangle
     chartContent       *fyne.Container
-    onAddToWatchlist   func(string)
-    alphaVantageClient *data.AlphaVantageClient
+    bus                *events.Bus
+    provider           data.MarketDataProvider
     loadingIndicator   *widget.ProgressBarInfinite
     symbolInfoLabel    *widget.Label
     chartArea          *fyne.Container
     emptyText          *canvas.Text
+    requests           requestTracker
+    dataMutex          sync.Mutex // guards candleData against streamed ticks
 }
 
-// CreateChartContainer creates the stock chart container
-func CreateChartContainer(onAddToWatchlist func(string)) *ChartContainer {
+// CreateChartContainer creates the stock chart container, charting the symbols
+// selected on the event bus
+func CreateChartContainer(provider data.MarketDataProvider, bus *events.Bus) *ChartContainer {
     // Create placeholder for chart
     chartPlaceholder := canvas.NewRectangle(color.NRGBA{R: 40, G: 40, B: 40, A: 255})
     chartPlaceholder.SetMinSize(fyne.NewSize(600, 400))
@@ -57,7 +61,7 @@ func CreateChartContainer(onAddToWatchlist func(string)) *ChartContainer {
     chartContent := container.NewWithoutLayout()
     
     // Timeframe selectors
-    timeframeOptions := []string{"5min", "15min", "30min", "60min", "1d", "1w"}
+    timeframeOptions := data.Timeframes
     timeframeSelect := widget.NewSelect(timeframeOptions, func(selected string) {
         // Will be implemented in the returned struct
     })
@@ -97,8 +101,8 @@ func CreateChartContainer(onAddToWatchlist func(string)) *ChartContainer {
         chartCanvas:        chartPlaceholder,
         chartContent:       chartContent,
         timeframe:          "1d",
-        onAddToWatchlist:   onAddToWatchlist,
-        alphaVantageClient: data.NewAlphaVantageClient(),
+        bus:                bus,
+        provider:           provider,
         loadingIndicator:   loadingIndicator,
         symbolInfoLabel:    symbolInfoLabel,
         chartArea:          chartArea,
@@ -115,11 +119,26 @@ func CreateChartContainer(onAddToWatchlist func(string)) *ChartContainer {
     
     // Set up add to watchlist callback
     addButton.OnTapped = func() {
-        if chartContainer.symbol != "" && chartContainer.onAddToWatchlist != nil {
-            chartContainer.onAddToWatchlist(chartContainer.symbol)
+        if chartContainer.symbol != "" {
+            bus.Publish(events.AddToWatchlistRequested{Symbol: chartContainer.symbol})
         }
     }
     
+    // Chart whatever symbol gets selected
+    events.Subscribe(bus, func(e events.SymbolSelected) {
+        chartContainer.LoadChart(e.Symbol)
+    })
+    
+    // Follow the charted symbol as quotes and ticks arrive
+    events.Subscribe(bus, func(e events.QuoteUpdated) {
+        if quote, ok := e.Quotes[chartContainer.symbol]; ok {
+            updateLabelTextSafely(chartContainer.symbolInfoLabel, formatQuoteInfo(quote))
+        }
+    })
+    events.Subscribe(bus, func(e events.TickReceived) {
+        chartContainer.ApplyTick(e.Tick)
+    })
+    
     return chartContainer
 }
 
@@ -128,9 +147,13 @@ func (c *ChartContainer) GetContainer() *fyne.Container {
     return c.container
 }
 
-// LoadChart loads the chart for a specific symbol
+// LoadChart loads the chart for a specific symbol, cancelling any chart still loading
 func (c *ChartContainer) LoadChart(symbol string) {
     c.symbol = symbol
+    timeframe := c.timeframe
+    
+    // Supersede the previous request so its results are dropped
+    ctx, generation := c.requests.Start()
     
     // Show loading indicator
     c.loadingIndicator.Show()
@@ -138,25 +161,14 @@ func (c *ChartContainer) LoadChart(symbol string) {
     
     // First try to get quote data
     go func() {
-        // Try to get quote data first
-        if c.alphaVantageClient.APIKey != "" {
-            quote, err := c.alphaVantageClient.GetQuote(symbol)
-            if err == nil {
-                // Update symbol info label
-                priceStr := quote.Price
-                changeStr := quote.Change
-                changePctStr := quote.ChangePercent
-                
-                info := fmt.Sprintf("%s - $%s | %s (%s)", 
-                    symbol, priceStr, changeStr, changePctStr)
-                
-                // Update UI on the main thread
-                updateLabelTextSafely(c.symbolInfoLabel, info)
-            }
+        quote, err := c.provider.GetQuote(ctx, symbol)
+        if err == nil && c.requests.IsCurrent(generation) {
+            // Update symbol info label on the main thread
+            updateLabelTextSafely(c.symbolInfoLabel, formatQuoteInfo(quote))
         }
         
         // Now load the chart data
-        c.loadChartData(symbol)
+        c.loadChartData(ctx, generation, symbol, timeframe)
     }()
     
      // Find the control bar at the bottom of the container
@@ -170,17 +182,13 @@ func (c *ChartContainer) LoadChart(symbol string) {
     }
 }
 
-// loadChartData loads candle data for the chart
-func (c *ChartContainer) loadChartData(symbol string) {
-    var candleData *models.CandleData
-    var err error
-    
-    // If Alpha Vantage API key is set, try to use it
-    if c.alphaVantageClient.APIKey != "" {
-        candleData, err = c.loadAlphaVantageData(symbol)
-    } else {
-        // Fall back to mock data
-        candleData, err = data.GetCandleData(symbol, c.timeframe, 100)
+// loadChartData loads candle data for the chart, dropping it if a newer request started
+func (c *ChartContainer) loadChartData(ctx context.Context, generation uint64, symbol, timeframe string) {
+    candleData, err := c.provider.GetCandles(ctx, symbol, timeframe, 100)
+    
+    // A newer request owns the chart now
+    if !c.requests.IsCurrent(generation) {
+        return
     }
     
     // Update the UI on the main thread
@@ -206,8 +214,35 @@ func (c *ChartContainer) loadChartData(symbol string) {
         return
     }
     
-    c.candleData = candleData
+    // Keep our own candles, streamed ticks update them in place
+    candles := *candleData
+    candles.Candles = append([]models.CandleStick(nil), candleData.Candles...)
+    c.dataMutex.Lock()
+    c.candleData = &candles
+    c.dataMutex.Unlock()
     
+    c.drawChart(candleData)
+}
+
+// ApplyTick updates the last candle with a streamed trade of the charted
+// symbol and redraws the chart without fetching the candles again
+func (c *ChartContainer) ApplyTick(tick models.Tick) {
+    c.dataMutex.Lock()
+    if c.candleData == nil || tick.Symbol != c.candleData.Symbol || !data.ApplyTickToCandles(c.candleData, tick) {
+        c.dataMutex.Unlock()
+        return
+    }
+    
+    // Draw a copy so the next tick can't change the candles while drawing
+    snapshot := *c.candleData
+    snapshot.Candles = append([]models.CandleStick(nil), c.candleData.Candles...)
+    c.dataMutex.Unlock()
+    
+    c.drawChart(&snapshot)
+}
+
+// drawChart replaces the chart with a drawing of candleData
+func (c *ChartContainer) drawChart(candleData *models.CandleData) {
     // Get the current size of the chart area
     chartSize := c.chartCanvas.Size()
     if chartSize.Width < 10 || chartSize.Height < 10 {
@@ -226,69 +261,10 @@ func (c *ChartContainer) loadChartData(symbol string) {
     c.chartContent.Refresh()
 }
 
-// loadAlphaVantageData loads candle data from Alpha Vantage
-func (c *ChartContainer) loadAlphaVantageData(symbol string) (*models.CandleData, error) {
-    // Choose the appropriate API call based on timeframe
-    var seriesData map[string]data.TimeSeriesData
-    var err error
-    
-    if c.timeframe == "1d" {
-        // Daily data
-        seriesData, err = c.alphaVantageClient.GetDailyTimeSeries(symbol, true)
-    } else {
-        // Intraday data
-        seriesData, err = c.alphaVantageClient.GetIntradayTimeSeries(symbol, c.timeframe, true)
-    }
-    
-    if err != nil {
-        return nil, err
-    }
-    
-    // Convert to CandleData format
-    candleData := &models.CandleData{
-        Symbol:    symbol,
-        Timeframe: c.timeframe,
-        Candles:   make([]models.CandleStick, 0, len(seriesData)),
-    }
-    
-    // Process the data
-    for dateStr, timeSeries := range seriesData {
-        // Parse date
-        date, err := time.Parse("2006-01-02", dateStr)
-        if err != nil {
-            // Try intraday format
-            date, err = time.Parse("2006-01-02 15:04:05", dateStr)
-            if err != nil {
-                continue // Skip dates we can't parse
-            }
-        }
-        
-        // Parse numeric values
-        open, _ := strconv.ParseFloat(timeSeries.Open, 64)
-        high, _ := strconv.ParseFloat(timeSeries.High, 64)
-        low, _ := strconv.ParseFloat(timeSeries.Low, 64)
-        close, _ := strconv.ParseFloat(timeSeries.Close, 64)
-        volume, _ := strconv.ParseInt(timeSeries.Volume, 10, 64)
-        
-        // Create candle stick
-        candle := models.CandleStick{
-            Time:   date,
-            Open:   open,
-            High:   high,
-            Low:    low,
-            Close:  close,
-            Volume: volume,
-        }
-        
-        candleData.Candles = append(candleData.Candles, candle)
-    }
-    
-    // Sort candles by time
-    sort.Slice(candleData.Candles, func(i, j int) bool {
-        return candleData.Candles[i].Time.Before(candleData.Candles[j].Time)
-    })
-    
-    return candleData, nil
+// formatQuoteInfo formats the price line shown above the chart
+func formatQuoteInfo(quote *models.Stock) string {
+    return fmt.Sprintf("%s - $%.2f | %+.2f (%+.2f%%)", 
+        quote.Symbol, quote.Price, quote.Change, quote.ChangePercent)
 }
 
 // Helper function to update a label's text safely from a goroutine
@@ -460,6 +436,8 @@ func formatShortDate(t time.Time, timeframe string) string {
         return t.Format("01/02")
     case "1w":
         return t.Format("01/02")
+    case "1mo":
+        return t.Format("Jan 06")
     default:
         return t.Format("01/02")
     }
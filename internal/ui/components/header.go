@@ -2,6 +2,8 @@
 package components
 
 import (
+    "context"
+    "fmt"
     "time"
 
     "fyne.io/fyne/v2"
@@ -13,6 +15,7 @@ import (
     "image/color"
 
     "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/events"
     "github.com/frederikblais/Moose-Market/internal/models"
 )
 
@@ -27,25 +30,26 @@ type SearchResult struct {
 type SearchBox struct {
     widget.Entry
     resultsContainer *fyne.Container
-    onSelectStock    func(string)
+    bus              *events.Bus
     resultButtons    []*widget.Button
     selectedIndex    int
     isDropdownOpen   bool
-    client           *data.AlphaVantageClient
+    provider         data.MarketDataProvider
     debounceTimer    *time.Timer
     overlay          *widget.PopUp
     window           fyne.Window
+    requests         requestTracker
 }
 
 // NewSearchBox creates a new search box widget
-func NewSearchBox(window fyne.Window, onSelectStock func(string)) *SearchBox {
+func NewSearchBox(window fyne.Window, provider data.MarketDataProvider, bus *events.Bus) *SearchBox {
     searchBox := &SearchBox{
-        onSelectStock:    onSelectStock,
+        bus:              bus,
         resultsContainer: container.NewVBox(),
         resultButtons:    make([]*widget.Button, 0),
         selectedIndex:    -1,
         isDropdownOpen:   false,
-        client:           data.NewAlphaVantageClient(),
+        provider:         provider,
         window:           window,
     }
 
@@ -65,6 +69,7 @@ func NewSearchBox(window fyne.Window, onSelectStock func(string)) *SearchBox {
             if len(text) >= 2 {
                 searchBox.Search(text)
             } else {
+                searchBox.requests.Cancel()
                 searchBox.CloseDropdown()
             }
         })
@@ -83,53 +88,36 @@ func NewSearchBox(window fyne.Window, onSelectStock func(string)) *SearchBox {
     return searchBox
 }
 
-// Search performs a search and updates the dropdown
+// Search performs a search and updates the dropdown, cancelling any search in flight
 func (s *SearchBox) Search(query string) {
-    // First try local search
-    localResults := data.SearchSymbols(query)
-    
-    // If local search doesn't yield enough results, try Alpha Vantage
-    if len(localResults) < 5 && s.client.APIKey != "" {
-        go s.performAPISearch(query)
-        return
-    }
-    
-    // Convert local results to search results
-    results := make([]SearchResult, 0, len(localResults))
-    for _, stock := range localResults {
-        results = append(results, SearchResult{
-            Symbol:   stock.Symbol,
-            Name:     stock.Name,
-            Exchange: stock.Exchange,
-        })
-    }
-    
-    // Update the dropdown with results
-    s.UpdateDropdown(results)
+    ctx, generation := s.requests.Start()
+    go s.performSearch(ctx, generation, query)
 }
 
-// performAPISearch searches using the Alpha Vantage API
-func (s *SearchBox) performAPISearch(query string) {
-    apiResults, err := s.client.SearchSymbols(query)
-    if err != nil {
+// performSearch searches using the market data provider
+func (s *SearchBox) performSearch(ctx context.Context, generation uint64, query string) {
+    matches, err := s.provider.SearchSymbols(ctx, query)
+    if err != nil || !s.requests.IsCurrent(generation) {
         return
     }
     
-    // Convert API results to search results (limit to 5)
+    // Convert matches to search results (limit to 5)
     results := make([]SearchResult, 0, 5)
-    for i, result := range apiResults {
+    for i, match := range matches {
         if i >= 5 {
             break
         }
         results = append(results, SearchResult{
-            Symbol:   result.Symbol,
-            Name:     result.Name,
-            Exchange: result.Region,
+            Symbol:   match.Symbol,
+            Name:     match.Name,
+            Exchange: match.Exchange,
         })
     }
     
     // Update the dropdown on the main thread
-    fyne.CurrentApp().Driver().CanvasForObject(s).Refresh(s)
+    if canvas := fyne.CurrentApp().Driver().CanvasForObject(s); canvas != nil {
+        canvas.Refresh(s)
+    }
     s.UpdateDropdown(results)
 }
 
@@ -152,7 +140,7 @@ func (s *SearchBox) UpdateDropdown(results []SearchResult) {
         // Create a copy of the symbol for the closure
         symbol := result.Symbol
         btn.OnTapped = func() {
-            s.onSelectStock(symbol)
+            s.bus.Publish(events.SymbolSelected{Symbol: symbol})
             s.SetText("") // Clear the search box after selection
             s.CloseDropdown()
         }
@@ -244,14 +232,14 @@ func (s *SearchBox) updateSelectedItem() {
 }
 
 // CreateCompactHeader creates a compact header with logo, search, and profile
-func CreateCompactHeader(window fyne.Window, onSelectStock func(string), profile *models.Profile, onProfileSelect func(string)) *fyne.Container {
+func CreateCompactHeader(window fyne.Window, provider data.MarketDataProvider, bus *events.Bus, profile *models.Profile, onProfileSelect func(string), onSettings func(), onChangeLog func(), onPortfolio func()) *fyne.Container {
     // App title/logo on the left
     title := canvas.NewText("Moose Market", color.NRGBA{R: 76, G: 175, B: 80, A: 255})
     title.TextSize = 20
     title.TextStyle = fyne.TextStyle{Bold: true}
     
     // Create search box with popup behavior in the middle
-    searchBox := NewSearchBox(window, onSelectStock)
+    searchBox := NewSearchBox(window, provider, bus)
     
     // Set up keyboard shortcut for / key
     if desktopCanvas, ok := window.Canvas().(desktop.Canvas); ok {
@@ -264,17 +252,48 @@ func CreateCompactHeader(window fyne.Window, onSelectStock func(string), profile
     }
     
     // Create profile and settings buttons on the right
-    profileButton := widget.NewButtonWithIcon("Default Profile", theme.AccountIcon(), func() {
+    profileName := "Default Profile"
+    if profile != nil {
+        profileName = profile.Name
+    }
+    profileButton := widget.NewButtonWithIcon(profileName, theme.AccountIcon(), func() {
         if onProfileSelect != nil {
-            onProfileSelect(profile.Name)
+            onProfileSelect(profileName)
         }
     })
     
-    // Settings button
-    settingsButton := widget.NewButtonWithIcon("", theme.SettingsIcon(), nil)
+    // Keep the profile button in sync with the active profile
+    events.Subscribe(bus, func(e events.ProfileSwitched) {
+        if e.Profile != nil {
+            profileName = e.Profile.Name
+            profileButton.SetText(profileName)
+        }
+    })
+    events.Subscribe(bus, func(e events.ProfileReloaded) {
+        profileName = e.Profile.Name
+        profileButton.SetText(profileName)
+    })
+    
+    // Portfolio, change log and settings buttons
+    portfolioButton := widget.NewButtonWithIcon("", theme.ListIcon(), onPortfolio)
+    changeLogButton := widget.NewButtonWithIcon("", theme.HistoryIcon(), onChangeLog)
+    settingsButton := widget.NewButtonWithIcon("", theme.SettingsIcon(), onSettings)
     
     // Create right side buttons container
-    rightContainer := container.NewHBox(profileButton, settingsButton)
+    rightContainer := container.NewHBox(profileButton, portfolioButton, changeLogButton, settingsButton)
+    
+    // Show the remaining API budget for providers that have one
+    if reporter, ok := data.QuotaOf(provider); ok {
+        quotaLabel := widget.NewLabel("")
+        updateQuotaLabel(quotaLabel, reporter)
+        rightContainer.Objects = append([]fyne.CanvasObject{quotaLabel}, rightContainer.Objects...)
+        
+        go func() {
+            for range time.Tick(15 * time.Second) {
+                updateQuotaLabel(quotaLabel, reporter)
+            }
+        }()
+    }
     
     // Create spacer to push title to left and buttons to right
     spacer1 := canvas.NewRectangle(color.Transparent)
@@ -296,4 +315,10 @@ func CreateCompactHeader(window fyne.Window, onSelectStock func(string), profile
     
     // Final container
     return container.NewBorder(nil, nil, nil, nil, topBar)
-}
\ No newline at end of file
+}
+
+// updateQuotaLabel shows the remaining daily API calls of a provider
+func updateQuotaLabel(label *widget.Label, reporter data.QuotaReporter) {
+    remaining, limit := reporter.RemainingRequests()
+    label.SetText(fmt.Sprintf("API calls left: %d/%d", remaining, limit))
+}
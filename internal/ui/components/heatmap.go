@@ -5,26 +5,29 @@ import (
     "fmt"
     "image/color"
     "math"
+    "sync"
     
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/canvas"
     "fyne.io/fyne/v2/container"
     "fyne.io/fyne/v2/widget"
     
-    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/events"
     "github.com/frederikblais/Moose-Market/internal/models"
 )
 
 // HeatmapContainer represents the market heatmap component
 type HeatmapContainer struct {
     container    *fyne.Container
-    watchlistID  string
+    watchlist    models.Watchlist
     stocks       []*models.Stock
-    onSelectStock func(string)
+    bus          *events.Bus
+    quotesMutex  sync.Mutex
+    quotes       map[string]*models.Stock
 }
 
-// CreateHeatmapContainer creates the market heatmap container
-func CreateHeatmapContainer(onSelectStock func(string)) *HeatmapContainer {
+// CreateHeatmapContainer creates the market heatmap container connected to the event bus
+func CreateHeatmapContainer(bus *events.Bus) *HeatmapContainer {
     // Title for the heatmap
     title := widget.NewLabel("Market Heatmap")
     title.TextStyle = fyne.TextStyle{Bold: true}
@@ -38,10 +41,21 @@ func CreateHeatmapContainer(onSelectStock func(string)) *HeatmapContainer {
     // Put everything together
     heatmapContainer := container.NewBorder(title, nil, nil, nil, scrollContainer)
     
-    return &HeatmapContainer{
+    h := &HeatmapContainer{
         container:    heatmapContainer,
-        onSelectStock: onSelectStock,
+        bus:          bus,
+        quotes:       make(map[string]*models.Stock),
     }
+    
+    // Follow the displayed watchlist and its quotes
+    events.Subscribe(bus, func(e events.WatchlistChanged) {
+        h.SetWatchlist(e.Watchlist)
+    })
+    events.Subscribe(bus, func(e events.QuoteUpdated) {
+        h.UpdateQuotes(e.Quotes)
+    })
+    
+    return h
 }
 
 // GetContainer returns the container for the heatmap
@@ -50,40 +64,35 @@ func (h *HeatmapContainer) GetContainer() *fyne.Container {
 }
 
 // SetWatchlist sets the watchlist to display in the heatmap
-func (h *HeatmapContainer) SetWatchlist(watchlistID string) {
-    h.watchlistID = watchlistID
+func (h *HeatmapContainer) SetWatchlist(watchlist models.Watchlist) {
+    h.watchlist = watchlist
     h.RefreshHeatmap()
 }
 
-// RefreshHeatmap refreshes the heatmap with the current watchlist data
-func (h *HeatmapContainer) RefreshHeatmap() {
-    // Get the active profile
-    profile := data.GetActiveProfile()
-    if profile == nil {
-        return
-    }
-    
-    // Find the watchlist
-    var watchlist *models.Watchlist
-    for i := range profile.Watchlists {
-        if profile.Watchlists[i].ID == h.watchlistID {
-            watchlist = &profile.Watchlists[i]
-            break
-        }
+// UpdateQuotes stores fresh quotes and redraws the heatmap
+func (h *HeatmapContainer) UpdateQuotes(quotes map[string]*models.Stock) {
+    h.quotesMutex.Lock()
+    for symbol, quote := range quotes {
+        h.quotes[symbol] = quote
     }
+    h.quotesMutex.Unlock()
     
-    if watchlist == nil {
-        return
-    }
+    h.RefreshHeatmap()
+}
+
+// RefreshHeatmap redraws the heatmap for the current watchlist from the latest known quotes
+func (h *HeatmapContainer) RefreshHeatmap() {
+    watchlist := h.watchlist
     
-    // Get stocks for this watchlist
+    // Get stocks for this watchlist, skipping those not fetched yet
     var stocks []*models.Stock
+    h.quotesMutex.Lock()
     for _, symbol := range watchlist.Symbols {
-        stock, err := data.GetStockBySymbol(symbol)
-        if err == nil {
+        if stock, ok := h.quotes[symbol]; ok {
             stocks = append(stocks, stock)
         }
     }
+    h.quotesMutex.Unlock()
     
     h.stocks = stocks
     
@@ -154,9 +163,7 @@ func (h *HeatmapContainer) createHeatmapTile(stock *models.Stock) fyne.CanvasObj
     
     // Make the tile clickable
     tileButton := widget.NewButton("", func() {
-        if h.onSelectStock != nil {
-            h.onSelectStock(stock.Symbol)
-        }
+        h.bus.Publish(events.SymbolSelected{Symbol: stock.Symbol})
     })
     tileButton.Importance = widget.LowImportance
     
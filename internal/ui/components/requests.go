@@ -0,0 +1,49 @@
+// File: internal/ui/components/requests.go
+package components
+
+import (
+    "context"
+    "sync"
+)
+
+// requestTracker cancels superseded data requests and recognizes their results.
+// Every request is tagged with a generation, only the latest one is current.
+type requestTracker struct {
+    mu         sync.Mutex
+    generation uint64
+    cancel     context.CancelFunc
+}
+
+// Start cancels the request in flight and returns the context and generation of a new one
+func (t *requestTracker) Start() (context.Context, uint64) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if t.cancel != nil {
+        t.cancel()
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    t.cancel = cancel
+    t.generation++
+    return ctx, t.generation
+}
+
+// IsCurrent reports whether generation belongs to the latest request
+func (t *requestTracker) IsCurrent(generation uint64) bool {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return generation == t.generation
+}
+
+// Cancel cancels the request in flight, if any
+func (t *requestTracker) Cancel() {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if t.cancel != nil {
+        t.cancel()
+        t.cancel = nil
+    }
+    t.generation++
+}
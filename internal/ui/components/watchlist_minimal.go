@@ -3,6 +3,9 @@ package components
 
 import (
     "fmt"
+    "log"
+    "slices"
+    "sync"
     // Remove unused import: time
 
     "fyne.io/fyne/v2"
@@ -11,6 +14,8 @@ import (
     "fyne.io/fyne/v2/widget"
 
     "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/events"
+    "github.com/frederikblais/Moose-Market/internal/history"
     "github.com/frederikblais/Moose-Market/internal/models"
 )
 
@@ -18,12 +23,16 @@ import (
 type MinimalWatchlistContainer struct {
     container        *fyne.Container
     currentWatchlist *models.Watchlist
-    onSelectStock    func(string)
-    onWatchlistChanged func(string)
+    bus              *events.Bus
+    history          *history.History
+    quotesMutex      sync.Mutex
+    quotes           map[string]*models.Stock
+    buttons          map[string]*widget.Button // displayed items by symbol
 }
 
 // CreateMinimalWatchlistContainer creates a simplified watchlist component
-func CreateMinimalWatchlistContainer(onSelectStock func(string), onWatchlistChanged func(string)) *MinimalWatchlistContainer {
+// connected to the event bus. Its changes are recorded in history.
+func CreateMinimalWatchlistContainer(bus *events.Bus, history *history.History) *MinimalWatchlistContainer {
     // Create a label
     label := widget.NewLabel("Watchlist")
     label.TextStyle = fyne.TextStyle{Bold: true}
@@ -39,11 +48,28 @@ func CreateMinimalWatchlistContainer(onSelectStock func(string), onWatchlistChan
         widget.NewLabel("No stocks in watchlist"),
     )
     
-    return &MinimalWatchlistContainer{
+    w := &MinimalWatchlistContainer{
         container: content,
-        onSelectStock: onSelectStock,
-        onWatchlistChanged: onWatchlistChanged,
+        bus: bus,
+        history: history,
+        quotes: make(map[string]*models.Stock),
     }
+    
+    // React to quotes, watch requests and profile switches
+    events.Subscribe(bus, func(e events.QuoteUpdated) {
+        w.UpdateQuotes(e.Quotes)
+    })
+    events.Subscribe(bus, func(e events.AddToWatchlistRequested) {
+        w.AddSymbol(e.Symbol)
+    })
+    events.Subscribe(bus, func(e events.ProfileSwitched) {
+        w.LoadWatchlists()
+    })
+    events.Subscribe(bus, func(e events.ProfileReloaded) {
+        w.LoadWatchlists()
+    })
+    
+    return w
 }
 
 // GetContainer returns the container
@@ -61,30 +87,72 @@ func (w *MinimalWatchlistContainer) LoadWatchlists() {
     
     // Just store the first watchlist
     w.currentWatchlist = &profile.Watchlists[0]
+    w.LoadWatchlistItems()
+    
+    // Tell the other components which watchlist is displayed
+    w.bus.Publish(events.WatchlistChanged{Watchlist: *w.currentWatchlist})
+}
+
+// UpdateQuotes stores fresh quotes and updates the items showing them. The
+// list is only redrawn when a quote arrives for a symbol not displayed yet.
+func (w *MinimalWatchlistContainer) UpdateQuotes(quotes map[string]*models.Stock) {
+    w.quotesMutex.Lock()
+    for symbol, quote := range quotes {
+        w.quotes[symbol] = quote
+    }
+    
+    var updated []*widget.Button
+    redraw := false
+    for symbol, quote := range quotes {
+        if btn, ok := w.buttons[symbol]; ok {
+            styleWatchlistButton(btn, quote)
+            updated = append(updated, btn)
+        } else if w.currentWatchlist != nil && slices.Contains(w.currentWatchlist.Symbols, symbol) {
+            redraw = true
+        }
+    }
+    w.quotesMutex.Unlock()
     
-    // If we have a callback, call it
-    if w.onWatchlistChanged != nil {
-        w.onWatchlistChanged(w.currentWatchlist.ID)
+    if redraw {
+        w.LoadWatchlistItems()
+        return
+    }
+    for _, btn := range updated {
+        btn.Refresh()
     }
 }
 
-// LoadWatchlistItems loads stocks - simplified version
+// styleWatchlistButton shows a quote on a watchlist item
+func styleWatchlistButton(btn *widget.Button, stock *models.Stock) {
+    btn.Text = fmt.Sprintf("%s: $%.2f (%+.2f)", stock.Symbol, stock.Price, stock.Change)
+    
+    // Style the button based on stock change
+    if stock.Change >= 0 {
+        btn.Importance = widget.HighImportance
+    } else {
+        btn.Importance = widget.DangerImportance
+    }
+}
+
+// LoadWatchlistItems draws the watchlist from the latest known quotes
 func (w *MinimalWatchlistContainer) LoadWatchlistItems() {
     if w.currentWatchlist == nil {
         return
     }
     
-    // Get stocks for symbols in the watchlist
+    // Get stocks for symbols in the watchlist, skipping those not fetched yet
     var items []*models.Stock
+    w.quotesMutex.Lock()
     for _, symbol := range w.currentWatchlist.Symbols {
-        stock, err := data.GetStockBySymbol(symbol)
-        if err == nil {
+        if stock, ok := w.quotes[symbol]; ok {
             items = append(items, stock)
         }
     }
+    w.quotesMutex.Unlock()
     
     // Create a container for the items
     listContainer := container.NewVBox()
+    buttons := make(map[string]*widget.Button)
     
     // Add items to the list
     if len(items) == 0 {
@@ -97,27 +165,20 @@ func (w *MinimalWatchlistContainer) LoadWatchlistItems() {
             stockSymbol := stock.Symbol // Store symbol for closure
             
             // Create a button for each stock
-            btn := widget.NewButton(fmt.Sprintf("%s: $%.2f (%+.2f)", 
-                stock.Symbol, 
-                stock.Price, 
-                stock.Change), 
-                func() {
-                    if w.onSelectStock != nil {
-                        w.onSelectStock(stockSymbol)
-                    }
-                })
-            
-            // Style the button based on stock change
-            if stock.Change >= 0 {
-                btn.Importance = widget.HighImportance
-            } else {
-                btn.Importance = widget.DangerImportance
-            }
+            btn := widget.NewButton("", func() {
+                w.bus.Publish(events.SymbolSelected{Symbol: stockSymbol})
+            })
+            styleWatchlistButton(btn, stock)
             
+            buttons[stockSymbol] = btn
             listContainer.Add(btn)
         }
     }
     
+    w.quotesMutex.Lock()
+    w.buttons = buttons
+    w.quotesMutex.Unlock()
+    
     // Update the container - safely
     if w.container != nil && len(w.container.Objects) > 1 {
         w.container.Objects[1] = listContainer
@@ -125,7 +186,7 @@ func (w *MinimalWatchlistContainer) LoadWatchlistItems() {
     }
 }
 
-// AddSymbol adds a symbol to the watchlist
+// AddSymbol adds a symbol to the watchlist, as an undoable change
 func (w *MinimalWatchlistContainer) AddSymbol(symbol string) {
     if w.currentWatchlist == nil {
         return
@@ -138,63 +199,46 @@ func (w *MinimalWatchlistContainer) AddSymbol(symbol string) {
         }
     }
     
-    // Add symbol to the watchlist
-    w.currentWatchlist.Symbols = append(w.currentWatchlist.Symbols, symbol)
-    
-    // Update the profile
-    profile := data.GetActiveProfile()
-    if profile == nil {
-        return
-    }
-    
-    // Find and update this watchlist
-    for i, wl := range profile.Watchlists {
-        if wl.ID == w.currentWatchlist.ID {
-            profile.Watchlists[i] = *w.currentWatchlist
-            break
-        }
-    }
-    
-    // Save profile
-    data.SaveProfile(profile)
-    
-    // Refresh display
-    w.LoadWatchlistItems()
+    w.editWatchlist("Add "+symbol, func(watchlist *models.Watchlist) {
+        watchlist.Symbols = append(watchlist.Symbols, symbol)
+    })
 }
 
-// RemoveSymbol removes a symbol from the watchlist
+// RemoveSymbol removes a symbol from the watchlist, as an undoable change
 func (w *MinimalWatchlistContainer) RemoveSymbol(symbol string) {
     if w.currentWatchlist == nil {
         return
     }
     
-    // Filter out the symbol
-    var newSymbols []string
-    for _, s := range w.currentWatchlist.Symbols {
-        if s != symbol {
-            newSymbols = append(newSymbols, s)
+    w.editWatchlist("Remove "+symbol, func(watchlist *models.Watchlist) {
+        // Filter out the symbol
+        var newSymbols []string
+        for _, s := range watchlist.Symbols {
+            if s != symbol {
+                newSymbols = append(newSymbols, s)
+            }
         }
-    }
-    
-    w.currentWatchlist.Symbols = newSymbols
-    
-    // Update the profile
-    profile := data.GetActiveProfile()
-    if profile == nil {
-        return
-    }
-    
-    // Find and update this watchlist
-    for i, wl := range profile.Watchlists {
-        if wl.ID == w.currentWatchlist.ID {
-            profile.Watchlists[i] = *w.currentWatchlist
-            break
+        watchlist.Symbols = newSymbols
+    })
+}
+
+// editWatchlist changes the displayed watchlist in the active profile through
+// the history, then redraws it and tells the other components
+func (w *MinimalWatchlistContainer) editWatchlist(name string, edit func(watchlist *models.Watchlist)) {
+    watchlistID := w.currentWatchlist.ID
+    err := w.history.Execute(history.EditProfile(name, func(profile *models.Profile) {
+        // Find and update this watchlist
+        for i := range profile.Watchlists {
+            if profile.Watchlists[i].ID == watchlistID {
+                edit(&profile.Watchlists[i])
+                break
+            }
         }
+    }))
+    if err != nil {
+        log.Printf("Failed to save watchlist: %v", err)
     }
     
-    // Save profile
-    data.SaveProfile(profile)
-    
-    // Refresh display
-    w.LoadWatchlistItems()
-}
\ No newline at end of file
+    // Reload in both cases, a failed edit restores the profile
+    w.LoadWatchlists()
+}
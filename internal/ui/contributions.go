@@ -0,0 +1,136 @@
+// File: internal/ui/contributions.go
+package ui
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// registeredAccountTypes are the account types with contribution room, in the
+// order they are shown
+var registeredAccountTypes = []string{models.AccountTFSA, models.AccountRRSP, models.AccountFHSA}
+
+// showContributionRoom shows the contribution room of the registered accounts
+// of the active profile this year, and what it is worked out from
+func (d *Dashboard) showContributionRoom() {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+    settings := profile.Settings.Contributions
+    year := time.Now().Year()
+
+    content := container.NewVBox()
+    title := widget.NewLabel(fmt.Sprintf("Contribution room in %d", year))
+    title.TextStyle = fyne.TextStyle{Bold: true}
+    content.Add(title)
+    for _, accountType := range registeredAccountTypes {
+        content.Add(widget.NewLabel(formatContributionRoom(profile, accountType, year)))
+    }
+    content.Add(widget.NewSeparator())
+
+    tfsaEntry := widget.NewEntry()
+    tfsaEntry.SetPlaceHolder("Year you turned 18, or 2009")
+    rrspLimitEntry := widget.NewEntry()
+    rrspLimitEntry.SetPlaceHolder("From your notice of assessment")
+    rrspYearEntry := widget.NewEntry()
+    rrspYearEntry.SetText(strconv.Itoa(year))
+    fhsaEntry := widget.NewEntry()
+    fhsaEntry.SetPlaceHolder("Year your first FHSA was opened")
+    if settings.TFSAFirstYear != 0 {
+        tfsaEntry.SetText(strconv.Itoa(settings.TFSAFirstYear))
+    }
+    if settings.RRSPLimitYear != 0 {
+        rrspLimitEntry.SetText(strconv.FormatFloat(settings.RRSPDeductionLimit, 'f', 2, 64))
+        rrspYearEntry.SetText(strconv.Itoa(settings.RRSPLimitYear))
+    }
+    if settings.FHSAFirstYear != 0 {
+        fhsaEntry.SetText(strconv.Itoa(settings.FHSAFirstYear))
+    }
+    content.Add(widget.NewForm(
+        widget.NewFormItem("TFSA room since", tfsaEntry),
+        widget.NewFormItem("RRSP deduction limit", rrspLimitEntry),
+        widget.NewFormItem("RRSP limit for year", rrspYearEntry),
+        widget.NewFormItem("FHSA opened in", fhsaEntry),
+    ))
+
+    var roomDialog dialog.Dialog
+    save := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+        var updated models.ContributionSettings
+        var err error
+        if updated.TFSAFirstYear, err = parseYear("TFSA year", tfsaEntry.Text); err == nil {
+            updated.FHSAFirstYear, err = parseYear("FHSA year", fhsaEntry.Text)
+        }
+        if err == nil {
+            updated.RRSPDeductionLimit, err = parseAmount("RRSP deduction limit", rrspLimitEntry.Text)
+        }
+        if err == nil && strings.TrimSpace(rrspLimitEntry.Text) != "" {
+            updated.RRSPLimitYear, err = parseYear("RRSP year", rrspYearEntry.Text)
+        }
+        if err == nil {
+            err = d.editPortfolio("Change contribution room", func(profile *models.Profile) error {
+                profile.Settings.Contributions = updated
+                return nil
+            })
+        }
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        roomDialog.Hide()
+        d.showContributionRoom()
+    })
+    back := widget.NewButtonWithIcon("Portfolio", theme.NavigateBackIcon(), func() {
+        roomDialog.Hide()
+        d.showPortfolio()
+    })
+
+    roomDialog = dialog.NewCustom("Contribution Room: "+profile.Name, "Close",
+        container.NewBorder(nil, container.NewHBox(save, back), nil, nil, container.NewVScroll(content)), d.window)
+    roomDialog.Resize(fyne.NewSize(650, 450))
+    roomDialog.Show()
+}
+
+// formatContributionRoom describes the contribution room of an account type in a year
+func formatContributionRoom(profile *models.Profile, accountType string, year int) string {
+    room, err := portfolio.ComputeContributionRoom(profile, accountType, year)
+    if err != nil {
+        return fmt.Sprintf("%s: %v", accountType, err)
+    }
+    if !room.Known {
+        return fmt.Sprintf("%s: enter your deduction limit for %d, deposited %.2f", accountType, year, room.Contributed)
+    }
+    line := fmt.Sprintf("%s: %.2f left of %.2f, deposited %.2f", accountType, room.Remaining, room.Available, room.Contributed)
+    if room.Withdrawn > 0 {
+        line += fmt.Sprintf(", withdrew %.2f", room.Withdrawn)
+    }
+    if room.Remaining < 0 {
+        line += fmt.Sprintf("  ⚠ over-contributed by %.2f", -room.Remaining)
+    }
+    return line
+}
+
+// parseYear parses a year typed in a form, empty meaning zero
+func parseYear(name, text string) (int, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return 0, nil
+    }
+    year, err := strconv.Atoi(text)
+    if err != nil || year < 1900 || year > 9999 {
+        return 0, fmt.Errorf("invalid %s: %q", name, text)
+    }
+    return year, nil
+}
@@ -1,15 +1,26 @@
 package ui
 
 import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "slices"
+    "strconv"
+    "strings"
+    "sync"
     "time"
 
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/container"
     "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/driver/desktop"
     "fyne.io/fyne/v2/theme"
     "fyne.io/fyne/v2/widget"
 
     "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/events"
+    "github.com/frederikblais/Moose-Market/internal/history"
     "github.com/frederikblais/Moose-Market/internal/models"
     "github.com/frederikblais/Moose-Market/internal/ui/components"
 )
@@ -29,15 +40,38 @@ type Dashboard struct {
     chartContainer    *components.ChartContainer
     watchlistContainer WatchlistInterface
     heatmapContainer  *components.HeatmapContainer
+    profileMutex      sync.RWMutex // guards activeProfile, which reloads replace from another goroutine
     activeProfile     *models.Profile
+    provider          data.MarketDataProvider
+    scheduler         *data.QuoteScheduler
+    stream            *data.QuoteStream // nil when streaming is off
+    syncMutex         sync.Mutex // guards sync, which the settings replace
+    sync              *data.SyncEngine // nil when sync is off
+    bus               *events.Bus
+    history           *history.History // undoable profile changes
+    watchlistQuotes   int // scheduler subscription for the displayed watchlist
+    chartQuotes       int // scheduler subscription for the charted symbol
+    watchlistSymbols  []string
+    chartSymbol       string
+    reloads           chan string   // profiles changed outside the app, reloaded one at a time
+    synced            chan data.SyncResult // sync results, handled along with the reloads
+    closed            chan struct{} // closed with the window
 }
 
-// NewDashboard creates a new dashboard UI
-func NewDashboard(app fyne.App, window fyne.Window) *Dashboard {
+// NewDashboard creates a new dashboard UI that reads market data from provider
+// and, when stream is not nil, receives live ticks from it
+func NewDashboard(app fyne.App, window fyne.Window, provider data.MarketDataProvider, stream *data.QuoteStream) *Dashboard {
     // Initialize the dashboard
     dashboard := &Dashboard{
-        app:    app,
-        window: window,
+        app:      app,
+        window:   window,
+        provider: provider,
+        stream:   stream,
+        bus:      events.NewBus(),
+        history:  history.NewHistory(history.DefaultLimit),
+        reloads:  make(chan string, 16),
+        synced:   make(chan data.SyncResult, 16),
+        closed:   make(chan struct{}),
     }
 
     // Set window properties
@@ -66,11 +100,19 @@ func (d *Dashboard) Setup() {
 
     // Set window content
     d.window.SetContent(d.createLayout())
+
+    // Every profile is encrypted, ask for the passphrase of the first one
+    if active := d.currentProfile(); active != nil && active.Locked {
+        d.promptUnlock(active, func(profile *models.Profile) {
+            d.switchProfile(profile.ID)
+        })
+    }
 }
 
 // initializeProfile loads or creates a profile
 func (d *Dashboard) initializeProfile() {
     // Try to load profiles
+    var active *models.Profile
     profiles, err := data.GetProfiles()
     if err != nil || len(profiles) == 0 {
         // Create a default profile if none exists
@@ -80,61 +122,240 @@ func (d *Dashboard) initializeProfile() {
             return
         }
         
-        d.activeProfile = defaultProfile
+        active = defaultProfile
     } else {
-        // Load the first profile
-        d.activeProfile = &profiles[0]
+        // Load the first profile that isn't locked
+        active = &profiles[0]
+        for i := range profiles {
+            if !profiles[i].Locked {
+                active = &profiles[i]
+                break
+            }
+        }
     }
 
     // Set as active
-    data.SetActiveProfile(d.activeProfile)
+    d.setActiveProfile(active)
+}
+
+// currentProfile returns the active profile
+func (d *Dashboard) currentProfile() *models.Profile {
+    d.profileMutex.RLock()
+    defer d.profileMutex.RUnlock()
+    return d.activeProfile
+}
+
+// setActiveProfile makes a profile the active one, here and in the data package
+func (d *Dashboard) setActiveProfile(profile *models.Profile) {
+    d.profileMutex.Lock()
+    defer d.profileMutex.Unlock()
+    d.activeProfile = profile
+    data.SetActiveProfile(profile)
 }
 
-// createUI creates all UI components
+// createUI creates all UI components and connects them through the event bus
 func (d *Dashboard) createUI() {
     // Create the compact header with improved search functionality
     d.header = components.CreateCompactHeader(
         d.window, 
-        func(symbol string) {
-            // Handle stock selection from search
-            d.chartContainer.LoadChart(symbol)
-        },
-        d.activeProfile,
+        d.provider,
+        d.bus,
+        d.currentProfile(),
         func(profileName string) {
             // For the header's profile button, we'll show the profile manager
             d.showProfileManager()
         },
+        d.showSettings,
+        d.showAuditLog,
+        d.showPortfolio,
     )
 
-    // Create chart container
-    d.chartContainer = components.CreateChartContainer(func(symbol string) {
-        // Add to watchlist callback
-        d.watchlistContainer.AddSymbol(symbol)
-    })
-
-    // Create watchlist container
-    d.watchlistContainer = components.CreateMinimalWatchlistContainer(
-        func(symbol string) {
-            // Select stock callback
-            d.chartContainer.LoadChart(symbol)
-        },
-        func(watchlistID string) {
-            // Watchlist changed callback
-            d.heatmapContainer.SetWatchlist(watchlistID)
-        },
-    )
+    // Create the scheduler and publish every quote it fetches
+    d.scheduler = data.NewQuoteScheduler(d.provider, d.refreshInterval())
+    publishQuotes := func(quotes map[string]*models.Stock) {
+        d.bus.Publish(events.QuoteUpdated{Quotes: quotes})
+    }
+    d.watchlistQuotes = d.scheduler.Subscribe(nil, publishQuotes)
+    d.chartQuotes = d.scheduler.Subscribe(nil, publishQuotes)
 
-    // Create heatmap container
-    d.heatmapContainer = components.CreateHeatmapContainer(func(symbol string) {
-        // Select stock callback
-        d.chartContainer.LoadChart(symbol)
+    // Keep the scheduled symbols in line with what is on screen
+    events.Subscribe(d.bus, func(e events.WatchlistChanged) {
+        d.scheduler.SetSymbols(d.watchlistQuotes, e.Watchlist.Symbols)
+        d.watchlistSymbols = e.Watchlist.Symbols
+        d.updateStreamSymbols()
+    })
+    events.Subscribe(d.bus, func(e events.SymbolSelected) {
+        d.scheduler.SetSymbols(d.chartQuotes, []string{e.Symbol})
+        d.chartSymbol = e.Symbol
+        d.updateStreamSymbols()
+    })
+    events.Subscribe(d.bus, func(e events.SettingsChanged) {
+        d.scheduler.SetInterval(d.refreshInterval())
+    })
+    events.Subscribe(d.bus, func(e events.ProfileSwitched) {
+        d.scheduler.SetInterval(d.refreshInterval())
     })
+    events.Subscribe(d.bus, func(e events.ProfileReloaded) {
+        d.scheduler.SetInterval(d.refreshInterval())
+    })
+
+    // Create the components, they subscribe to the events they need
+    d.chartContainer = components.CreateChartContainer(d.provider, d.bus)
+    d.watchlistContainer = components.CreateMinimalWatchlistContainer(d.bus, d.history)
+    d.heatmapContainer = components.CreateHeatmapContainer(d.bus)
 
     // Load watchlists from the active profile
     d.watchlistContainer.LoadWatchlists()
 
-    // Set up periodic refresh
-    go d.setupPeriodicRefresh()
+    // Start refreshing quotes, stopping when the window goes away
+    d.scheduler.Start()
+    if d.stream != nil {
+        d.stream.Start(func(tick models.Tick, quote *models.Stock) {
+            d.bus.Publish(events.TickReceived{Tick: tick, Quote: quote})
+            d.bus.Publish(events.QuoteUpdated{Quotes: map[string]*models.Stock{quote.Symbol: quote}})
+        })
+    }
+
+    // Undo and redo profile changes
+    d.window.Canvas().AddShortcut(&desktop.CustomShortcut{
+        KeyName:  fyne.KeyZ,
+        Modifier: fyne.KeyModifierShortcutDefault,
+    }, func(fyne.Shortcut) { d.undo() })
+    d.window.Canvas().AddShortcut(&desktop.CustomShortcut{
+        KeyName:  fyne.KeyZ,
+        Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift,
+    }, func(fyne.Shortcut) { d.redo() })
+
+    // Pick up changes saved by other instances of the app
+    go d.processChanges()
+    stopWatching, err := data.WatchProfiles(d.queueReload)
+    if err != nil {
+        log.Printf("Not watching profiles for changes: %v", err)
+        stopWatching = func() {}
+    }
+
+    // Merge the changes made on other machines
+    d.startSync()
+
+    d.window.SetOnClosed(func() {
+        d.scheduler.Stop()
+        if d.stream != nil {
+            d.stream.Stop()
+        }
+        d.stopSync()
+        stopWatching()
+        close(d.closed)
+    })
+}
+
+// queueReload asks for a profile changed outside the app to be reloaded. It is
+// called from the watcher and sync goroutines as well as the UI, the reloads
+// run one at a time on the goroutine of processChanges.
+func (d *Dashboard) queueReload(id string) {
+    select {
+    case d.reloads <- id:
+    case <-d.closed:
+    }
+}
+
+// processChanges reloads the queued profiles and handles the sync results
+// until the window is closed
+func (d *Dashboard) processChanges() {
+    for {
+        select {
+        case id := <-d.reloads:
+            d.reloadChangedProfile(id)
+        case result := <-d.synced:
+            d.handleSyncResult(result)
+        case <-d.closed:
+            return
+        }
+    }
+}
+
+// reloadChangedProfile loads the active profile again when it was changed on
+// disk by someone else and tells the components, or switches to another
+// profile when it was removed. Only processChanges calls it.
+func (d *Dashboard) reloadChangedProfile(id string) {
+    if active := d.currentProfile(); active == nil || active.ID != id {
+        return
+    }
+
+    profile, err := data.GetProfileByID(id)
+    if errors.Is(err, os.ErrNotExist) {
+        // Deleted or moved to the trash, stop editing it
+        log.Printf("Profile %s was removed by another instance", id)
+        d.history.Clear()
+        d.refreshProfileList()
+        return
+    }
+    if err != nil {
+        log.Printf("Failed to reload profile %s: %v", id, err)
+        return
+    }
+    if profile.Locked {
+        return
+    }
+
+    // Swap the profile between edits, the history refers to the version that
+    // was replaced. Our own saves leave the profile in memory as recent as the file.
+    replaced := false
+    d.history.Replace(func() bool {
+        active := d.currentProfile()
+        if active == nil || active.ID != id || profile.LastModified.Equal(active.LastModified) {
+            return false
+        }
+        d.setActiveProfile(profile)
+        replaced = true
+        return true
+    })
+    if replaced {
+        log.Printf("Profile %s was changed by another instance, reloading it", id)
+        d.bus.Publish(events.ProfileReloaded{Profile: profile})
+    }
+}
+
+// undo reverts the most recent profile change
+func (d *Dashboard) undo() {
+    cmd, err := d.history.Undo()
+    d.afterHistoryChange(cmd, err)
+}
+
+// redo applies the most recently undone profile change again
+func (d *Dashboard) redo() {
+    cmd, err := d.history.Redo()
+    d.afterHistoryChange(cmd, err)
+}
+
+// afterHistoryChange shows the profiles as changed by undo or redo
+func (d *Dashboard) afterHistoryChange(cmd history.Command, err error) {
+    if err != nil {
+        dialog.ShowError(fmt.Errorf("%s: %w", cmd.Name(), err), d.window)
+        return
+    }
+    if cmd == nil {
+        return
+    }
+
+    // The active profile may have been deleted or restored
+    d.refreshProfileList()
+    if profile := data.GetActiveProfile(); profile != nil {
+        d.setActiveProfile(profile)
+        d.bus.Publish(events.ProfileReloaded{Profile: profile})
+    }
+}
+
+// updateStreamSymbols streams the watchlist symbols and the charted symbol
+func (d *Dashboard) updateStreamSymbols() {
+    if d.stream == nil {
+        return
+    }
+
+    symbols := append([]string(nil), d.watchlistSymbols...)
+    if d.chartSymbol != "" && !slices.Contains(symbols, d.chartSymbol) {
+        symbols = append(symbols, d.chartSymbol)
+    }
+    d.stream.SetSymbols(symbols)
 }
 
 // createLayout creates the main layout for the dashboard
@@ -173,34 +394,83 @@ func (d *Dashboard) showProfileManager() {
     }
 
     // Create list widget
-    list := widget.NewList(
+    var list *widget.List
+    reload := func() {
+        profiles, _ = data.GetProfiles()
+        items = items[:0]
+        for _, profile := range profiles {
+            items = append(items, profile.Name)
+        }
+        list.Refresh()
+    }
+    list = widget.NewList(
         func() int {
             return len(items)
         },
         func() fyne.CanvasObject {
             return container.NewBorder(
                 nil, nil, nil,
-                widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+                container.NewHBox(
+                    widget.NewButtonWithIcon("", theme.VisibilityOffIcon(), nil),
+                    widget.NewButtonWithIcon("", theme.DownloadIcon(), nil),
+                    widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+                ),
                 widget.NewLabel("Template"),
             )
         },
         func(i widget.ListItemID, o fyne.CanvasObject) {
-            o.(*fyne.Container).Objects[1].(*widget.Label).SetText(items[i])
-            o.(*fyne.Container).Objects[0].(*widget.Button).OnTapped = func() {
+            label := items[i]
+            if profiles[i].Locked {
+                label += " (locked)"
+            }
+            if profiles[i].ID == d.currentProfile().ID {
+                label += " (active)"
+            }
+            buttons := o.(*fyne.Container).Objects[1].(*fyne.Container)
+            o.(*fyne.Container).Objects[0].(*widget.Label).SetText(label)
+            buttons.Objects[0].(*widget.Button).OnTapped = func() {
+                d.showPassphraseDialog(profiles[i])
+            }
+            buttons.Objects[1].(*widget.Button).OnTapped = func() {
+                d.showExportDialog(profiles[i])
+            }
+            buttons.Objects[2].(*widget.Button).OnTapped = func() {
                 // Delete profile confirmation
                 dialog.ShowConfirm("Delete Profile", 
-                    "Are you sure you want to delete "+items[i]+"?", 
+                    "Move "+items[i]+" to the trash? It can be restored from there, or with Undo.", 
                     func(confirm bool) {
-                        if confirm {
-                            data.DeleteProfile(profiles[i].ID)
-                            d.refreshProfileList()
+                        if !confirm {
+                            return
                         }
+                        if err := d.history.Execute(history.DeleteProfile(&profiles[i])); err != nil {
+                            dialog.ShowError(err, d.window)
+                            return
+                        }
+                        d.refreshProfileList()
+                        reload()
                     }, 
                     d.window)
             }
         },
     )
 
+    // Switch to a profile when it is selected, asking for its passphrase when it is locked
+    list.OnSelected = func(i widget.ListItemID) {
+        list.UnselectAll()
+        if profiles[i].Locked {
+            d.promptUnlock(&profiles[i], func(profile *models.Profile) {
+                profiles[i] = *profile
+                d.switchProfile(profile.ID)
+                list.Refresh()
+            })
+            return
+        }
+        if profiles[i].ID != d.currentProfile().ID {
+            d.switchProfile(profiles[i].ID)
+            list.Refresh()
+        }
+    }
+
     // Create add profile button
     addButton := widget.NewButton("Add Profile", func() {
         // Show dialog to add a new profile
@@ -225,46 +495,390 @@ func (d *Dashboard) showProfileManager() {
             d.window)
     })
 
+    // Create import button
+    importButton := widget.NewButtonWithIcon("Import", theme.UploadIcon(), func() {
+        d.showImportDialog(reload)
+    })
+
+    // Create trash button
+    trashButton := widget.NewButtonWithIcon("Trash", theme.DeleteIcon(), func() {
+        d.showTrash(reload)
+    })
+
     // Show profile manager dialog
     dialog.ShowCustom("Manage Profiles", "Close", 
-        container.NewBorder(nil, addButton, nil, nil, list),
+        container.NewBorder(nil, container.NewGridWithColumns(3, addButton, importButton, trashButton), nil, nil, list),
+        d.window)
+}
+
+// promptUnlock asks for the passphrase of a locked profile and calls
+// onUnlocked with the decrypted profile once it is right
+func (d *Dashboard) promptUnlock(profile *models.Profile, onUnlocked func(*models.Profile)) {
+    passphrase := widget.NewPasswordEntry()
+    passphrase.SetPlaceHolder("Passphrase")
+
+    dialog.ShowCustomConfirm("Unlock "+profile.Name, "Unlock", "Cancel",
+        container.NewVBox(
+            widget.NewLabel(profile.Name+" is encrypted, enter its passphrase:"),
+            passphrase,
+        ),
+        func(confirm bool) {
+            if !confirm {
+                return
+            }
+            unlocked, err := data.UnlockProfile(profile.ID, passphrase.Text)
+            if errors.Is(err, data.ErrWrongPassphrase) {
+                // Ask again, with the error shown over the new prompt
+                d.promptUnlock(profile, onUnlocked)
+                dialog.ShowError(err, d.window)
+                return
+            }
+            if err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            onUnlocked(unlocked)
+        },
+        d.window)
+}
+
+// showPassphraseDialog sets, changes or removes the passphrase of a profile
+func (d *Dashboard) showPassphraseDialog(profile models.Profile) {
+    current := widget.NewPasswordEntry()
+    next := widget.NewPasswordEntry()
+    next.SetPlaceHolder("Leave empty to remove encryption")
+    confirmEntry := widget.NewPasswordEntry()
+
+    form := widget.NewForm(
+        widget.NewFormItem("New passphrase", next),
+        widget.NewFormItem("Confirm", confirmEntry),
+    )
+    if profile.Encrypted {
+        form.Items = append([]*widget.FormItem{widget.NewFormItem("Current passphrase", current)}, form.Items...)
+    }
+
+    dialog.ShowCustomConfirm("Passphrase of "+profile.Name, "Save", "Cancel", form, func(confirm bool) {
+        if !confirm {
+            return
+        }
+        if next.Text != confirmEntry.Text {
+            dialog.ShowError(errors.New("the new passphrases don't match"), d.window)
+            return
+        }
+        if !profile.Encrypted && next.Text == "" {
+            return
+        }
+
+        if err := data.ChangeProfilePassphrase(profile.ID, current.Text, next.Text); err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+
+        // Reload the active profile so it is saved with its new key
+        if profile.ID == d.currentProfile().ID {
+            d.switchProfile(profile.ID)
+        }
+        d.refreshProfileList()
+    }, d.window)
+}
+
+// showExportDialog exports a profile with its drawings, and optionally its
+// cached candles, to an archive chosen by the user
+func (d *Dashboard) showExportDialog(profile models.Profile) {
+    if profile.Locked {
+        dialog.ShowError(data.ErrProfileLocked, d.window)
+        return
+    }
+
+    includeCandles := widget.NewCheck("Include cached candles", nil)
+    dialog.ShowCustomConfirm("Export "+profile.Name, "Export", "Cancel",
+        container.NewVBox(
+            widget.NewLabel("The profile and its chart drawings are exported to a single archive."),
+            includeCandles,
+        ),
+        func(confirm bool) {
+            if !confirm {
+                return
+            }
+
+            save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+                if err != nil {
+                    dialog.ShowError(err, d.window)
+                    return
+                }
+                if writer == nil {
+                    return
+                }
+                // The export replaces the file atomically, only its path is needed
+                target := writer.URI().Path()
+                writer.Close()
+
+                if err := data.ExportProfile(profile.ID, target, includeCandles.Checked); err != nil {
+                    dialog.ShowError(err, d.window)
+                    return
+                }
+                dialog.ShowInformation("Export", profile.Name+" was exported to "+target, d.window)
+            }, d.window)
+            save.SetFileName(profile.Name + ".moosemarket.zip")
+            save.Show()
+        },
         d.window)
 }
 
+// showImportDialog imports a profile archive chosen by the user, asking for a
+// new name when its profile already exists and for its passphrase when it is
+// encrypted, then calls onImported
+func (d *Dashboard) showImportDialog(onImported func()) {
+    dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        if reader == nil {
+            return
+        }
+        source := reader.URI().Path()
+        reader.Close()
+
+        bundle, err := data.OpenProfileBundle(source)
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+
+        importBundle := func(opts data.ImportOptions) {
+            profile, err := bundle.Import(opts)
+            if err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            onImported()
+            dialog.ShowInformation("Import", profile.Name+" was imported", d.window)
+        }
+
+        // Ask for the passphrase of an encrypted profile before importing it
+        withPassphrase := func(opts data.ImportOptions) {
+            if !bundle.Manifest.Encrypted {
+                importBundle(opts)
+                return
+            }
+            passphrase := widget.NewPasswordEntry()
+            dialog.ShowCustomConfirm("Import "+bundle.Manifest.ProfileName, "Import", "Cancel",
+                container.NewVBox(
+                    widget.NewLabel(bundle.Manifest.ProfileName+" is encrypted, enter its passphrase:"),
+                    passphrase,
+                ),
+                func(confirm bool) {
+                    if confirm {
+                        opts.Passphrase = passphrase.Text
+                        importBundle(opts)
+                    }
+                },
+                d.window)
+        }
+
+        // A profile with the same ID can only be imported as a copy
+        if _, err := data.GetProfileByID(bundle.Manifest.ProfileID); err != nil {
+            withPassphrase(data.ImportOptions{})
+            return
+        }
+        name := widget.NewEntry()
+        name.SetText(bundle.Manifest.ProfileName + " (imported)")
+        dialog.ShowCustomConfirm("Profile Already Exists", "Import as Copy", "Cancel",
+            container.NewVBox(
+                widget.NewLabel(bundle.Manifest.ProfileName+" already exists. Import it as a new profile named:"),
+                name,
+            ),
+            func(confirm bool) {
+                if confirm && name.Text != "" {
+                    withPassphrase(data.ImportOptions{Name: name.Text})
+                }
+            },
+            d.window)
+    }, d.window)
+}
+
+// showTrash lists the deleted profiles so they can be restored or deleted
+// for good, calling onRestored after a profile was restored
+func (d *Dashboard) showTrash(onRestored func()) {
+    trashed, err := data.TrashedProfiles()
+    if err != nil {
+        dialog.ShowError(err, d.window)
+        return
+    }
+    if len(trashed) == 0 {
+        dialog.ShowInformation("Trash", "The trash is empty.", d.window)
+        return
+    }
+
+    var list *widget.List
+    remove := func(i int) {
+        trashed = append(trashed[:i], trashed[i+1:]...)
+        list.Refresh()
+    }
+    list = widget.NewList(
+        func() int {
+            return len(trashed)
+        },
+        func() fyne.CanvasObject {
+            return container.NewBorder(
+                nil, nil, nil,
+                container.NewHBox(
+                    widget.NewButtonWithIcon("Restore", theme.ContentUndoIcon(), nil),
+                    widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+                ),
+                widget.NewLabel("Template"),
+            )
+        },
+        func(i widget.ListItemID, o fyne.CanvasObject) {
+            profile := trashed[i]
+            buttons := o.(*fyne.Container).Objects[1].(*fyne.Container)
+            o.(*fyne.Container).Objects[0].(*widget.Label).SetText(profile.Name)
+            buttons.Objects[0].(*widget.Button).OnTapped = func() {
+                if err := data.RestoreProfile(profile.ID); err != nil {
+                    dialog.ShowError(err, d.window)
+                    return
+                }
+                remove(i)
+                onRestored()
+            }
+            buttons.Objects[1].(*widget.Button).OnTapped = func() {
+                dialog.ShowConfirm("Delete Forever",
+                    "Delete "+profile.Name+" and its backups for good? This can't be undone.",
+                    func(confirm bool) {
+                        if !confirm {
+                            return
+                        }
+                        if err := data.PurgeProfile(profile.ID); err != nil {
+                            dialog.ShowError(err, d.window)
+                            return
+                        }
+                        remove(i)
+                    },
+                    d.window)
+            }
+        },
+    )
+
+    trash := dialog.NewCustom("Trash", "Close", list, d.window)
+    trash.Resize(fyne.NewSize(400, 300))
+    trash.Show()
+}
+
 // refreshProfileList refreshes the profile dropdown
 func (d *Dashboard) refreshProfileList() {
     // Reload profiles
     profiles, _ := data.GetProfiles()
     
     // Update active profile if it was deleted
+    active := d.currentProfile()
     found := false
     for _, profile := range profiles {
-        if profile.ID == d.activeProfile.ID {
+        if profile.ID == active.ID {
             found = true
             break
         }
     }
     
     if !found && len(profiles) > 0 {
-        d.activeProfile = &profiles[0]
-        data.SetActiveProfile(d.activeProfile)
-        d.watchlistContainer.LoadWatchlists()
+        d.switchProfile(profiles[0].ID)
     }
     
     // Refresh UI
     d.window.Content().Refresh()
 }
 
-// setupPeriodicRefresh refreshes stock data periodically
-func (d *Dashboard) setupPeriodicRefresh() {
-    for {
-        // Sleep for the refresh interval (default 60 seconds)
-        time.Sleep(time.Duration(d.activeProfile.Settings.RefreshInterval) * time.Second)
-        
-        // Refresh the watchlist
-        d.watchlistContainer.LoadWatchlistItems()
-        
-        // Refresh the heatmap
-        d.heatmapContainer.RefreshHeatmap()
+// switchProfile makes the profile with the given ID active and tells the components
+func (d *Dashboard) switchProfile(id string) {
+    profile, err := data.GetProfileByID(id)
+    if err != nil {
+        dialog.ShowError(err, d.window)
+        return
+    }
+
+    d.setActiveProfile(profile)
+    d.bus.Publish(events.ProfileSwitched{Profile: profile})
+}
+
+// showSettings displays the settings dialog of the active profile
+func (d *Dashboard) showSettings() {
+    active := d.currentProfile()
+    if active == nil {
+        return
     }
-}
\ No newline at end of file
+    settings := active.Settings
+
+    // Create the inputs
+    intervalEntry := widget.NewEntry()
+    intervalEntry.SetText(strconv.Itoa(settings.RefreshInterval))
+    intervalEntry.SetPlaceHolder("Seconds")
+    currencySelect := widget.NewSelect([]string{"CAD", "USD"}, nil)
+    currencySelect.SetSelected(settings.Currency)
+    darkModeCheck := widget.NewCheck("Dark mode", nil)
+    darkModeCheck.SetChecked(settings.DarkMode)
+
+    // The sync folder belongs to this machine rather than the profile
+    syncFolder := data.SyncFolder()
+    syncEntry := widget.NewEntry()
+    syncEntry.SetText(syncFolder)
+    syncEntry.SetPlaceHolder("Off")
+    chooseButton := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+        dialog.ShowFolderOpen(func(folder fyne.ListableURI, err error) {
+            if err == nil && folder != nil {
+                syncEntry.SetText(folder.Path())
+            }
+        }, d.window)
+    })
+    conflictsButton := widget.NewButton("Conflicts", func() {
+        d.showSyncConflicts(active.ID)
+    })
+
+    form := widget.NewForm(
+        widget.NewFormItem("Refresh interval (s)", intervalEntry),
+        widget.NewFormItem("Currency", currencySelect),
+        widget.NewFormItem("", darkModeCheck),
+        widget.NewFormItem("Sync folder", container.NewBorder(nil, nil, nil, container.NewHBox(chooseButton, conflictsButton), syncEntry)),
+    )
+
+    dialog.ShowCustomConfirm("Settings", "Save", "Cancel", form, func(confirm bool) {
+        if !confirm {
+            return
+        }
+
+        interval, err := strconv.Atoi(intervalEntry.Text)
+        if err != nil || interval < 0 {
+            dialog.ShowError(fmt.Errorf("invalid refresh interval: %q", intervalEntry.Text), d.window)
+            return
+        }
+
+        // Save the settings and let the components pick them up
+        settings.RefreshInterval = interval
+        settings.Currency = currencySelect.Selected
+        settings.DarkMode = darkModeCheck.Checked
+        err = d.history.Execute(history.EditProfile("Change settings", func(profile *models.Profile) {
+            profile.Settings = settings
+        }))
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        d.bus.Publish(events.SettingsChanged{Settings: settings})
+
+        if folder := strings.TrimSpace(syncEntry.Text); folder != syncFolder {
+            if err := data.SetSyncFolder(folder); err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            d.startSync()
+        }
+    }, d.window)
+}
+
+// refreshInterval returns the quote refresh interval of the active profile
+func (d *Dashboard) refreshInterval() time.Duration {
+    settings, ok := data.GetActiveSettings()
+    if !ok || settings.RefreshInterval <= 0 {
+        return data.DefaultRefreshInterval
+    }
+    return time.Duration(settings.RefreshInterval) * time.Second
+}
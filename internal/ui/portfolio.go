@@ -0,0 +1,375 @@
+// File: internal/ui/portfolio.go
+package ui
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/history"
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// editPortfolio applies an edit that may be rejected to the active profile,
+// as an undoable change. The edit is tried on a copy first so a rejected edit
+// changes nothing.
+func (d *Dashboard) editPortfolio(name string, edit func(profile *models.Profile) error) error {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return errors.New("no active profile")
+    }
+    content, err := json.Marshal(profile)
+    if err != nil {
+        return err
+    }
+    var trial models.Profile
+    if err := json.Unmarshal(content, &trial); err != nil {
+        return err
+    }
+    if err := edit(&trial); err != nil {
+        return err
+    }
+
+    return d.history.Execute(history.EditProfile(name, func(profile *models.Profile) {
+        edit(profile)
+    }))
+}
+
+// showPortfolio lists the accounts of the active profile with their
+// positions as derived from their transactions
+func (d *Dashboard) showPortfolio() {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+
+    var portfolioDialog dialog.Dialog
+    reopen := func() {
+        portfolioDialog.Hide()
+        d.showPortfolio()
+    }
+    content := container.NewVBox()
+
+    // Positions whose stored numbers don't match their transactions
+    if issues := portfolio.CheckProfile(profile); len(issues) > 0 {
+        title := widget.NewLabel(fmt.Sprintf("%d positions or cash balances don't match their transactions:", len(issues)))
+        title.TextStyle = fyne.TextStyle{Bold: true}
+        content.Add(title)
+        for _, issue := range issues {
+            subject := issue.Symbol
+            if subject == "" {
+                subject = "Cash"
+            }
+            line := fmt.Sprintf("%s in %s: %s", subject, accountName(profile, issue.AccountID), issue.Problem)
+            if issue.TransactionID != "" {
+                line += " (transaction " + issue.TransactionID + ")"
+            }
+            label := widget.NewLabel(line)
+            label.Wrapping = fyne.TextWrapWord
+            content.Add(label)
+        }
+        content.Add(widget.NewButtonWithIcon("Derive Positions From Transactions", theme.ViewRefreshIcon(), func() {
+            if err := d.editPortfolio("Derive positions", portfolio.DeriveProfile); err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            reopen()
+        }))
+        content.Add(widget.NewSeparator())
+    }
+
+    if len(profile.Accounts) == 0 {
+        content.Add(widget.NewLabel("No accounts yet."))
+    }
+    for _, account := range profile.Accounts {
+        accountID := account.ID
+        title := widget.NewLabel(fmt.Sprintf("%s (%s), cash %s",
+            account.Name, account.Type, formatBalances(portfolio.CashBalances(account, time.Time{}))))
+        title.TextStyle = fyne.TextStyle{Bold: true}
+        cash := widget.NewButtonWithIcon("Cash", theme.ListIcon(), func() {
+            portfolioDialog.Hide()
+            d.showCashTransactions(accountID)
+        })
+        content.Add(container.NewBorder(nil, nil, nil, cash, title))
+
+        for _, position := range account.Positions {
+            accountID, symbol := account.ID, position.StockSymbol
+            var line string
+            if ledger, err := portfolio.ReplayPosition(accountID, position); err != nil {
+                line = fmt.Sprintf("%s: %v", symbol, errors.Unwrap(err))
+            } else {
+                line = fmt.Sprintf("%s: %g shares at %.2f, realized %+.2f",
+                    symbol, ledger.Quantity, ledger.AverageCost, ledger.Realized)
+            }
+            transactions := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+                portfolioDialog.Hide()
+                d.showTransactions(accountID, symbol)
+            })
+            content.Add(container.NewBorder(nil, nil, nil, transactions, widget.NewLabel(line)))
+        }
+    }
+
+    buttons := container.NewHBox(
+        widget.NewButtonWithIcon("Add Account", theme.ContentAddIcon(), func() {
+            portfolioDialog.Hide()
+            d.showAddAccount()
+        }),
+        widget.NewButtonWithIcon("Add Transaction", theme.ContentAddIcon(), func() {
+            if len(profile.Accounts) == 0 {
+                dialog.ShowInformation("Add Transaction", "Add an account first.", d.window)
+                return
+            }
+            portfolioDialog.Hide()
+            d.showAddTransaction(profile.Accounts[0].ID, "")
+        }),
+        widget.NewButtonWithIcon("Tax Report", theme.DocumentIcon(), func() {
+            portfolioDialog.Hide()
+            d.showTaxReport()
+        }),
+        widget.NewButtonWithIcon("Contribution Room", theme.InfoIcon(), func() {
+            portfolioDialog.Hide()
+            d.showContributionRoom()
+        }),
+    )
+
+    portfolioDialog = dialog.NewCustom("Portfolio: "+profile.Name, "Close",
+        container.NewBorder(nil, buttons, nil, nil, container.NewVScroll(content)), d.window)
+    portfolioDialog.Resize(fyne.NewSize(700, 500))
+    portfolioDialog.Show()
+}
+
+// showTransactions lists the transactions of a position with the position
+// each of them leaves
+func (d *Dashboard) showTransactions(accountID, symbol string) {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+    var position models.Position
+    for _, account := range profile.Accounts {
+        for _, p := range account.Positions {
+            if account.ID == accountID && p.StockSymbol == symbol {
+                position = p
+            }
+        }
+    }
+
+    var transactionsDialog dialog.Dialog
+    content := container.NewVBox()
+    ledger, err := portfolio.ReplayPosition(accountID, position)
+    if err != nil {
+        label := widget.NewLabel(err.Error())
+        label.Wrapping = fyne.TextWrapWord
+        content.Add(label)
+    }
+    // Superficial losses are found across accounts, so a report that stops
+    // at a transaction of another position still flags the earlier ones
+    report, _ := portfolio.ComputeACB(profile)
+
+    // The transactions after one that can't be replayed are listed without
+    // the position they leave, so they can still be removed
+    for i, t := range portfolio.SortTransactions(position.Transactions) {
+        line := fmt.Sprintf("%s  %s %g @ %.2f", t.Date.Format("2006-01-02"), t.Type, t.Quantity, t.Price)
+        if t.Commission != 0 {
+            line += fmt.Sprintf(" + %.2f commission", t.Commission)
+        }
+        if i < len(ledger.Entries) {
+            entry := ledger.Entries[i]
+            line += fmt.Sprintf(" → %g shares at %.2f", entry.Quantity, entry.AverageCost)
+            if t.Type == models.TransactionSell || entry.Realized != 0 {
+                line += fmt.Sprintf(", realized %+.2f", entry.Realized)
+            }
+        }
+        if loss, ok := report.SuperficialLossFor(t.ID); ok {
+            if loss.TransactionID == t.ID {
+                line += fmt.Sprintf("  ⚠ superficial loss, %.2f denied", loss.Denied)
+            } else {
+                line += "  ⚠ replaces shares sold at a superficial loss"
+            }
+        }
+        if t.Notes != "" {
+            line += "  (" + t.Notes + ")"
+        }
+
+        transactionID := t.ID
+        remove := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+            err := d.editPortfolio("Remove transaction", func(profile *models.Profile) error {
+                return portfolio.RemoveTransaction(profile, accountID, symbol, transactionID)
+            })
+            if err != nil {
+                dialog.ShowError(err, d.window)
+                return
+            }
+            transactionsDialog.Hide()
+            d.showTransactions(accountID, symbol)
+        })
+        content.Add(container.NewBorder(nil, nil, nil, remove, widget.NewLabel(line)))
+    }
+
+    buttons := container.NewHBox(
+        widget.NewButtonWithIcon("Add Transaction", theme.ContentAddIcon(), func() {
+            transactionsDialog.Hide()
+            d.showAddTransaction(accountID, symbol)
+        }),
+        widget.NewButtonWithIcon("Portfolio", theme.NavigateBackIcon(), func() {
+            transactionsDialog.Hide()
+            d.showPortfolio()
+        }),
+    )
+
+    transactionsDialog = dialog.NewCustom(fmt.Sprintf("%s in %s", symbol, accountName(profile, accountID)), "Close",
+        container.NewBorder(nil, buttons, nil, nil, container.NewVScroll(content)), d.window)
+    transactionsDialog.Resize(fyne.NewSize(750, 450))
+    transactionsDialog.Show()
+}
+
+// showAddAccount asks for the name and type of a new account
+func (d *Dashboard) showAddAccount() {
+    nameEntry := widget.NewEntry()
+    typeSelect := widget.NewSelect(portfolio.AccountTypes, nil)
+    typeSelect.SetSelected(models.AccountTFSA)
+
+    form := []*widget.FormItem{
+        widget.NewFormItem("Name", nameEntry),
+        widget.NewFormItem("Type", typeSelect),
+    }
+    dialog.ShowForm("Add Account", "Add", "Cancel", form, func(confirm bool) {
+        if confirm {
+            profile := data.GetActiveProfile()
+            id := portfolio.NewAccountID(profile)
+            err := d.editPortfolio("Add account "+nameEntry.Text, func(profile *models.Profile) error {
+                return portfolio.AddAccount(profile, id, nameEntry.Text, typeSelect.Selected)
+            })
+            if err != nil {
+                dialog.ShowError(err, d.window)
+            }
+        }
+        d.showPortfolio()
+    }, d.window)
+}
+
+// showAddTransaction asks for a transaction to record, with the account and
+// symbol filled in
+func (d *Dashboard) showAddTransaction(accountID, symbol string) {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+
+    accountIDs := make(map[string]string)
+    var names []string
+    for _, account := range profile.Accounts {
+        accountIDs[account.Name] = account.ID
+        names = append(names, account.Name)
+    }
+    accountSelect := widget.NewSelect(names, nil)
+    accountSelect.SetSelected(accountName(profile, accountID))
+    symbolEntry := widget.NewEntry()
+    symbolEntry.SetText(symbol)
+    typeSelect := widget.NewSelect([]string{
+        models.TransactionBuy,
+        models.TransactionSell,
+        models.TransactionSplit,
+        models.TransactionReinvest,
+        models.TransactionReturnOfCapital,
+    }, nil)
+    typeSelect.SetSelected(models.TransactionBuy)
+    quantityEntry := widget.NewEntry()
+    quantityEntry.SetPlaceHolder("Shares, or new shares per share for a split")
+    priceEntry := widget.NewEntry()
+    priceEntry.SetPlaceHolder("Per share, or capital returned per share")
+    currencyEntry := widget.NewEntry()
+    currencyEntry.SetText(portfolio.DefaultCurrency)
+    dateEntry := widget.NewEntry()
+    dateEntry.SetText(time.Now().Format("2006-01-02"))
+    commissionEntry := widget.NewEntry()
+    commissionEntry.SetText("0")
+    notesEntry := widget.NewEntry()
+
+    form := []*widget.FormItem{
+        widget.NewFormItem("Account", accountSelect),
+        widget.NewFormItem("Symbol", symbolEntry),
+        widget.NewFormItem("Type", typeSelect),
+        widget.NewFormItem("Quantity", quantityEntry),
+        widget.NewFormItem("Price", priceEntry),
+        widget.NewFormItem("Currency", currencyEntry),
+        widget.NewFormItem("Date", dateEntry),
+        widget.NewFormItem("Commission", commissionEntry),
+        widget.NewFormItem("Notes", notesEntry),
+    }
+    dialog.ShowForm("Add Transaction", "Add", "Cancel", form, func(confirm bool) {
+        if !confirm {
+            d.showPortfolio()
+            return
+        }
+
+        symbol := strings.ToUpper(strings.TrimSpace(symbolEntry.Text))
+        accountID := accountIDs[accountSelect.Selected]
+        t := models.Transaction{
+            ID:       portfolio.NewTransactionID(profile),
+            Type:     typeSelect.Selected,
+            Currency: parseCurrency(currencyEntry.Text),
+            Notes:    strings.TrimSpace(notesEntry.Text),
+        }
+        var err error
+        if symbol == "" {
+            err = errors.New("the transaction needs a symbol")
+        }
+        if err == nil {
+            t.Quantity, err = parseAmount("quantity", quantityEntry.Text)
+        }
+        if err == nil {
+            t.Price, err = parseAmount("price", priceEntry.Text)
+        }
+        if err == nil {
+            t.Commission, err = parseAmount("commission", commissionEntry.Text)
+        }
+        if err == nil {
+            t.Date, err = parseDate(dateEntry.Text)
+        }
+        if err == nil {
+            err = d.editPortfolio(fmt.Sprintf("Add %s %s", t.Type, symbol), func(profile *models.Profile) error {
+                return portfolio.AddTransaction(profile, accountID, symbol, t)
+            })
+        }
+        if err != nil {
+            dialog.ShowError(err, d.window)
+            return
+        }
+        d.showTransactions(accountID, symbol)
+    }, d.window)
+}
+
+// parseAmount parses a number typed in a form, empty meaning zero
+func parseAmount(name, text string) (float64, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return 0, nil
+    }
+    value, err := strconv.ParseFloat(text, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid %s: %q", name, text)
+    }
+    return value, nil
+}
+
+// accountName returns the name of an account of a profile
+func accountName(profile *models.Profile, accountID string) string {
+    for _, account := range profile.Accounts {
+        if account.ID == accountID {
+            return account.Name
+        }
+    }
+    return accountID
+}
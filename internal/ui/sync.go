@@ -0,0 +1,127 @@
+// File: internal/ui/sync.go
+package ui
+
+import (
+    "fmt"
+    "log"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+)
+
+// startSync starts syncing profiles through the sync folder of this machine,
+// stopping the previous engine first. It does nothing when sync is off.
+func (d *Dashboard) startSync() {
+    d.stopSync()
+
+    folder := data.SyncFolder()
+    if folder == "" {
+        return
+    }
+    engine, err := data.StartSync(folder, d.onSynced)
+    if err != nil {
+        log.Printf("Sync disabled: %v", err)
+        dialog.ShowError(fmt.Errorf("can't sync through %s: %w", folder, err), d.window)
+        return
+    }
+    d.syncMutex.Lock()
+    d.sync = engine
+    d.syncMutex.Unlock()
+}
+
+// stopSync stops the running sync engine, if any
+func (d *Dashboard) stopSync() {
+    d.syncMutex.Lock()
+    engine := d.sync
+    d.sync = nil
+    d.syncMutex.Unlock()
+
+    // Outside the lock, the engine may be waiting to report a result
+    if engine != nil {
+        engine.Stop()
+    }
+}
+
+// syncEngine returns the running sync engine, nil when sync is off
+func (d *Dashboard) syncEngine() *data.SyncEngine {
+    d.syncMutex.Lock()
+    defer d.syncMutex.Unlock()
+    return d.sync
+}
+
+// onSynced queues a sync result for processChanges. It is called from the
+// goroutines of the sync engine.
+func (d *Dashboard) onSynced(result data.SyncResult) {
+    select {
+    case d.synced <- result:
+    case <-d.closed:
+    }
+}
+
+// handleSyncResult shows what a sync merged into the profiles, or why a
+// profile can't be synced. Only processChanges calls it.
+func (d *Dashboard) handleSyncResult(result data.SyncResult) {
+    if result.Err != nil {
+        dialog.ShowError(fmt.Errorf("can't sync the profile: %w", result.Err), d.window)
+        return
+    }
+    if result.Merged {
+        d.reloadChangedProfile(result.ProfileID)
+    }
+    if result.NewConflicts > 0 {
+        d.showSyncConflicts(result.ProfileID)
+    }
+}
+
+// showSyncConflicts lists the sync conflicts of a profile so one version of
+// each can be kept
+func (d *Dashboard) showSyncConflicts(profileID string) {
+    engine := d.syncEngine()
+    if engine == nil {
+        dialog.ShowInformation("Sync Conflicts", "Sync is off, choose a sync folder in the settings.", d.window)
+        return
+    }
+    conflicts := engine.Conflicts(profileID)
+    if len(conflicts) == 0 {
+        dialog.ShowInformation("Sync Conflicts", "There are no sync conflicts.", d.window)
+        return
+    }
+
+    var conflictsDialog dialog.Dialog
+    content := container.NewVBox()
+    for _, conflict := range conflicts {
+        title := widget.NewLabel(fmt.Sprintf("The %s %s was changed on two machines:", conflict.Kind, conflict.Key))
+        title.TextStyle = fyne.TextStyle{Bold: true}
+        content.Add(title)
+
+        for i, version := range conflict.Versions {
+            value := string(version.Value)
+            if version.Path == nil {
+                value = "deleted"
+            }
+            label := widget.NewLabel(fmt.Sprintf("%s, %s: %s",
+                version.Actor, version.Time.Local().Format("2006-01-02 15:04"), value))
+            label.Wrapping = fyne.TextWrapWord
+
+            keep := widget.NewButton("Keep", func() {
+                if err := engine.ResolveConflict(profileID, conflict.ID, i); err != nil {
+                    dialog.ShowError(err, d.window)
+                    return
+                }
+                conflictsDialog.Hide()
+                d.queueReload(profileID)
+                d.showSyncConflicts(profileID)
+            })
+            content.Add(container.NewBorder(nil, nil, nil, keep, label))
+        }
+        content.Add(widget.NewSeparator())
+    }
+
+    conflictsDialog = dialog.NewCustom("Sync Conflicts", "Later", container.NewVScroll(content), d.window)
+    conflictsDialog.Resize(fyne.NewSize(700, 450))
+    conflictsDialog.Show()
+}
@@ -0,0 +1,126 @@
+// File: internal/ui/tax_report.go
+package ui
+
+import (
+    "fmt"
+    "strconv"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+
+    "github.com/frederikblais/Moose-Market/internal/data"
+    "github.com/frederikblais/Moose-Market/internal/models"
+    "github.com/frederikblais/Moose-Market/internal/portfolio"
+)
+
+// showTaxReport lists the adjusted cost base of the securities held in the
+// taxable accounts of the active profile and the capital gains and losses
+// realized in a year
+func (d *Dashboard) showTaxReport() {
+    profile := data.GetActiveProfile()
+    if profile == nil {
+        return
+    }
+
+    report, err := portfolio.ComputeACB(profile)
+    years := report.Years()
+    if len(years) == 0 {
+        years = []int{time.Now().Year()}
+    }
+    var options []string
+    for _, year := range years {
+        options = append(options, strconv.Itoa(year))
+    }
+
+    content := container.NewVBox()
+    show := func(year int) {
+        content.RemoveAll()
+        if err != nil {
+            label := widget.NewLabel(err.Error())
+            label.Wrapping = fyne.TextWrapWord
+            content.Add(label)
+        }
+
+        title := widget.NewLabel("Adjusted cost base")
+        title.TextStyle = fyne.TextStyle{Bold: true}
+        content.Add(title)
+        held := 0
+        for _, pool := range report.Pools {
+            if pool.Shares > 0 {
+                held++
+                content.Add(widget.NewLabel(fmt.Sprintf("%s: %g shares, ACB %.2f (%.4f per share)",
+                    pool.Symbol, pool.Shares, pool.ACB, pool.ACBPerShare())))
+            }
+        }
+        if held == 0 {
+            content.Add(widget.NewLabel("No shares held in taxable accounts."))
+        }
+        content.Add(widget.NewSeparator())
+
+        title = widget.NewLabel(fmt.Sprintf("Capital gains and losses in %d", year))
+        title.TextStyle = fyne.TextStyle{Bold: true}
+        content.Add(title)
+        dispositions := report.DispositionsIn(year)
+        if len(dispositions) == 0 {
+            content.Add(widget.NewLabel("No dispositions."))
+        }
+        for _, e := range dispositions {
+            label := widget.NewLabel(formatDisposition(profile, report, e))
+            label.Wrapping = fyne.TextWrapWord
+            content.Add(label)
+        }
+        total := widget.NewLabel(fmt.Sprintf("Net capital gain: %+.2f", report.NetGain(year)))
+        total.TextStyle = fyne.TextStyle{Bold: true}
+        content.Add(total)
+    }
+
+    yearSelect := widget.NewSelect(options, func(selected string) {
+        year, _ := strconv.Atoi(selected)
+        show(year)
+    })
+    yearSelect.SetSelected(options[0])
+
+    var reportDialog dialog.Dialog
+    note := widget.NewLabel("Shares of the same security are pooled across all non-registered accounts. TFSA, RRSP and FHSA accounts are left out.")
+    note.Wrapping = fyne.TextWrapWord
+    back := widget.NewButtonWithIcon("Portfolio", theme.NavigateBackIcon(), func() {
+        reportDialog.Hide()
+        d.showPortfolio()
+    })
+    reportDialog = dialog.NewCustom("Tax Report: "+profile.Name, "Close",
+        container.NewBorder(container.NewVBox(yearSelect, note), back, nil, nil, container.NewVScroll(content)), d.window)
+    reportDialog.Resize(fyne.NewSize(800, 500))
+    reportDialog.Show()
+}
+
+// formatDisposition describes a disposition with the ACB per share before and
+// after it, flagging a superficial loss
+func formatDisposition(profile *models.Profile, report *portfolio.ACBReport, e portfolio.ACBEvent) string {
+    t := e.Transaction
+    date := t.Date.Format("2006-01-02")
+    if t.Type != models.TransactionSell {
+        return fmt.Sprintf("%s  %s in %s: return of capital %.2f exceeds the ACB, gain %+.2f",
+            date, e.Symbol, accountName(profile, e.AccountID), t.Quantity*t.Price, e.Gain)
+    }
+    cost := e.ACBBefore - e.ACBAfter + e.AddedLoss
+    line := fmt.Sprintf("%s  %s in %s: sold %g for %.2f, ACB %.2f (%.4f → %.4f per share), gain %+.2f",
+        date, e.Symbol, accountName(profile, e.AccountID), t.Quantity, e.Proceeds, cost,
+        e.ACBPerShareBefore(), e.ACBPerShareAfter(), e.Gain)
+
+    loss, ok := report.SuperficialLossFor(t.ID)
+    if !ok || loss.TransactionID != t.ID {
+        return line
+    }
+    line += fmt.Sprintf("\n    ⚠ Superficial loss: %.2f of the %.2f loss is denied", loss.Denied, loss.Loss)
+    if loss.Added > 0 {
+        line += fmt.Sprintf(", %.2f is added to the ACB of the replacement shares", loss.Added)
+    }
+    if lost := loss.Denied - loss.Added; lost >= 0.005 {
+        line += fmt.Sprintf(", %.2f is lost to purchases in registered accounts", lost)
+    }
+    return line
+}